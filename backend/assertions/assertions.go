@@ -0,0 +1,248 @@
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/jsonpath"
+	"github.com/triplewhale/postwhale/validation"
+)
+
+// Assertion types
+const (
+	TypeStatusEquals      = "statusEquals"
+	TypeStatusInRange     = "statusInRange"
+	TypeHeaderPresent     = "headerPresent"
+	TypeHeaderMatches     = "headerMatches"
+	TypeJSONPathEquals    = "jsonPathEquals"
+	TypeJSONPathExists    = "jsonPathExists"
+	TypeJSONPathMatches   = "jsonPathMatches"
+	TypeResponseTimeBelow = "responseTimeBelow"
+	TypeMatchesSchema     = "matchesSchema"
+)
+
+// Assertion is a single check against a response.
+// Which fields are used depends on Type.
+type Assertion struct {
+	Type     string `json:"type" yaml:"type"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`         // header name or JSONPath
+	Expected string `json:"expected,omitempty" yaml:"expected,omitempty"` // statusEquals, jsonPathEquals
+	Pattern  string `json:"pattern,omitempty" yaml:"pattern,omitempty"`   // headerMatches, jsonPathMatches
+	Min      int    `json:"min,omitempty" yaml:"min,omitempty"`           // statusInRange
+	Max      int    `json:"max,omitempty" yaml:"max,omitempty"`           // statusInRange
+	Ms       int64  `json:"ms,omitempty" yaml:"ms,omitempty"`             // responseTimeBelow
+}
+
+// Result is the outcome of evaluating a single assertion
+type Result struct {
+	Type    string             `json:"type"`
+	Path    string             `json:"path,omitempty"`
+	Passed  bool               `json:"passed"`
+	Actual  string             `json:"actual,omitempty"`
+	Message string             `json:"message,omitempty"`
+	Issues  []validation.Issue `json:"issues,omitempty"`
+}
+
+// ParseAssertions decodes assertions stored as JSON; empty input means no assertions
+func ParseAssertions(assertionsJSON string) ([]Assertion, error) {
+	list := []Assertion{}
+	if assertionsJSON == "" {
+		return list, nil
+	}
+	if err := json.Unmarshal([]byte(assertionsJSON), &list); err != nil {
+		return nil, fmt.Errorf("invalid assertions: %w", err)
+	}
+	for i, a := range list {
+		if err := a.check(); err != nil {
+			return nil, fmt.Errorf("invalid assertion %d: %w", i, err)
+		}
+	}
+	return list, nil
+}
+
+// check validates that an assertion has the fields its type needs
+func (a Assertion) check() error {
+	switch a.Type {
+	case TypeStatusEquals:
+		if _, err := strconv.Atoi(a.Expected); err != nil {
+			return fmt.Errorf("expected status must be a number: %q", a.Expected)
+		}
+	case TypeStatusInRange:
+		// Zero is never a status, so it means the field was left out
+		if a.Min <= 0 || a.Max <= 0 {
+			return fmt.Errorf("statusInRange requires min and max")
+		}
+		if a.Min > a.Max {
+			return fmt.Errorf("min %d is greater than max %d", a.Min, a.Max)
+		}
+	case TypeHeaderPresent, TypeJSONPathExists, TypeJSONPathEquals:
+		if a.Path == "" {
+			return fmt.Errorf("%s requires a path", a.Type)
+		}
+	case TypeHeaderMatches, TypeJSONPathMatches:
+		if a.Path == "" {
+			return fmt.Errorf("%s requires a path", a.Type)
+		}
+		if _, err := regexp.Compile(a.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case TypeResponseTimeBelow:
+		if a.Ms <= 0 {
+			return fmt.Errorf("responseTimeBelow requires a positive ms")
+		}
+	case TypeMatchesSchema:
+	default:
+		return fmt.Errorf("unknown assertion type: %q", a.Type)
+	}
+	return nil
+}
+
+// Evaluate runs every assertion against the response.
+// spec is the endpoint's OpenAPI operation, used by matchesSchema; it may be nil.
+// When the request failed (response.Error is set) every assertion fails with
+// the error, since there is no response to check.
+func Evaluate(list []Assertion, response client.Response, spec *discovery.APIEndpoint) []Result {
+	results := make([]Result, 0, len(list))
+
+	if response.Error != "" {
+		for _, a := range list {
+			results = append(results, Result{Type: a.Type, Path: a.Path, Message: fmt.Sprintf("request failed: %s", response.Error)})
+		}
+		return results
+	}
+
+	// Decode the body lazily and only once. JSONPath checks keep numbers
+	// exact; schema checks use the float64 values validation expects.
+	var doc, schemaDoc interface{}
+	var docErr, schemaDocErr error
+	decoded, schemaDecoded := false, false
+	body := func() (interface{}, error) {
+		if !decoded {
+			doc, docErr = jsonpath.Decode([]byte(response.Body))
+			decoded = true
+		}
+		return doc, docErr
+	}
+	schemaBody := func() (interface{}, error) {
+		if !schemaDecoded {
+			schemaDocErr = json.Unmarshal([]byte(response.Body), &schemaDoc)
+			schemaDecoded = true
+		}
+		return schemaDoc, schemaDocErr
+	}
+
+	for _, a := range list {
+		result := Result{Type: a.Type, Path: a.Path}
+		if err := a.check(); err != nil {
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		switch a.Type {
+		case TypeStatusEquals:
+			expected, _ := strconv.Atoi(a.Expected)
+			result.Actual = strconv.Itoa(response.StatusCode)
+			result.Passed = response.StatusCode == expected
+			if !result.Passed {
+				result.Message = fmt.Sprintf("expected status %d, got %d", expected, response.StatusCode)
+			}
+		case TypeStatusInRange:
+			result.Actual = strconv.Itoa(response.StatusCode)
+			result.Passed = response.StatusCode >= a.Min && response.StatusCode <= a.Max
+			if !result.Passed {
+				result.Message = fmt.Sprintf("expected status between %d and %d, got %d", a.Min, a.Max, response.StatusCode)
+			}
+		case TypeHeaderPresent:
+			values := response.HeaderValues(a.Path)
+			result.Passed = len(values) > 0
+			if result.Passed {
+				result.Actual = values[0]
+			} else {
+				result.Message = fmt.Sprintf("header not found: %s", a.Path)
+			}
+		case TypeHeaderMatches:
+			values := response.HeaderValues(a.Path)
+			if len(values) == 0 {
+				result.Message = fmt.Sprintf("header not found: %s", a.Path)
+				break
+			}
+			result.Actual = values[0]
+			result.Passed = regexp.MustCompile(a.Pattern).MatchString(values[0])
+			if !result.Passed {
+				result.Message = fmt.Sprintf("header %s does not match %s", a.Path, a.Pattern)
+			}
+		case TypeJSONPathEquals, TypeJSONPathExists, TypeJSONPathMatches:
+			d, err := body()
+			if err != nil {
+				result.Message = fmt.Sprintf("response body is not JSON: %v", err)
+				break
+			}
+			value, ok, err := jsonpath.First(d, a.Path)
+			if err != nil {
+				result.Message = err.Error()
+				break
+			}
+			if !ok {
+				result.Message = fmt.Sprintf("no match for %s", a.Path)
+				break
+			}
+			result.Actual = jsonpath.String(value)
+			switch a.Type {
+			case TypeJSONPathExists:
+				result.Passed = true
+			case TypeJSONPathEquals:
+				result.Passed = result.Actual == a.Expected
+				if !result.Passed {
+					result.Message = fmt.Sprintf("expected %s to equal %q, got %q", a.Path, a.Expected, result.Actual)
+				}
+			case TypeJSONPathMatches:
+				result.Passed = regexp.MustCompile(a.Pattern).MatchString(result.Actual)
+				if !result.Passed {
+					result.Message = fmt.Sprintf("%s does not match %s", a.Path, a.Pattern)
+				}
+			}
+		case TypeResponseTimeBelow:
+			elapsed := response.ResponseTime.Milliseconds()
+			result.Actual = strconv.FormatInt(elapsed, 10)
+			result.Passed = elapsed < a.Ms
+			if !result.Passed {
+				result.Message = fmt.Sprintf("expected response time below %dms, took %dms", a.Ms, elapsed)
+			}
+		case TypeMatchesSchema:
+			schema, ok := validation.ResponseSchema(spec, response.StatusCode)
+			if !ok {
+				result.Message = fmt.Sprintf("no JSON response schema documented for status %d", response.StatusCode)
+				break
+			}
+			d, err := schemaBody()
+			if err != nil {
+				result.Message = fmt.Sprintf("response body is not JSON: %v", err)
+				break
+			}
+			result.Issues = validation.ValidatePayload(schema, d, "", validation.Response)
+			result.Passed = len(result.Issues) == 0
+			if !result.Passed {
+				result.Message = fmt.Sprintf("response body has %d schema violation(s)", len(result.Issues))
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Passed reports whether every result passed
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
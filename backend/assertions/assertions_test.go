@@ -0,0 +1,163 @@
+package assertions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+func testResponse() client.Response {
+	return client.Response{
+		StatusCode:   201,
+		Headers:      map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:         `{"id": "ord_1", "total": 42.5, "items": [{"sku": "a"}]}`,
+		ResponseTime: 120 * time.Millisecond,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	list := []Assertion{
+		{Type: TypeStatusEquals, Expected: "201"},
+		{Type: TypeStatusInRange, Min: 200, Max: 299},
+		{Type: TypeHeaderPresent, Path: "content-type"},
+		{Type: TypeHeaderMatches, Path: "Content-Type", Pattern: "^application/json"},
+		{Type: TypeJSONPathEquals, Path: "$.total", Expected: "42.5"},
+		{Type: TypeJSONPathExists, Path: "$.items[0].sku"},
+		{Type: TypeJSONPathMatches, Path: "$.id", Pattern: "^ord_"},
+		{Type: TypeResponseTimeBelow, Ms: 500},
+	}
+
+	results := Evaluate(list, testResponse(), nil)
+
+	if len(results) != len(list) {
+		t.Fatalf("Expected %d results, got %d", len(list), len(results))
+	}
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("Expected %s %s to pass: %s", r.Type, r.Path, r.Message)
+		}
+	}
+	if !Passed(results) {
+		t.Error("Expected Passed to be true")
+	}
+}
+
+func TestEvaluate_Failures(t *testing.T) {
+	list := []Assertion{
+		{Type: TypeStatusEquals, Expected: "200"},
+		{Type: TypeHeaderPresent, Path: "X-Request-Id"},
+		{Type: TypeJSONPathEquals, Path: "$.id", Expected: "ord_2"},
+		{Type: TypeJSONPathExists, Path: "$.missing"},
+		{Type: TypeResponseTimeBelow, Ms: 100},
+		{Type: TypeMatchesSchema},
+	}
+
+	results := Evaluate(list, testResponse(), nil)
+
+	for _, r := range results {
+		if r.Passed {
+			t.Errorf("Expected %s %s to fail", r.Type, r.Path)
+		}
+		if r.Message == "" {
+			t.Errorf("Expected a message for failed %s", r.Type)
+		}
+	}
+	if results[0].Actual != "201" {
+		t.Errorf("Expected actual status 201, got %q", results[0].Actual)
+	}
+	if Passed(results) {
+		t.Error("Expected Passed to be false")
+	}
+}
+
+func TestEvaluate_JSONPathNumbers(t *testing.T) {
+	response := client.Response{StatusCode: 200, Body: `{"id": 9007199254740993, "totals": {"b": 2, "a": 1}}`}
+	list := []Assertion{
+		{Type: TypeJSONPathEquals, Path: "$.id", Expected: "9007199254740993"},
+		{Type: TypeJSONPathEquals, Path: "$.totals[*]", Expected: "1"},
+	}
+
+	for _, r := range Evaluate(list, response, nil) {
+		if !r.Passed {
+			t.Errorf("Expected %s to pass: %s", r.Path, r.Message)
+		}
+	}
+}
+
+func TestEvaluate_RequestError(t *testing.T) {
+	list := []Assertion{
+		{Type: TypeStatusInRange, Min: 200, Max: 599},
+		{Type: TypeResponseTimeBelow, Ms: 1000},
+	}
+
+	results := Evaluate(list, client.Response{Error: "context deadline exceeded"}, nil)
+
+	if len(results) != 2 || Passed(results) {
+		t.Fatalf("Expected every assertion to fail, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Message != "request failed: context deadline exceeded" {
+			t.Errorf("Expected the request error as the message, got %q", r.Message)
+		}
+	}
+}
+
+func TestEvaluate_MatchesSchema(t *testing.T) {
+	spec := &discovery.APIEndpoint{
+		Responses: map[string]discovery.Response{
+			"201": {Content: map[string]discovery.MediaType{
+				"application/json": {Schema: discovery.Schema{
+					Type:     "object",
+					Required: []string{"id", "status"},
+					Properties: map[string]discovery.Schema{
+						"id":    {Type: "string"},
+						"total": {Type: "integer"},
+					},
+				}},
+			}},
+		},
+	}
+
+	results := Evaluate([]Assertion{{Type: TypeMatchesSchema}}, testResponse(), spec)
+
+	if results[0].Passed {
+		t.Fatal("Expected schema assertion to fail")
+	}
+	if len(results[0].Issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %v", results[0].Issues)
+	}
+	if results[0].Issues[1].Pointer != "/total" {
+		t.Errorf("Expected issue at /total, got %q", results[0].Issues[1].Pointer)
+	}
+}
+
+func TestParseAssertions(t *testing.T) {
+	list, err := ParseAssertions(`[{"type":"statusInRange","min":200,"max":299}]`)
+	if err != nil {
+		t.Fatalf("ParseAssertions failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Max != 299 {
+		t.Errorf("Unexpected assertions: %+v", list)
+	}
+
+	if list, err := ParseAssertions(""); err != nil || len(list) != 0 {
+		t.Errorf("Expected empty input to yield no assertions, got %v, %v", list, err)
+	}
+
+	invalid := []string{
+		`[{"type":"bogus"}]`,
+		`[{"type":"headerMatches","path":"X","pattern":"("}]`,
+		`[{"type":"statusEquals","expected":"ok"}]`,
+		`[{"type":"statusInRange"}]`,
+		`[{"type":"statusInRange","min":200}]`,
+		`[{"type":"statusInRange","min":500,"max":200}]`,
+		`not json`,
+	}
+	for _, input := range invalid {
+		if _, err := ParseAssertions(input); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
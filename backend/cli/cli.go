@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Exit codes returned by Main
+const (
+	ExitOK     = 0
+	ExitFailed = 1 // a request or assertion failed
+	ExitUsage  = 2 // bad arguments or the repository couldn't be loaded
+)
+
+const usage = `Usage: postwhale <command> [flags]
+
+Commands:
+  run    Run saved requests from postwhale.saved.yml files against an environment
+
+Run "postwhale <command> -h" for command flags.
+Without a command, postwhale speaks the JSON IPC protocol on stdin/stdout.
+`
+
+// Main runs a headless subcommand and returns the process exit code
+func Main(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
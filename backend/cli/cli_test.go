@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPI = `openapi: 3.0.0
+info:
+  title: Fusion
+  version: 1.0.0
+paths:
+  /orders:
+    post:
+      operationId: createOrder
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: string
+`
+
+const testSaved = `version: 1
+service_id: fusion
+saved_requests:
+  - name: Create order
+    endpoint:
+      method: POST
+      path: /orders
+    headers:
+      - key: X-Token
+        value: "{{token}}"
+        enabled: true
+    extractions:
+      - variable: orderId
+        source: body
+        path: $.id
+    assertions:
+      - type: statusEquals
+        expected: "201"
+      - type: matchesSchema
+  - name: Get order
+    endpoint:
+      method: GET
+      path: /orders/{id}
+    path_params:
+      id: "{{orderId}}"
+    assertions:
+      - type: jsonPathEquals
+        path: $.status
+        expected: open
+`
+
+// writeTestRepo creates a repository with one service and its saved requests
+func writeTestRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	svcDir := filepath.Join(repo, "services", "fusion")
+	if err := os.MkdirAll(svcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"tw-config.json":      `{"serviceId": "fusion", "env": {"PORT": 8080}}`,
+		"openapi.yaml":        testOpenAPI,
+		"postwhale.saved.yml": testSaved,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(svcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestMain_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.Header.Get("X-Token") == "secret":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "ord_7"}`))
+		case r.URL.Path == "/fusion/orders/ord_7":
+			w.Write([]byte(`{"status": "open"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	repo := writeTestRepo(t)
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"run", "-repo", repo, "-service", "fusion", "-base-url", server.URL + "/{{serviceId}}", "-var", "token=secret"}, &stdout, &stderr)
+
+	if code != ExitOK {
+		t.Fatalf("Expected exit %d, got %d\nstdout: %s\nstderr: %s", ExitOK, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "2 passed, 0 failed, 0 skipped") {
+		t.Errorf("Expected summary line, got: %s", stdout.String())
+	}
+}
+
+func TestMain_RunFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 5}`))
+	}))
+	defer server.Close()
+
+	repo := writeTestRepo(t)
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"run", "-repo", repo, "-base-url", server.URL, "-var", "token=x", "-request", "Create order"}, &stdout, &stderr)
+
+	if code != ExitFailed {
+		t.Fatalf("Expected exit %d, got %d\nstdout: %s", ExitFailed, code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "/id: expected string, got integer") {
+		t.Errorf("Expected schema violation in output, got: %s", stdout.String())
+	}
+}
+
+func TestMain_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := Main(nil, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage exit without a command, got %d", code)
+	}
+	if code := Main([]string{"bogus"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage exit for unknown command, got %d", code)
+	}
+	if code := Main([]string{"run"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage exit without -repo, got %d", code)
+	}
+	if code := Main([]string{"run", "-repo", writeTestRepo(t), "-service", "missing"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage exit for unknown service, got %d", code)
+	}
+}
+
+func TestMain_RunReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "ord_7"}`))
+	}))
+	defer server.Close()
+
+	repo := writeTestRepo(t)
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"run", "-repo", repo, "-base-url", server.URL, "-var", "token=x", "-request", "Create order", "-report", "json"}, &stdout, &stderr)
+	if code != ExitOK {
+		t.Fatalf("Expected exit %d, got %d\nstderr: %s", ExitOK, code, stderr.String())
+	}
+
+	var parsed struct {
+		Environment string `json:"environment"`
+		Totals      struct {
+			Passed int `json:"passed"`
+		} `json:"totals"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &parsed); err != nil {
+		t.Fatalf("Expected only the JSON report on stdout: %v\n%s", err, stdout.String())
+	}
+	if parsed.Environment != "LOCAL" || parsed.Totals.Passed != 1 {
+		t.Errorf("Unexpected report: %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "1 passed") {
+		t.Errorf("Expected progress on stderr, got %q", stderr.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "junit.xml")
+	stdout.Reset()
+	code = Main([]string{"run", "-repo", repo, "-base-url", server.URL, "-var", "token=x", "-request", "Create order", "-report", "junit", "-out", out}, &stdout, &stderr)
+	if code != ExitOK {
+		t.Fatalf("Expected exit %d, got %d", ExitOK, code)
+	}
+	if data, err := os.ReadFile(out); err != nil || !strings.Contains(string(data), "<testsuites") {
+		t.Errorf("Expected JUnit file, got %q (%v)", data, err)
+	}
+	if !strings.Contains(stdout.String(), "1 passed") {
+		t.Errorf("Expected progress on stdout when writing to a file, got %q", stdout.String())
+	}
+}
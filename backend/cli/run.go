@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/environment"
+	"github.com/triplewhale/postwhale/extract"
+	"github.com/triplewhale/postwhale/portability"
+	"github.com/triplewhale/postwhale/report"
+	"github.com/triplewhale/postwhale/runner"
+	"github.com/triplewhale/postwhale/scanner"
+	"github.com/triplewhale/postwhale/templating"
+)
+
+// runOptions are the parsed flags of the run command
+type runOptions struct {
+	repo          string
+	services      stringList
+	requests      stringList
+	env           string
+	deployment    string
+	endpoint      string
+	cluster       string
+	baseURL       string
+	auth          bool
+	vars          stringList
+	stopOnFailure bool
+	delay         time.Duration
+	concurrency   int
+	timeout       time.Duration
+	report        string
+	out           string
+}
+
+// runCommand implements `postwhale run`
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	var opts runOptions
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.repo, "repo", "", "repository root containing services/ (required)")
+	fs.Var(&opts.services, "service", "serviceId to run (repeatable; default all services)")
+	fs.Var(&opts.requests, "request", "saved request name to run (repeatable; default all)")
+	fs.StringVar(&opts.env, "env", "LOCAL", "environment: LOCAL, STAGING or PRODUCTION")
+	fs.StringVar(&opts.deployment, "deployment", "", "tw-config.json deployment to target")
+	fs.StringVar(&opts.endpoint, "endpoint", "", "deployment endpoint name (default internal, public with -auth)")
+	fs.StringVar(&opts.cluster, "cluster", "", "deployment cluster to target")
+	fs.StringVar(&opts.baseURL, "base-url", "", "base URL template overriding deployments, e.g. http://localhost:{{port}}")
+	fs.BoolVar(&opts.auth, "auth", false, "target the authenticated public API")
+	fs.Var(&opts.vars, "var", "global variable as name=value (repeatable)")
+	fs.BoolVar(&opts.stopOnFailure, "stop-on-failure", false, "skip remaining requests after the first failure")
+	fs.DurationVar(&opts.delay, "delay", 0, "pause between requests")
+	fs.IntVar(&opts.concurrency, "concurrency", 1, "number of requests in flight")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "per-request timeout")
+	fs.StringVar(&opts.report, "report", "", "report format: "+strings.Join(report.Formats, ", "))
+	fs.StringVar(&opts.out, "out", "", "report file (default stdout, with progress moved to stderr)")
+
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if opts.repo == "" {
+		fmt.Fprintln(stderr, "run: -repo is required")
+		fs.Usage()
+		return ExitUsage
+	}
+	opts.env = strings.ToUpper(opts.env)
+	if opts.report != "" && report.Extension(opts.report) == "" {
+		fmt.Fprintf(stderr, "run: unknown report format %q, expected one of %s\n", opts.report, strings.Join(report.Formats, ", "))
+		return ExitUsage
+	}
+
+	// A report on stdout must stay machine-readable, so progress goes to stderr
+	progress := stdout
+	if opts.report != "" && opts.out == "" {
+		progress = stderr
+	}
+
+	globals, err := parseVars(opts.vars)
+	if err != nil {
+		fmt.Fprintf(stderr, "run: %v\n", err)
+		return ExitUsage
+	}
+
+	services, err := selectServices(scanner.ScanRepository(opts.repo), opts.services)
+	if err != nil {
+		fmt.Fprintf(stderr, "run: %v\n", err)
+		return ExitUsage
+	}
+
+	items, err := loadItems(services, opts.requests, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "run: %v\n", err)
+		return ExitUsage
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(stderr, "run: no saved requests to run")
+		return ExitUsage
+	}
+
+	configs := map[string]*discovery.TWConfig{}
+	for _, svc := range services {
+		configs[svc.ServiceID] = svc.Config
+	}
+
+	vars := newVariableStore(globals)
+	r := &runner.Runner{
+		Prepare: func(item runner.Item) (client.RequestConfig, error) {
+			return prepareItem(item, opts, configs[item.ServiceID], vars)
+		},
+		Extract: func(item runner.Item, response client.Response) []extract.Result {
+			results := extract.Apply(item.Extractions, response, "global")
+			for _, result := range results {
+				if result.OK {
+					vars.set(result.Scope, result.Variable, result.Value)
+				}
+			}
+			return results
+		},
+		Progress: func(event runner.Event) {
+			if event.Type == runner.EventFinished {
+				printResult(progress, *event.Result)
+			}
+		},
+	}
+
+	// Ctrl-C cancels the in-flight request and skips the rest
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	summary := r.Run(ctx, items, runner.Options{
+		StopOnFailure: opts.stopOnFailure,
+		Delay:         opts.delay,
+		Concurrency:   opts.concurrency,
+	})
+
+	fmt.Fprintf(progress, "\n%d passed, %d failed, %d skipped (%dms)\n", summary.Passed, summary.Failed, summary.Skipped, summary.DurationMs)
+
+	if opts.report != "" {
+		if err := writeReport(opts, services, summary, stdout); err != nil {
+			fmt.Fprintf(stderr, "run: failed to write report: %v\n", err)
+			return ExitFailed
+		}
+	}
+
+	if !summary.Success() {
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+// writeReport renders the run summary to -out, or to stdout without it
+func writeReport(opts runOptions, services []scanner.DiscoveredService, summary runner.Summary, stdout io.Writer) error {
+	serviceIDs := make([]string, len(services))
+	for i, svc := range services {
+		serviceIDs[i] = svc.ServiceID
+	}
+
+	properties := map[string]string{"repo": opts.repo}
+	for key, value := range map[string]string{"baseUrl": opts.baseURL, "deployment": opts.deployment, "endpoint": opts.endpoint, "cluster": opts.cluster} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+
+	meta := report.Metadata{
+		Name:        filepath.Base(opts.repo),
+		Target:      strings.Join(serviceIDs, ","),
+		Environment: opts.env,
+		Properties:  properties,
+	}
+
+	if opts.out == "" {
+		return report.Write(stdout, opts.report, summary, meta)
+	}
+
+	f, err := os.Create(opts.out)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f, opts.report, summary, meta); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseVars parses name=value pairs
+func parseVars(pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid -var %q, expected name=value", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// selectServices filters scanned services by serviceId, keeping scan order
+func selectServices(scan scanner.ScanResult, serviceIDs []string) ([]scanner.DiscoveredService, error) {
+	// A full scan only returns Unchanged placeholders for services whose
+	// config couldn't be read; their problem is in scan.Errors
+	parsed := []scanner.DiscoveredService{}
+	for _, svc := range scan.Services {
+		if !svc.Unchanged {
+			parsed = append(parsed, svc)
+		}
+	}
+	scan.Services = parsed
+
+	if len(scan.Services) == 0 {
+		if len(scan.Errors) > 0 {
+			return nil, fmt.Errorf("failed to scan %s: %s", scan.RepoPath, strings.Join(scan.Errors, "; "))
+		}
+		return nil, fmt.Errorf("no services found in %s", scan.RepoPath)
+	}
+	if len(serviceIDs) == 0 {
+		return scan.Services, nil
+	}
+
+	byID := map[string]scanner.DiscoveredService{}
+	for _, svc := range scan.Services {
+		byID[svc.ServiceID] = svc
+	}
+
+	services := []scanner.DiscoveredService{}
+	for _, id := range serviceIDs {
+		svc, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("service not found: %s", id)
+		}
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+// loadItems reads each service's postwhale.saved.yml into runnable items.
+// Services without the file are skipped with a note on stderr.
+func loadItems(services []scanner.DiscoveredService, names []string, stderr io.Writer) ([]runner.Item, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	items := []runner.Item{}
+	for _, svc := range services {
+		file, err := portability.ReadSavedRequestsFile(svc.Path)
+		if err != nil {
+			if _, statErr := os.Stat(filepath.Join(svc.Path, portability.FileName)); os.IsNotExist(statErr) {
+				fmt.Fprintf(stderr, "%s: no %s, skipping\n", svc.ServiceID, portability.FileName)
+				continue
+			}
+			return nil, fmt.Errorf("%s: %v", svc.ServiceID, err)
+		}
+
+		for _, portable := range file.SavedRequests {
+			if len(wanted) > 0 && !wanted[portable.Name] {
+				continue
+			}
+			items = append(items, portableItem(svc, portable))
+		}
+	}
+	return items, nil
+}
+
+// portableItem converts a saved request from the YAML file into a runnable item
+func portableItem(svc scanner.DiscoveredService, portable portability.PortableSavedRequest) runner.Item {
+	item := runner.Item{
+		Name:        portable.Name,
+		ServiceID:   svc.ServiceID,
+		Port:        svc.Port,
+		Method:      strings.ToUpper(portable.Endpoint.Method),
+		Path:        portable.Endpoint.Path,
+		PathParams:  portable.PathParams,
+		QueryParams: []templating.QueryParam{},
+		Headers:     map[string]string{},
+		Body:        portable.Body,
+		Extractions: portable.Extractions,
+		Assertions:  portable.Assertions,
+	}
+
+	for _, q := range portable.QueryParams {
+		item.QueryParams = append(item.QueryParams, templating.QueryParam{Key: q.Key, Value: q.Value, Enabled: q.Enabled})
+	}
+	for _, h := range portable.Headers {
+		if h.Enabled && h.Key != "" {
+			item.Headers[h.Key] = h.Value
+		}
+	}
+
+	for i := range svc.Endpoints {
+		ep := &svc.Endpoints[i]
+		if strings.EqualFold(ep.Method, item.Method) && ep.Path == item.Path {
+			item.Spec = ep
+			break
+		}
+	}
+
+	return item
+}
+
+// prepareItem resolves the target URL and {{variables}} for an item,
+// mirroring executeRequest: explicit base URL, then tw-config deployments, then the default URL scheme
+func prepareItem(item runner.Item, opts runOptions, config *discovery.TWConfig, vars *variableStore) (client.RequestConfig, error) {
+	requestConfig := client.RequestConfig{
+		ServiceID:   item.ServiceID,
+		Port:        item.Port,
+		Endpoint:    item.Path,
+		Method:      item.Method,
+		Environment: client.Environment(opts.env),
+		Headers:     item.Headers,
+		Body:        item.Body,
+		Timeout:     opts.timeout,
+		AuthEnabled: opts.auth,
+	}
+
+	globals := vars.get("global")
+	if opts.baseURL != "" {
+		env := environment.Definition{Name: "cli", BaseURLTemplate: opts.baseURL, Variables: globals}
+		baseURL, err := env.BaseURL(item.ServiceID, item.Port)
+		if err != nil {
+			return requestConfig, err
+		}
+		requestConfig.BaseURL = baseURL
+	} else {
+		defaultName := "internal"
+		if opts.auth {
+			defaultName = "public"
+		}
+		selector := discovery.EndpointSelector{Deployment: opts.deployment, Endpoint: opts.endpoint, Cluster: opts.cluster}
+		if target, ok := discovery.SelectEndpoint(config.ResolveEndpoints(), opts.env, selector, defaultName); ok {
+			requestConfig.BaseURL = target.URL
+		}
+	}
+
+	resolved, err := templating.NewResolver(
+		templating.Scope{Name: "global", Vars: globals},
+		templating.Scope{Name: "service", Vars: vars.get("service:" + item.ServiceID)},
+	).ResolveRequest(templating.Request{
+		Path:        item.Path,
+		PathParams:  item.PathParams,
+		QueryParams: item.QueryParams,
+		Headers:     item.Headers,
+		Body:        item.Body,
+	})
+	requestConfig.Endpoint = resolved.URL()
+	requestConfig.Headers = resolved.Headers
+	requestConfig.Body = resolved.Body
+
+	return requestConfig, err
+}
+
+// printResult writes one line per finished request, plus failure details
+func printResult(out io.Writer, result runner.Result) {
+	switch {
+	case result.Skipped:
+		fmt.Fprintf(out, "- %s [%s] skipped\n", result.Name, result.ServiceID)
+		return
+	case result.Passed:
+		fmt.Fprintf(out, "✓ %s [%s] %s %s → %d (%dms)\n", result.Name, result.ServiceID, result.Method, result.URL, result.StatusCode, result.ResponseTime)
+		return
+	}
+
+	fmt.Fprintf(out, "✗ %s [%s] %s %s", result.Name, result.ServiceID, result.Method, result.URL)
+	if result.StatusCode != 0 {
+		fmt.Fprintf(out, " → %d (%dms)", result.StatusCode, result.ResponseTime)
+	}
+	fmt.Fprintln(out)
+	if result.Error != "" {
+		fmt.Fprintf(out, "    %s\n", result.Error)
+	}
+	for _, a := range result.Assertions {
+		if !a.Passed {
+			fmt.Fprintf(out, "    %s %s: %s\n", a.Type, a.Path, a.Message)
+			for _, issue := range a.Issues {
+				fmt.Fprintf(out, "      %s: %s\n", issue.Pointer, issue.Message)
+			}
+		}
+	}
+}
+
+// variableStore holds variables in memory for a headless run, keyed by scope
+type variableStore struct {
+	mu     sync.Mutex
+	scopes map[string]map[string]string
+}
+
+// newVariableStore creates a store seeded with global variables
+func newVariableStore(globals map[string]string) *variableStore {
+	return &variableStore{scopes: map[string]map[string]string{"global": globals}}
+}
+
+// get returns a copy of a scope's variables
+func (s *variableStore) get(scope string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vars := map[string]string{}
+	for k, v := range s.scopes[scope] {
+		vars[k] = v
+	}
+	return vars
+}
+
+// set stores a variable in a scope
+func (s *variableStore) set(scope, name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scopes[scope] == nil {
+		s.scopes[scope] = map[string]string{}
+	}
+	s.scopes[scope][name] = value
+}
@@ -2,7 +2,7 @@ package client
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -32,6 +32,7 @@ type RequestConfig struct {
 	Environment Environment
 	Timeout     time.Duration
 	AuthEnabled bool
+	BaseURL     string // Resolved deployment URL; overrides the environment rules when set
 }
 
 // Response contains the HTTP response data
@@ -42,18 +43,36 @@ type Response struct {
 	Body          string
 	ResponseTime  time.Duration
 	RemoteAddress string
+	Timing        Timing
 	Error         string
+	Cancelled     bool
 }
 
-// buildURL constructs the full URL based on environment and config
-// When AuthEnabled, routes through api.triplewhale.com API gateway
-// Otherwise, LOCAL uses local proxy and STAGING/PRODUCTION use DNS records
-func buildURL(config RequestConfig) string {
+// HeaderValues returns the values of a response header, matching its name
+// case-insensitively
+func (r Response) HeaderValues(name string) []string {
+	for key, values := range r.Headers {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// BuildURL constructs the full URL based on environment and config
+// A BaseURL resolved from tw-config.json deployments takes precedence.
+// Otherwise, when AuthEnabled, routes through api.triplewhale.com API gateway,
+// and without auth LOCAL uses local proxy and STAGING/PRODUCTION use DNS records
+func BuildURL(config RequestConfig) string {
 	endpoint := config.Endpoint
 	if !strings.HasPrefix(endpoint, "/") {
 		endpoint = "/" + endpoint
 	}
 
+	if config.BaseURL != "" {
+		return strings.TrimRight(config.BaseURL, "/") + endpoint
+	}
+
 	if config.AuthEnabled {
 		switch config.Environment {
 		case EnvLocal:
@@ -78,13 +97,14 @@ func buildURL(config RequestConfig) string {
 }
 
 // ExecuteRequest executes an HTTP request based on the config
-func ExecuteRequest(config RequestConfig) Response {
-	url := buildURL(config)
-	return executeRequestWithURL(url, config)
+// Cancelling ctx aborts the request and closes the underlying connection
+func ExecuteRequest(ctx context.Context, config RequestConfig) Response {
+	url := BuildURL(config)
+	return executeRequestWithURL(ctx, url, config)
 }
 
 // executeRequestWithURL executes an HTTP request with a given URL (testable)
-func executeRequestWithURL(url string, config RequestConfig) Response {
+func executeRequestWithURL(parent context.Context, url string, config RequestConfig) Response {
 	start := time.Now()
 
 	timeout := config.Timeout
@@ -92,27 +112,11 @@ func executeRequestWithURL(url string, config RequestConfig) Response {
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
-	var remoteAddr string
-	trace := &httptrace.ClientTrace{
-		GotConn: func(info httptrace.GotConnInfo) {
-			if info.Conn != nil {
-				remoteAddr = info.Conn.RemoteAddr().String()
-			}
-		},
-		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {},
-		ConnectDone: func(network, addr string, _ error) {
-			if remoteAddr == "" {
-				remoteAddr = addr
-			}
-		},
-		DNSDone: func(_ httptrace.DNSDoneInfo) {},
-		GetConn: func(_ string) {},
-		GotFirstResponseByte: func() {},
-	}
-	ctx = httptrace.WithClientTrace(ctx, trace)
+	recorder := &timingRecorder{}
+	ctx = httptrace.WithClientTrace(ctx, recorder.trace())
 
 	var bodyReader io.Reader
 	if config.Body != "" {
@@ -134,30 +138,40 @@ func executeRequestWithURL(url string, config RequestConfig) Response {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
+		if errors.Is(parent.Err(), context.Canceled) {
+			return cancelledResponse(start, recorder)
+		}
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			return Response{
 				Error:         fmt.Sprintf("request timed out: %v", err),
 				ResponseTime:  time.Since(start),
-				RemoteAddress: remoteAddr,
+				RemoteAddress: recorder.remoteAddress(),
+				Timing:        recorder.timing(),
 			}
 		}
 		return Response{
 			Error:         fmt.Sprintf("request failed: %v", err),
 			ResponseTime:  time.Since(start),
-			RemoteAddress: remoteAddr,
+			RemoteAddress: recorder.remoteAddress(),
+			Timing:        recorder.timing(),
 		}
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
+	recorder.finishBody()
 	if err != nil {
+		if errors.Is(parent.Err(), context.Canceled) {
+			return cancelledResponse(start, recorder)
+		}
 		return Response{
 			StatusCode:    resp.StatusCode,
 			Status:        resp.Status,
 			Headers:       resp.Header,
 			Error:         fmt.Sprintf("failed to read response body: %v", err),
 			ResponseTime:  time.Since(start),
-			RemoteAddress: remoteAddr,
+			RemoteAddress: recorder.remoteAddress(),
+			Timing:        recorder.timing(),
 		}
 	}
 
@@ -167,6 +181,18 @@ func executeRequestWithURL(url string, config RequestConfig) Response {
 		Headers:       resp.Header,
 		Body:          string(bodyBytes),
 		ResponseTime:  time.Since(start),
-		RemoteAddress: remoteAddr,
+		RemoteAddress: recorder.remoteAddress(),
+		Timing:        recorder.timing(),
+	}
+}
+
+// cancelledResponse builds the result for a request aborted by its caller
+func cancelledResponse(start time.Time, recorder *timingRecorder) Response {
+	return Response{
+		Error:         "request cancelled",
+		ResponseTime:  time.Since(start),
+		RemoteAddress: recorder.remoteAddress(),
+		Timing:        recorder.timing(),
+		Cancelled:     true,
 	}
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -16,11 +17,11 @@ func TestBuildURL_Local(t *testing.T) {
 		Environment: EnvLocal,
 	}
 
-	url := buildURL(config)
+	url := BuildURL(config)
 	expected := "http://localhost/fusion/orders"
 
 	if url != expected {
-		t.Errorf("buildURL() = %q, want %q", url, expected)
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
 	}
 }
 
@@ -31,11 +32,11 @@ func TestBuildURL_Staging(t *testing.T) {
 		Environment: EnvStaging,
 	}
 
-	url := buildURL(config)
+	url := BuildURL(config)
 	expected := "http://stg.fusion.srv.whale3.io/orders"
 
 	if url != expected {
-		t.Errorf("buildURL() = %q, want %q", url, expected)
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
 	}
 }
 
@@ -46,11 +47,11 @@ func TestBuildURL_Production(t *testing.T) {
 		Environment: EnvProduction,
 	}
 
-	url := buildURL(config)
+	url := BuildURL(config)
 	expected := "http://fusion.srv.whale3.io/orders"
 
 	if url != expected {
-		t.Errorf("buildURL() = %q, want %q", url, expected)
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
 	}
 }
 
@@ -61,11 +62,11 @@ func TestBuildURL_WithLeadingSlash(t *testing.T) {
 		Environment: EnvLocal,
 	}
 
-	url := buildURL(config)
+	url := BuildURL(config)
 	expected := "http://localhost/moby/chat"
 
 	if url != expected {
-		t.Errorf("buildURL() = %q, want %q", url, expected)
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
 	}
 }
 
@@ -76,11 +77,28 @@ func TestBuildURL_WithoutLeadingSlash(t *testing.T) {
 		Environment: EnvLocal,
 	}
 
-	url := buildURL(config)
+	url := BuildURL(config)
 	expected := "http://localhost/moby/chat"
 
 	if url != expected {
-		t.Errorf("buildURL() = %q, want %q", url, expected)
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
+	}
+}
+
+func TestBuildURL_BaseURL(t *testing.T) {
+	config := RequestConfig{
+		ServiceID:   "fusion",
+		Endpoint:    "/orders",
+		Environment: EnvProduction,
+		AuthEnabled: true,
+		BaseURL:     "http://fusion.srv.whale3.io/",
+	}
+
+	url := BuildURL(config)
+	expected := "http://fusion.srv.whale3.io/orders"
+
+	if url != expected {
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
 	}
 }
 
@@ -108,7 +126,7 @@ func TestExecuteRequest_GET(t *testing.T) {
 	}
 
 	// Override with test server URL
-	response := executeRequestWithURL(server.URL+"/test", config)
+	response := executeRequestWithURL(context.Background(), server.URL+"/test", config)
 
 	if response.Error != "" {
 		t.Errorf("Expected no error, got %s", response.Error)
@@ -158,7 +176,7 @@ func TestExecuteRequest_POST_WithBody(t *testing.T) {
 		Timeout:     5 * time.Second,
 	}
 
-	response := executeRequestWithURL(server.URL+"/create", config)
+	response := executeRequestWithURL(context.Background(), server.URL+"/create", config)
 
 	if response.Error != "" {
 		t.Errorf("Expected no error, got %s", response.Error)
@@ -185,7 +203,7 @@ func TestExecuteRequest_Timeout(t *testing.T) {
 		Timeout:     100 * time.Millisecond, // Very short timeout
 	}
 
-	response := executeRequestWithURL(server.URL+"/slow", config)
+	response := executeRequestWithURL(context.Background(), server.URL+"/slow", config)
 
 	if response.Error == "" {
 		t.Error("Expected timeout error, got none")
@@ -205,7 +223,7 @@ func TestExecuteRequest_InvalidURL(t *testing.T) {
 		Timeout:     5 * time.Second,
 	}
 
-	response := executeRequestWithURL("http://invalid-host-that-does-not-exist-12345.local", config)
+	response := executeRequestWithURL(context.Background(), "http://invalid-host-that-does-not-exist-12345.local", config)
 
 	if response.Error == "" {
 		t.Error("Expected error for invalid host, got none")
@@ -231,9 +249,91 @@ func TestExecuteRequest_CustomHeaders(t *testing.T) {
 		Timeout:     5 * time.Second,
 	}
 
-	response := executeRequestWithURL(server.URL+"/test", config)
+	response := executeRequestWithURL(context.Background(), server.URL+"/test", config)
 
 	if response.Error != "" {
 		t.Errorf("Expected no error, got %s", response.Error)
 	}
 }
+
+func TestExecuteRequest_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := RequestConfig{
+		ServiceID:   "test",
+		Endpoint:    "/slow",
+		Method:      "GET",
+		Environment: EnvLocal,
+		Timeout:     5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	response := executeRequestWithURL(ctx, server.URL+"/slow", config)
+
+	if !response.Cancelled {
+		t.Errorf("Expected cancelled response, got error: %s", response.Error)
+	}
+	if response.StatusCode != 0 {
+		t.Errorf("Expected no status code, got %d", response.StatusCode)
+	}
+	if response.ResponseTime >= 5*time.Second {
+		t.Errorf("Expected request to abort early, took %v", response.ResponseTime)
+	}
+}
+
+func TestExecuteRequest_Timing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	config := RequestConfig{
+		ServiceID:   "test",
+		Endpoint:    "/test",
+		Method:      "GET",
+		Environment: EnvLocal,
+		Timeout:     5 * time.Second,
+	}
+
+	response := executeRequestWithURL(context.Background(), server.URL+"/test", config)
+
+	if response.Error != "" {
+		t.Fatalf("Expected no error, got %s", response.Error)
+	}
+	if response.Timing.ConnReused {
+		t.Error("Expected a fresh connection")
+	}
+	if response.Timing.TCPConnect == 0 {
+		t.Error("Expected non-zero TCP connect time")
+	}
+	if response.Timing.TimeToFirstByte < 20*time.Millisecond {
+		t.Errorf("Expected TTFB to include server delay, got %v", response.Timing.TimeToFirstByte)
+	}
+	if response.Timing.TLSHandshake != 0 {
+		t.Errorf("Expected no TLS handshake for plain HTTP, got %v", response.Timing.TLSHandshake)
+	}
+}
+
+func TestResponse_HeaderValues(t *testing.T) {
+	response := Response{Headers: map[string][]string{"Content-Type": {"application/json"}}}
+
+	if got := response.HeaderValues("content-type"); len(got) != 1 || got[0] != "application/json" {
+		t.Errorf("Expected case-insensitive match, got %v", got)
+	}
+	if got := response.HeaderValues("X-Missing"); got != nil {
+		t.Errorf("Expected nil for a missing header, got %v", got)
+	}
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing contains the per-phase breakdown of a request
+// Phases that did not happen (e.g. DNS/TCP/TLS on a reused connection) are zero
+type Timing struct {
+	DNSLookup       time.Duration
+	TCPConnect      time.Duration
+	TLSHandshake    time.Duration
+	RequestWrite    time.Duration // connection acquired -> request fully written
+	TimeToFirstByte time.Duration // request written -> first response byte
+	ContentDownload time.Duration // first response byte -> body fully read
+	ConnReused      bool
+}
+
+// timingRecorder collects phase timestamps from httptrace hooks
+// Hooks may fire on transport goroutines, so all fields are guarded by mu
+type timingRecorder struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, wroteRequest     time.Time
+	firstByte, bodyDone       time.Time
+	connReused                bool
+	remoteAddr                string
+}
+
+// trace returns a ClientTrace that records into r
+func (r *timingRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			r.mark(&r.dnsStart)
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			r.mark(&r.dnsDone)
+		},
+		ConnectStart: func(_, _ string) {
+			// Only the first dial attempt counts when dialing multiple addresses
+			r.mu.Lock()
+			if r.connectStart.IsZero() {
+				r.connectStart = time.Now()
+			}
+			r.mu.Unlock()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if err == nil {
+				r.connectDone = time.Now()
+			}
+			if r.remoteAddr == "" {
+				r.remoteAddr = addr
+			}
+		},
+		TLSHandshakeStart: func() {
+			r.mark(&r.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			r.mark(&r.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.gotConn = time.Now()
+			r.connReused = info.Reused
+			if info.Conn != nil {
+				r.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			r.mark(&r.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			r.mark(&r.firstByte)
+		},
+	}
+}
+
+// mark sets *t to the current time
+func (r *timingRecorder) mark(t *time.Time) {
+	r.mu.Lock()
+	*t = time.Now()
+	r.mu.Unlock()
+}
+
+// finishBody records the moment the response body was fully read
+func (r *timingRecorder) finishBody() {
+	r.mark(&r.bodyDone)
+}
+
+// remoteAddress returns the address of the connection used, if any
+func (r *timingRecorder) remoteAddress() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remoteAddr
+}
+
+// timing computes phase durations from the recorded timestamps
+func (r *timingRecorder) timing() Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Timing{
+		DNSLookup:       between(r.dnsStart, r.dnsDone),
+		TCPConnect:      between(r.connectStart, r.connectDone),
+		TLSHandshake:    between(r.tlsStart, r.tlsDone),
+		RequestWrite:    between(r.gotConn, r.wroteRequest),
+		TimeToFirstByte: between(r.wroteRequest, r.firstByte),
+		ContentDownload: between(r.firstByte, r.bodyDone),
+		ConnReused:      r.connReused,
+	}
+}
+
+// between returns end-start, or zero if either phase boundary was never reached
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
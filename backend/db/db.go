@@ -11,9 +11,10 @@ import (
 
 // Repository represents a repository in the database
 type Repository struct {
-	ID   int64
-	Name string
-	Path string
+	ID         int64
+	Name       string
+	Path       string
+	LayoutJSON string // scan layout overrides for the repository; {} uses postwhale.config.yml or the default
 }
 
 // Service represents a service in the database
@@ -24,6 +25,9 @@ type Service struct {
 	Name       string
 	Port       int
 	ConfigJSON string
+	SpecJSON   string // service-level OpenAPI info, servers and security
+	Dir        string // service directory relative to the repository
+	FilesJSON  string // fingerprint of the files the service was scanned from
 }
 
 // Endpoint represents an endpoint in the database
@@ -34,6 +38,8 @@ type Endpoint struct {
 	Path        string
 	OperationID string
 	SpecJSON    string
+	SpecFile    string // spec file the endpoint was read from, relative to the service
+	Visibility  string // private or public, from the spec file name; empty when unknown
 }
 
 // Request represents a saved request in the database
@@ -56,10 +62,63 @@ type SavedRequest struct {
 	QueryParamsJSON string
 	HeadersJSON     string
 	Body            string
+	ExtractionsJSON string
+	AssertionsJSON  string
 	CreatedAt       string
 }
 
-// InitDB initializes the SQLite database and creates tables
+// Environment represents a user-defined target environment
+type Environment struct {
+	ID                   int64
+	Name                 string
+	BaseURLTemplate      string
+	VariablesJSON        string
+	ServiceOverridesJSON string
+	CreatedAt            string
+}
+
+// Run represents a persisted collection run
+type Run struct {
+	ID          int64
+	Target      string
+	Environment string
+	Status      string
+	Total       int
+	Passed      int
+	Failed      int
+	Skipped     int
+	DurationMs  int64
+	SummaryJSON string
+	StartedAt   string
+	FinishedAt  string
+	CreatedAt   string
+}
+
+// Querier is what the CRUD functions run statements against. Both *sql.DB
+// and *sql.Tx satisfy it, so several calls can share one transaction.
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. The database has a single connection, so fn must use tx
+// rather than db or it will block forever.
+func WithTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InitDB opens the SQLite database and migrates it to the current schema
 func InitDB(dbPath string) (*sql.DB, error) {
 	// Validate and sanitize database path
 	if dbPath == "" {
@@ -72,76 +131,31 @@ func InitDB(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("invalid database path: path traversal not allowed")
 	}
 
-	db, err := sql.Open("sqlite3", cleanPath)
+	// Enforce foreign keys so ON DELETE CASCADE works, and wait for locks
+	// held by other processes instead of failing with "database is locked".
+	// WAL lets readers see the last commit while a write is in progress.
+	dsn := cleanPath + "?_foreign_keys=on&_busy_timeout=5000"
+	if cleanPath != ":memory:" {
+		dsn += "&_journal_mode=WAL"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	// IPC requests are handled concurrently; a single connection serializes access
+	// and keeps :memory: databases from splitting across connections
+	db.SetMaxOpenConns(1)
+
 	// Verify connection works
 	if err := db.Ping(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Create tables
-	schema := `
-	CREATE TABLE IF NOT EXISTS repositories (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		path TEXT NOT NULL UNIQUE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS services (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		repo_id INTEGER NOT NULL,
-		service_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		port INTEGER NOT NULL,
-		config_json TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (repo_id) REFERENCES repositories(id) ON DELETE CASCADE,
-		UNIQUE(repo_id, service_id)
-	);
-
-	CREATE TABLE IF NOT EXISTS endpoints (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		service_id INTEGER NOT NULL,
-		method TEXT NOT NULL,
-		path TEXT NOT NULL,
-		operation_id TEXT NOT NULL,
-		spec_json TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE,
-		UNIQUE(service_id, method, path)
-	);
-
-	CREATE TABLE IF NOT EXISTS requests (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		endpoint_id INTEGER NOT NULL,
-		environment TEXT NOT NULL,
-		headers TEXT,
-		body TEXT,
-		response TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (endpoint_id) REFERENCES endpoints(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS saved_requests (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		endpoint_id INTEGER NOT NULL,
-		name TEXT NOT NULL,
-		path_params_json TEXT NOT NULL DEFAULT '{}',
-		query_params_json TEXT NOT NULL DEFAULT '[]',
-		headers_json TEXT NOT NULL DEFAULT '[]',
-		body TEXT NOT NULL DEFAULT '',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (endpoint_id) REFERENCES endpoints(id) ON DELETE CASCADE
-	);
-	`
-
-	_, err = db.Exec(schema)
-	if err != nil {
+	// Create or upgrade the schema
+	if err := migrate(db, cleanPath, migrations); err != nil {
 		db.Close()
 		return nil, err
 	}
@@ -150,7 +164,7 @@ func InitDB(dbPath string) (*sql.DB, error) {
 }
 
 // AddRepository adds a new repository to the database
-func AddRepository(db *sql.DB, repo Repository) (int64, error) {
+func AddRepository(db Querier, repo Repository) (int64, error) {
 	// Validate inputs
 	if repo.Name == "" {
 		return 0, fmt.Errorf("repository name cannot be empty")
@@ -159,9 +173,13 @@ func AddRepository(db *sql.DB, repo Repository) (int64, error) {
 		return 0, fmt.Errorf("repository path cannot be empty")
 	}
 
+	if repo.LayoutJSON == "" {
+		repo.LayoutJSON = "{}"
+	}
+
 	result, err := db.Exec(
-		"INSERT INTO repositories (name, path) VALUES (?, ?)",
-		repo.Name, repo.Path,
+		"INSERT INTO repositories (name, path, layout_json) VALUES (?, ?, ?)",
+		repo.Name, repo.Path, repo.LayoutJSON,
 	)
 	if err != nil {
 		return 0, err
@@ -170,9 +188,28 @@ func AddRepository(db *sql.DB, repo Repository) (int64, error) {
 	return result.LastInsertId()
 }
 
+// UpdateRepositoryLayout replaces a repository's scan layout
+func UpdateRepositoryLayout(db Querier, id int64, layoutJSON string) error {
+	if id == 0 {
+		return fmt.Errorf("repository id cannot be empty")
+	}
+	if layoutJSON == "" {
+		layoutJSON = "{}"
+	}
+
+	result, err := db.Exec("UPDATE repositories SET layout_json = ? WHERE id = ?", layoutJSON, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("repository not found: %d", id)
+	}
+	return nil
+}
+
 // GetRepositories retrieves all repositories from the database
-func GetRepositories(db *sql.DB) ([]Repository, error) {
-	rows, err := db.Query("SELECT id, name, path FROM repositories ORDER BY created_at DESC")
+func GetRepositories(db Querier) ([]Repository, error) {
+	rows, err := db.Query("SELECT id, name, path, layout_json FROM repositories ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +219,7 @@ func GetRepositories(db *sql.DB) ([]Repository, error) {
 	repositories := []Repository{}
 	for rows.Next() {
 		var repo Repository
-		if err := rows.Scan(&repo.ID, &repo.Name, &repo.Path); err != nil {
+		if err := rows.Scan(&repo.ID, &repo.Name, &repo.Path, &repo.LayoutJSON); err != nil {
 			return nil, err
 		}
 		repositories = append(repositories, repo)
@@ -197,7 +234,7 @@ func GetRepositories(db *sql.DB) ([]Repository, error) {
 }
 
 // AddService adds a new service to the database
-func AddService(db *sql.DB, service Service) (int64, error) {
+func AddService(db Querier, service Service) (int64, error) {
 	// Validate inputs
 	if service.ServiceID == "" {
 		return 0, fmt.Errorf("service_id cannot be empty")
@@ -209,9 +246,16 @@ func AddService(db *sql.DB, service Service) (int64, error) {
 		return 0, fmt.Errorf("port must be between 0 and 65535")
 	}
 
+	if service.SpecJSON == "" {
+		service.SpecJSON = "{}"
+	}
+	if service.FilesJSON == "" {
+		service.FilesJSON = "{}"
+	}
+
 	result, err := db.Exec(
-		"INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)",
-		service.RepoID, service.ServiceID, service.Name, service.Port, service.ConfigJSON,
+		"INSERT INTO services (repo_id, service_id, name, port, config_json, spec_json, dir, files_json) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		service.RepoID, service.ServiceID, service.Name, service.Port, service.ConfigJSON, service.SpecJSON, service.Dir, service.FilesJSON,
 	)
 	if err != nil {
 		return 0, err
@@ -220,10 +264,48 @@ func AddService(db *sql.DB, service Service) (int64, error) {
 	return result.LastInsertId()
 }
 
+// UpdateService updates a service's scanned fields
+func UpdateService(db Querier, service Service) error {
+	// Validate inputs
+	if service.ID == 0 {
+		return fmt.Errorf("service id cannot be empty")
+	}
+	if service.Name == "" {
+		return fmt.Errorf("service name cannot be empty")
+	}
+	if service.Port < 0 || service.Port > 65535 {
+		return fmt.Errorf("port must be between 0 and 65535")
+	}
+
+	if service.SpecJSON == "" {
+		service.SpecJSON = "{}"
+	}
+	if service.FilesJSON == "" {
+		service.FilesJSON = "{}"
+	}
+
+	_, err := db.Exec(
+		"UPDATE services SET name = ?, port = ?, config_json = ?, spec_json = ?, dir = ?, files_json = ? WHERE id = ?",
+		service.Name, service.Port, service.ConfigJSON, service.SpecJSON, service.Dir, service.FilesJSON, service.ID,
+	)
+	return err
+}
+
+// DeleteService deletes a service; its endpoints, saved requests and history
+// go with it through ON DELETE CASCADE
+func DeleteService(db Querier, id int64) error {
+	if id == 0 {
+		return fmt.Errorf("service id cannot be empty")
+	}
+
+	_, err := db.Exec("DELETE FROM services WHERE id = ?", id)
+	return err
+}
+
 // GetServicesByRepo retrieves all services for a repository
-func GetServicesByRepo(db *sql.DB, repoID int64) ([]Service, error) {
+func GetServicesByRepo(db Querier, repoID int64) ([]Service, error) {
 	rows, err := db.Query(
-		"SELECT id, repo_id, service_id, name, port, config_json FROM services WHERE repo_id = ? ORDER BY name",
+		"SELECT id, repo_id, service_id, name, port, config_json, spec_json, dir, files_json FROM services WHERE repo_id = ? ORDER BY name",
 		repoID,
 	)
 	if err != nil {
@@ -235,7 +317,7 @@ func GetServicesByRepo(db *sql.DB, repoID int64) ([]Service, error) {
 	services := []Service{}
 	for rows.Next() {
 		var svc Service
-		if err := rows.Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON); err != nil {
+		if err := rows.Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON, &svc.SpecJSON, &svc.Dir, &svc.FilesJSON); err != nil {
 			return nil, err
 		}
 		services = append(services, svc)
@@ -250,9 +332,9 @@ func GetServicesByRepo(db *sql.DB, repoID int64) ([]Service, error) {
 }
 
 // GetAllServices retrieves all services from the database
-func GetAllServices(db *sql.DB) ([]Service, error) {
+func GetAllServices(db Querier) ([]Service, error) {
 	rows, err := db.Query(
-		"SELECT id, repo_id, service_id, name, port, config_json FROM services ORDER BY name",
+		"SELECT id, repo_id, service_id, name, port, config_json, spec_json, dir, files_json FROM services ORDER BY name",
 	)
 	if err != nil {
 		return nil, err
@@ -262,7 +344,7 @@ func GetAllServices(db *sql.DB) ([]Service, error) {
 	services := []Service{}
 	for rows.Next() {
 		var svc Service
-		if err := rows.Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON); err != nil {
+		if err := rows.Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON, &svc.SpecJSON, &svc.Dir, &svc.FilesJSON); err != nil {
 			return nil, err
 		}
 		services = append(services, svc)
@@ -275,8 +357,42 @@ func GetAllServices(db *sql.DB) ([]Service, error) {
 	return services, nil
 }
 
+// GetServiceByEndpoint retrieves the service that owns an endpoint
+func GetServiceByEndpoint(db Querier, endpointID int64) (Service, error) {
+	var svc Service
+	err := db.QueryRow(
+		`SELECT s.id, s.repo_id, s.service_id, s.name, s.port, s.config_json, s.spec_json, s.dir, s.files_json
+		FROM services s
+		JOIN endpoints e ON e.service_id = s.id
+		WHERE e.id = ?`,
+		endpointID,
+	).Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON, &svc.SpecJSON, &svc.Dir, &svc.FilesJSON)
+	return svc, err
+}
+
+// GetServiceByServiceID retrieves a service by its tw-config serviceId
+// If several repositories contain the service, the oldest one wins
+func GetServiceByServiceID(db Querier, serviceID string) (Service, error) {
+	var svc Service
+	err := db.QueryRow(
+		"SELECT id, repo_id, service_id, name, port, config_json, spec_json, dir, files_json FROM services WHERE service_id = ? ORDER BY id LIMIT 1",
+		serviceID,
+	).Scan(&svc.ID, &svc.RepoID, &svc.ServiceID, &svc.Name, &svc.Port, &svc.ConfigJSON, &svc.SpecJSON, &svc.Dir, &svc.FilesJSON)
+	return svc, err
+}
+
+// GetEndpoint retrieves a single endpoint by ID
+func GetEndpoint(db Querier, id int64) (Endpoint, error) {
+	var ep Endpoint
+	err := db.QueryRow(
+		"SELECT id, service_id, method, path, operation_id, spec_json, spec_file, visibility FROM endpoints WHERE id = ?",
+		id,
+	).Scan(&ep.ID, &ep.ServiceID, &ep.Method, &ep.Path, &ep.OperationID, &ep.SpecJSON, &ep.SpecFile, &ep.Visibility)
+	return ep, err
+}
+
 // AddEndpoint adds a new endpoint to the database
-func AddEndpoint(db *sql.DB, endpoint Endpoint) (int64, error) {
+func AddEndpoint(db Querier, endpoint Endpoint) (int64, error) {
 	// Validate inputs
 	if endpoint.Method == "" {
 		return 0, fmt.Errorf("endpoint method cannot be empty")
@@ -294,14 +410,15 @@ func AddEndpoint(db *sql.DB, endpoint Endpoint) (int64, error) {
 		"DELETE":  true,
 		"HEAD":    true,
 		"OPTIONS": true,
+		"TRACE":   true,
 	}
 	if !validMethods[strings.ToUpper(endpoint.Method)] {
 		return 0, fmt.Errorf("invalid HTTP method: %s", endpoint.Method)
 	}
 
 	result, err := db.Exec(
-		"INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)",
-		endpoint.ServiceID, endpoint.Method, endpoint.Path, endpoint.OperationID, endpoint.SpecJSON,
+		"INSERT INTO endpoints (service_id, method, path, operation_id, spec_json, spec_file, visibility) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		endpoint.ServiceID, endpoint.Method, endpoint.Path, endpoint.OperationID, endpoint.SpecJSON, endpoint.SpecFile, endpoint.Visibility,
 	)
 	if err != nil {
 		return 0, err
@@ -310,10 +427,33 @@ func AddEndpoint(db *sql.DB, endpoint Endpoint) (int64, error) {
 	return result.LastInsertId()
 }
 
+// UpdateEndpoint updates an endpoint's scanned fields
+func UpdateEndpoint(db Querier, endpoint Endpoint) error {
+	if endpoint.ID == 0 {
+		return fmt.Errorf("endpoint id cannot be empty")
+	}
+
+	_, err := db.Exec(
+		"UPDATE endpoints SET operation_id = ?, spec_json = ?, spec_file = ?, visibility = ? WHERE id = ?",
+		endpoint.OperationID, endpoint.SpecJSON, endpoint.SpecFile, endpoint.Visibility, endpoint.ID,
+	)
+	return err
+}
+
+// DeleteEndpoint deletes an endpoint along with its saved requests and history
+func DeleteEndpoint(db Querier, id int64) error {
+	if id == 0 {
+		return fmt.Errorf("endpoint id cannot be empty")
+	}
+
+	_, err := db.Exec("DELETE FROM endpoints WHERE id = ?", id)
+	return err
+}
+
 // GetEndpointsByService retrieves all endpoints for a service
-func GetEndpointsByService(db *sql.DB, serviceID int64) ([]Endpoint, error) {
+func GetEndpointsByService(db Querier, serviceID int64) ([]Endpoint, error) {
 	rows, err := db.Query(
-		"SELECT id, service_id, method, path, operation_id, spec_json FROM endpoints WHERE service_id = ? ORDER BY path, method",
+		"SELECT id, service_id, method, path, operation_id, spec_json, spec_file, visibility FROM endpoints WHERE service_id = ? ORDER BY path, method",
 		serviceID,
 	)
 	if err != nil {
@@ -325,7 +465,7 @@ func GetEndpointsByService(db *sql.DB, serviceID int64) ([]Endpoint, error) {
 	endpoints := []Endpoint{}
 	for rows.Next() {
 		var ep Endpoint
-		if err := rows.Scan(&ep.ID, &ep.ServiceID, &ep.Method, &ep.Path, &ep.OperationID, &ep.SpecJSON); err != nil {
+		if err := rows.Scan(&ep.ID, &ep.ServiceID, &ep.Method, &ep.Path, &ep.OperationID, &ep.SpecJSON, &ep.SpecFile, &ep.Visibility); err != nil {
 			return nil, err
 		}
 		endpoints = append(endpoints, ep)
@@ -340,9 +480,9 @@ func GetEndpointsByService(db *sql.DB, serviceID int64) ([]Endpoint, error) {
 }
 
 // GetAllEndpoints retrieves all endpoints from the database
-func GetAllEndpoints(db *sql.DB) ([]Endpoint, error) {
+func GetAllEndpoints(db Querier) ([]Endpoint, error) {
 	rows, err := db.Query(
-		"SELECT id, service_id, method, path, operation_id, spec_json FROM endpoints ORDER BY path, method",
+		"SELECT id, service_id, method, path, operation_id, spec_json, spec_file, visibility FROM endpoints ORDER BY path, method",
 	)
 	if err != nil {
 		return nil, err
@@ -352,7 +492,7 @@ func GetAllEndpoints(db *sql.DB) ([]Endpoint, error) {
 	endpoints := []Endpoint{}
 	for rows.Next() {
 		var ep Endpoint
-		if err := rows.Scan(&ep.ID, &ep.ServiceID, &ep.Method, &ep.Path, &ep.OperationID, &ep.SpecJSON); err != nil {
+		if err := rows.Scan(&ep.ID, &ep.ServiceID, &ep.Method, &ep.Path, &ep.OperationID, &ep.SpecJSON, &ep.SpecFile, &ep.Visibility); err != nil {
 			return nil, err
 		}
 		endpoints = append(endpoints, ep)
@@ -366,7 +506,7 @@ func GetAllEndpoints(db *sql.DB) ([]Endpoint, error) {
 }
 
 // AddRequest adds a new request to the database
-func AddRequest(db *sql.DB, request Request) (int64, error) {
+func AddRequest(db Querier, request Request) (int64, error) {
 	// Validate inputs
 	if request.Environment == "" {
 		return 0, fmt.Errorf("environment cannot be empty")
@@ -384,7 +524,7 @@ func AddRequest(db *sql.DB, request Request) (int64, error) {
 }
 
 // GetRequestHistory retrieves request history for an endpoint
-func GetRequestHistory(db *sql.DB, endpointID int64, limit int) ([]Request, error) {
+func GetRequestHistory(db Querier, endpointID int64, limit int) ([]Request, error) {
 	rows, err := db.Query(
 		`SELECT id, endpoint_id, environment, headers, body, response, created_at
 		FROM requests
@@ -417,7 +557,7 @@ func GetRequestHistory(db *sql.DB, endpointID int64, limit int) ([]Request, erro
 }
 
 // AddSavedRequest adds a new saved request to the database
-func AddSavedRequest(db *sql.DB, savedRequest SavedRequest) (int64, error) {
+func AddSavedRequest(db Querier, savedRequest SavedRequest) (int64, error) {
 	// Validate inputs
 	if savedRequest.Name == "" {
 		return 0, fmt.Errorf("saved request name cannot be empty")
@@ -426,9 +566,16 @@ func AddSavedRequest(db *sql.DB, savedRequest SavedRequest) (int64, error) {
 		return 0, fmt.Errorf("endpoint_id cannot be empty")
 	}
 
+	if savedRequest.ExtractionsJSON == "" {
+		savedRequest.ExtractionsJSON = "[]"
+	}
+	if savedRequest.AssertionsJSON == "" {
+		savedRequest.AssertionsJSON = "[]"
+	}
+
 	result, err := db.Exec(
-		"INSERT INTO saved_requests (endpoint_id, name, path_params_json, query_params_json, headers_json, body) VALUES (?, ?, ?, ?, ?, ?)",
-		savedRequest.EndpointID, savedRequest.Name, savedRequest.PathParamsJSON, savedRequest.QueryParamsJSON, savedRequest.HeadersJSON, savedRequest.Body,
+		"INSERT INTO saved_requests (endpoint_id, name, path_params_json, query_params_json, headers_json, body, extractions_json, assertions_json) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		savedRequest.EndpointID, savedRequest.Name, savedRequest.PathParamsJSON, savedRequest.QueryParamsJSON, savedRequest.HeadersJSON, savedRequest.Body, savedRequest.ExtractionsJSON, savedRequest.AssertionsJSON,
 	)
 	if err != nil {
 		return 0, err
@@ -438,9 +585,9 @@ func AddSavedRequest(db *sql.DB, savedRequest SavedRequest) (int64, error) {
 }
 
 // GetSavedRequestsByEndpoint retrieves all saved requests for an endpoint
-func GetSavedRequestsByEndpoint(db *sql.DB, endpointID int64) ([]SavedRequest, error) {
+func GetSavedRequestsByEndpoint(db Querier, endpointID int64) ([]SavedRequest, error) {
 	rows, err := db.Query(
-		`SELECT id, endpoint_id, name, path_params_json, query_params_json, headers_json, body, created_at
+		`SELECT id, endpoint_id, name, path_params_json, query_params_json, headers_json, body, extractions_json, assertions_json, created_at
 		FROM saved_requests
 		WHERE endpoint_id = ?
 		ORDER BY created_at DESC`,
@@ -455,7 +602,7 @@ func GetSavedRequestsByEndpoint(db *sql.DB, endpointID int64) ([]SavedRequest, e
 	savedRequests := []SavedRequest{}
 	for rows.Next() {
 		var req SavedRequest
-		if err := rows.Scan(&req.ID, &req.EndpointID, &req.Name, &req.PathParamsJSON, &req.QueryParamsJSON, &req.HeadersJSON, &req.Body, &req.CreatedAt); err != nil {
+		if err := rows.Scan(&req.ID, &req.EndpointID, &req.Name, &req.PathParamsJSON, &req.QueryParamsJSON, &req.HeadersJSON, &req.Body, &req.ExtractionsJSON, &req.AssertionsJSON, &req.CreatedAt); err != nil {
 			return nil, err
 		}
 		savedRequests = append(savedRequests, req)
@@ -470,7 +617,7 @@ func GetSavedRequestsByEndpoint(db *sql.DB, endpointID int64) ([]SavedRequest, e
 }
 
 // UpdateSavedRequest updates an existing saved request
-func UpdateSavedRequest(db *sql.DB, savedRequest SavedRequest) error {
+func UpdateSavedRequest(db Querier, savedRequest SavedRequest) error {
 	// Validate inputs
 	if savedRequest.ID == 0 {
 		return fmt.Errorf("saved request id cannot be empty")
@@ -479,15 +626,28 @@ func UpdateSavedRequest(db *sql.DB, savedRequest SavedRequest) error {
 		return fmt.Errorf("saved request name cannot be empty")
 	}
 
+	// An empty ExtractionsJSON or AssertionsJSON keeps the stored rules, so clients unaware of them don't wipe them
 	_, err := db.Exec(
-		"UPDATE saved_requests SET name = ?, path_params_json = ?, query_params_json = ?, headers_json = ?, body = ? WHERE id = ?",
-		savedRequest.Name, savedRequest.PathParamsJSON, savedRequest.QueryParamsJSON, savedRequest.HeadersJSON, savedRequest.Body, savedRequest.ID,
+		"UPDATE saved_requests SET name = ?, path_params_json = ?, query_params_json = ?, headers_json = ?, body = ?, extractions_json = COALESCE(NULLIF(?, ''), extractions_json), assertions_json = COALESCE(NULLIF(?, ''), assertions_json) WHERE id = ?",
+		savedRequest.Name, savedRequest.PathParamsJSON, savedRequest.QueryParamsJSON, savedRequest.HeadersJSON, savedRequest.Body, savedRequest.ExtractionsJSON, savedRequest.AssertionsJSON, savedRequest.ID,
 	)
 	return err
 }
 
+// GetSavedRequest retrieves a single saved request by ID
+func GetSavedRequest(db Querier, id int64) (SavedRequest, error) {
+	var req SavedRequest
+	err := db.QueryRow(
+		`SELECT id, endpoint_id, name, path_params_json, query_params_json, headers_json, body, extractions_json, assertions_json, created_at
+		FROM saved_requests
+		WHERE id = ?`,
+		id,
+	).Scan(&req.ID, &req.EndpointID, &req.Name, &req.PathParamsJSON, &req.QueryParamsJSON, &req.HeadersJSON, &req.Body, &req.ExtractionsJSON, &req.AssertionsJSON, &req.CreatedAt)
+	return req, err
+}
+
 // DeleteSavedRequest deletes a saved request from the database
-func DeleteSavedRequest(db *sql.DB, id int64) error {
+func DeleteSavedRequest(db Querier, id int64) error {
 	if id == 0 {
 		return fmt.Errorf("saved request id cannot be empty")
 	}
@@ -497,9 +657,9 @@ func DeleteSavedRequest(db *sql.DB, id int64) error {
 }
 
 // GetAllSavedRequests retrieves all saved requests from the database
-func GetAllSavedRequests(db *sql.DB) ([]SavedRequest, error) {
+func GetAllSavedRequests(db Querier) ([]SavedRequest, error) {
 	rows, err := db.Query(
-		`SELECT id, endpoint_id, name, path_params_json, query_params_json, headers_json, body, created_at
+		`SELECT id, endpoint_id, name, path_params_json, query_params_json, headers_json, body, extractions_json, assertions_json, created_at
 		FROM saved_requests
 		ORDER BY created_at DESC`,
 	)
@@ -511,7 +671,7 @@ func GetAllSavedRequests(db *sql.DB) ([]SavedRequest, error) {
 	savedRequests := []SavedRequest{}
 	for rows.Next() {
 		var req SavedRequest
-		if err := rows.Scan(&req.ID, &req.EndpointID, &req.Name, &req.PathParamsJSON, &req.QueryParamsJSON, &req.HeadersJSON, &req.Body, &req.CreatedAt); err != nil {
+		if err := rows.Scan(&req.ID, &req.EndpointID, &req.Name, &req.PathParamsJSON, &req.QueryParamsJSON, &req.HeadersJSON, &req.Body, &req.ExtractionsJSON, &req.AssertionsJSON, &req.CreatedAt); err != nil {
 			return nil, err
 		}
 		savedRequests = append(savedRequests, req)
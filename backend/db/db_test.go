@@ -1,6 +1,7 @@
 package db
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"encoding/json"
@@ -418,3 +419,158 @@ func TestDeleteSavedRequest(t *testing.T) {
 		t.Errorf("Expected saved request to be deleted, but found %d", count)
 	}
 }
+
+func TestInitDB_AddsMissingColumns(t *testing.T) {
+	dbPath := t.TempDir() + "/legacy.db"
+
+	// A database from before versioning, with a saved_requests table from
+	// before extraction rules existed
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := legacy.Exec(baselineSchema); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	_, err = legacy.Exec(`
+		INSERT INTO repositories (name, path) VALUES ('repo', '/tmp/repo');
+		INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (1, 'orders', 'Orders', 8080, '{}');
+		INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (1, 'GET', '/orders', 'listOrders', '{}');
+		INSERT INTO saved_requests (endpoint_id, name) VALUES (1, 'legacy');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+	legacy.Close()
+
+	database, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer database.Close()
+
+	req, err := GetSavedRequest(database, 1)
+	if err != nil {
+		t.Fatalf("Failed to read legacy saved request: %v", err)
+	}
+	if req.ExtractionsJSON != "[]" {
+		t.Errorf("Expected default extractions '[]', got %q", req.ExtractionsJSON)
+	}
+}
+
+func TestUpdateSavedRequest_KeepsRules(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	repoID, _ := AddRepository(database, Repository{Name: "test-repo", Path: "/test"})
+	serviceID, _ := AddService(database, Service{RepoID: repoID, ServiceID: "orders", Name: "Orders", Port: 8080, ConfigJSON: "{}"})
+	endpointID, _ := AddEndpoint(database, Endpoint{ServiceID: serviceID, Method: "POST", Path: "/orders", OperationID: "createOrder", SpecJSON: "{}"})
+
+	id, err := AddSavedRequest(database, SavedRequest{
+		EndpointID:      endpointID,
+		Name:            "Create order",
+		ExtractionsJSON: `[{"variable":"orderId","source":"body","path":"$.id"}]`,
+		AssertionsJSON:  `[{"type":"statusEquals","expected":"201"}]`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add saved request: %v", err)
+	}
+
+	if err := UpdateSavedRequest(database, SavedRequest{ID: id, Name: "Renamed"}); err != nil {
+		t.Fatalf("Failed to update saved request: %v", err)
+	}
+
+	req, _ := GetSavedRequest(database, id)
+	if req.Name != "Renamed" || req.ExtractionsJSON != `[{"variable":"orderId","source":"body","path":"$.id"}]` {
+		t.Errorf("Expected rename to keep extraction rules, got %+v", req)
+	}
+	if req.AssertionsJSON != `[{"type":"statusEquals","expected":"201"}]` {
+		t.Errorf("Expected rename to keep assertions, got %q", req.AssertionsJSON)
+	}
+}
+
+func TestInitDB_ConnectionSettings(t *testing.T) {
+	database, err := InitDB(t.TempDir() + "/settings.db")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	var foreignKeys, busyTimeout int
+	var journalMode string
+	database.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys)
+	database.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout)
+	database.QueryRow("PRAGMA journal_mode").Scan(&journalMode)
+	if foreignKeys != 1 || busyTimeout != 5000 || journalMode != "wal" {
+		t.Errorf("Expected foreign keys, a 5s busy timeout and WAL, got foreign_keys=%d busy_timeout=%d journal_mode=%s", foreignKeys, busyTimeout, journalMode)
+	}
+}
+
+func TestDeleteRepository_Cascades(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	repoID, _ := AddRepository(database, Repository{Name: "test-repo", Path: "/test"})
+	serviceID, _ := AddService(database, Service{RepoID: repoID, ServiceID: "orders", Name: "Orders", Port: 8080, ConfigJSON: "{}"})
+	endpointID, _ := AddEndpoint(database, Endpoint{ServiceID: serviceID, Method: "GET", Path: "/orders", OperationID: "listOrders", SpecJSON: "{}"})
+	if _, err := AddSavedRequest(database, SavedRequest{EndpointID: endpointID, Name: "List"}); err != nil {
+		t.Fatalf("Failed to add saved request: %v", err)
+	}
+	if _, err := AddRequest(database, Request{EndpointID: endpointID, Environment: "LOCAL"}); err != nil {
+		t.Fatalf("Failed to add request: %v", err)
+	}
+
+	if _, err := database.Exec("DELETE FROM repositories WHERE id = ?", repoID); err != nil {
+		t.Fatalf("Failed to delete repository: %v", err)
+	}
+	for _, table := range []string{"services", "endpoints", "saved_requests", "requests"} {
+		var count int
+		database.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+		if count != 0 {
+			t.Errorf("Expected %s to be emptied by the cascade, found %d rows", table, count)
+		}
+	}
+
+	// Rows can't point at parents that don't exist
+	if _, err := AddService(database, Service{RepoID: repoID, ServiceID: "orphan", Name: "Orphan", Port: 8080, ConfigJSON: "{}"}); err == nil {
+		t.Error("Expected a service for a missing repository to be rejected")
+	}
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	err = WithTx(database, func(tx *sql.Tx) error {
+		if _, err := AddRepository(tx, Repository{Name: "a", Path: "/a"}); err != nil {
+			return err
+		}
+		_, err := AddRepository(tx, Repository{Name: "b", Path: "/a"}) // duplicate path
+		return err
+	})
+	if err == nil {
+		t.Fatal("Expected the duplicate path to fail the transaction")
+	}
+
+	repos, _ := GetRepositories(database)
+	if len(repos) != 0 {
+		t.Errorf("Expected the first insert to be rolled back, got %+v", repos)
+	}
+
+	err = WithTx(database, func(tx *sql.Tx) error {
+		_, err := AddRepository(tx, Repository{Name: "a", Path: "/a"})
+		return err
+	})
+	if repos, _ := GetRepositories(database); err != nil || len(repos) != 1 {
+		t.Errorf("Expected the repository to be committed, got %+v (%v)", repos, err)
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+)
+
+// validateEnvironment checks required fields and fills in JSON defaults
+func validateEnvironment(env *Environment) error {
+	if env.Name == "" {
+		return fmt.Errorf("environment name cannot be empty")
+	}
+	if env.BaseURLTemplate == "" {
+		return fmt.Errorf("environment base URL template cannot be empty")
+	}
+	if env.VariablesJSON == "" {
+		env.VariablesJSON = "{}"
+	}
+	if env.ServiceOverridesJSON == "" {
+		env.ServiceOverridesJSON = "{}"
+	}
+	return nil
+}
+
+// AddEnvironment adds a new user-defined environment
+func AddEnvironment(db Querier, env Environment) (int64, error) {
+	if err := validateEnvironment(&env); err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO environments (name, base_url_template, variables_json, service_overrides_json) VALUES (?, ?, ?, ?)",
+		env.Name, env.BaseURLTemplate, env.VariablesJSON, env.ServiceOverridesJSON,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetEnvironments retrieves all user-defined environments
+func GetEnvironments(db Querier) ([]Environment, error) {
+	rows, err := db.Query(
+		`SELECT id, name, base_url_template, variables_json, service_overrides_json, created_at
+		FROM environments
+		ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	environments := []Environment{}
+	for rows.Next() {
+		var env Environment
+		if err := rows.Scan(&env.ID, &env.Name, &env.BaseURLTemplate, &env.VariablesJSON, &env.ServiceOverridesJSON, &env.CreatedAt); err != nil {
+			return nil, err
+		}
+		environments = append(environments, env)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return environments, nil
+}
+
+// GetEnvironment retrieves a single environment by ID
+func GetEnvironment(db Querier, id int64) (Environment, error) {
+	var env Environment
+	err := db.QueryRow(
+		`SELECT id, name, base_url_template, variables_json, service_overrides_json, created_at
+		FROM environments
+		WHERE id = ?`,
+		id,
+	).Scan(&env.ID, &env.Name, &env.BaseURLTemplate, &env.VariablesJSON, &env.ServiceOverridesJSON, &env.CreatedAt)
+	return env, err
+}
+
+// UpdateEnvironment updates an existing environment
+func UpdateEnvironment(db Querier, env Environment) error {
+	if env.ID == 0 {
+		return fmt.Errorf("environment id cannot be empty")
+	}
+	if err := validateEnvironment(&env); err != nil {
+		return err
+	}
+
+	result, err := db.Exec(
+		"UPDATE environments SET name = ?, base_url_template = ?, variables_json = ?, service_overrides_json = ? WHERE id = ?",
+		env.Name, env.BaseURLTemplate, env.VariablesJSON, env.ServiceOverridesJSON, env.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("environment not found: %d", env.ID)
+	}
+	return nil
+}
+
+// DeleteEnvironment deletes an environment
+func DeleteEnvironment(db Querier, id int64) error {
+	if id == 0 {
+		return fmt.Errorf("environment id cannot be empty")
+	}
+
+	result, err := db.Exec("DELETE FROM environments WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("environment not found: %d", id)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestEnvironmentCRUD(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	id, err := AddEnvironment(database, Environment{
+		Name:            "preview",
+		BaseURLTemplate: "https://{{host}}/{{serviceId}}",
+		VariablesJSON:   `{"host":"preview.example.com"}`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+
+	env, err := GetEnvironment(database, id)
+	if err != nil {
+		t.Fatalf("Failed to get environment: %v", err)
+	}
+	if env.Name != "preview" || env.ServiceOverridesJSON != "{}" {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+
+	env.BaseURLTemplate = "http://{{host}}"
+	if err := UpdateEnvironment(database, env); err != nil {
+		t.Fatalf("Failed to update environment: %v", err)
+	}
+
+	environments, err := GetEnvironments(database)
+	if err != nil {
+		t.Fatalf("Failed to list environments: %v", err)
+	}
+	if len(environments) != 1 || environments[0].BaseURLTemplate != "http://{{host}}" {
+		t.Errorf("Expected updated environment, got %+v", environments)
+	}
+
+	if err := DeleteEnvironment(database, id); err != nil {
+		t.Fatalf("Failed to delete environment: %v", err)
+	}
+	environments, _ = GetEnvironments(database)
+	if len(environments) != 0 {
+		t.Errorf("Expected 0 environments after delete, got %d", len(environments))
+	}
+
+	// The environment is gone, so neither succeeds
+	if err := UpdateEnvironment(database, env); err == nil {
+		t.Error("Expected error updating a missing environment")
+	}
+	if err := DeleteEnvironment(database, id); err == nil {
+		t.Error("Expected error deleting a missing environment")
+	}
+}
+
+func TestAddEnvironment_Validation(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := AddEnvironment(database, Environment{BaseURLTemplate: "http://x"}); err == nil {
+		t.Error("Expected error for empty name")
+	}
+	if _, err := AddEnvironment(database, Environment{Name: "x"}); err == nil {
+		t.Error("Expected error for empty base URL template")
+	}
+}
@@ -0,0 +1,296 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migration is one step of the schema's history. Migrations are applied in
+// order, each in its own transaction together with its schema_migrations row.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations is the schema's history. Append new migrations with the next
+// version; never edit or reorder ones that have shipped.
+//
+// Databases created before versioning have no schema_migrations table and
+// may already have some of the early changes, so migrations up to 10 only
+// create what's missing.
+var migrations = []migration{
+	{1, "baseline schema", execSQL(baselineSchema)},
+	{2, "environments", execSQL(`
+		CREATE TABLE IF NOT EXISTS environments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			base_url_template TEXT NOT NULL,
+			variables_json TEXT NOT NULL DEFAULT '{}',
+			service_overrides_json TEXT NOT NULL DEFAULT '{}',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)},
+	{3, "variables", execSQL(`
+		CREATE TABLE IF NOT EXISTS variables (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			scope TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(scope, name)
+		);
+	`)},
+	{4, "saved request extractions", addColumn("saved_requests", "extractions_json", "TEXT NOT NULL DEFAULT '[]'")},
+	{5, "saved request assertions", addColumn("saved_requests", "assertions_json", "TEXT NOT NULL DEFAULT '[]'")},
+	{6, "runs", execSQL(`
+		CREATE TABLE IF NOT EXISTS runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			target TEXT NOT NULL,
+			environment TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			total INTEGER NOT NULL DEFAULT 0,
+			passed INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			skipped INTEGER NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			summary_json TEXT NOT NULL DEFAULT '{}',
+			started_at DATETIME,
+			finished_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)},
+	{7, "service specs", addColumn("services", "spec_json", "TEXT NOT NULL DEFAULT '{}'")},
+	{8, "endpoint sources", steps(
+		addColumn("endpoints", "spec_file", "TEXT NOT NULL DEFAULT ''"),
+		addColumn("endpoints", "visibility", "TEXT NOT NULL DEFAULT ''"),
+	)},
+	{9, "service directories and fingerprints", steps(
+		addColumn("services", "dir", "TEXT NOT NULL DEFAULT ''"),
+		addColumn("services", "files_json", "TEXT NOT NULL DEFAULT '{}'"),
+	)},
+	{10, "repository scan layouts", addColumn("repositories", "layout_json", "TEXT NOT NULL DEFAULT '{}'")},
+	// Foreign keys weren't enforced before this, so deletes didn't cascade
+	{11, "remove orphaned rows", execSQL(`
+		DELETE FROM services WHERE repo_id NOT IN (SELECT id FROM repositories);
+		DELETE FROM endpoints WHERE service_id NOT IN (SELECT id FROM services);
+		DELETE FROM requests WHERE endpoint_id NOT IN (SELECT id FROM endpoints);
+		DELETE FROM saved_requests WHERE endpoint_id NOT IN (SELECT id FROM endpoints);
+	`)},
+}
+
+// baselineSchema is the schema from before migrations existed
+const baselineSchema = `
+	CREATE TABLE IF NOT EXISTS repositories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		path TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS services (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		repo_id INTEGER NOT NULL,
+		service_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		config_json TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (repo_id) REFERENCES repositories(id) ON DELETE CASCADE,
+		UNIQUE(repo_id, service_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS endpoints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id INTEGER NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		operation_id TEXT NOT NULL,
+		spec_json TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE,
+		UNIQUE(service_id, method, path)
+	);
+
+	CREATE TABLE IF NOT EXISTS requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		endpoint_id INTEGER NOT NULL,
+		environment TEXT NOT NULL,
+		headers TEXT,
+		body TEXT,
+		response TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (endpoint_id) REFERENCES endpoints(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS saved_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		endpoint_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		path_params_json TEXT NOT NULL DEFAULT '{}',
+		query_params_json TEXT NOT NULL DEFAULT '[]',
+		headers_json TEXT NOT NULL DEFAULT '[]',
+		body TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (endpoint_id) REFERENCES endpoints(id) ON DELETE CASCADE
+	);
+`
+
+// execSQL returns a migration step that runs statements
+func execSQL(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// steps returns a migration step that runs several steps in order
+func steps(fns ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, fn := range fns {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumn returns a migration step that adds a column unless the table
+// already has it
+func addColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return err
+		}
+
+		exists := false
+		for rows.Next() {
+			var cid, notNull, pk int
+			var name, colType string
+			var defaultValue sql.NullString
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+				rows.Close()
+				return err
+			}
+			if name == column {
+				exists = true
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+		return err
+	}
+}
+
+// schemaVersion returns the highest applied migration, 0 for a database
+// that predates versioning or is new
+func schemaVersion(db *sql.DB) (int, error) {
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables); err != nil || tables == 0 {
+		return 0, err
+	}
+
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// migrate brings the database at dbPath up to the latest of list. A database
+// with existing tables is backed up next to dbPath first. A database from a
+// newer release is refused rather than opened with a schema we don't know.
+func migrate(db *sql.DB, dbPath string, list []migration) error {
+	current, err := schemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	latest := 0
+	if len(list) > 0 {
+		latest = list[len(list)-1].version
+	}
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this version of PostWhale supports (%d); please upgrade PostWhale", current, latest)
+	}
+
+	var pending []migration
+	for _, m := range list {
+		if m.version > current {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := backupBeforeMigrating(db, dbPath, current); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration and records it in a single transaction
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backupBeforeMigrating copies a file database that has tables to
+// <dbPath>.v<version>-<timestamp>.bak. New and in-memory databases have
+// nothing to lose and aren't backed up.
+func backupBeforeMigrating(db *sql.DB, dbPath string, version int) error {
+	if dbPath == ":memory:" || strings.HasPrefix(dbPath, "file:") {
+		return nil
+	}
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", dbPath, version, time.Now().Format("20060102-150405"))
+	if _, err := db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+	return nil
+}
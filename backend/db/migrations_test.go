@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openBaseline creates a database with the schema from before migrations
+// existed and one row per table
+func openBaseline(t *testing.T, dbPath string) {
+	t.Helper()
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer legacy.Close()
+
+	_, err = legacy.Exec(baselineSchema + `
+		INSERT INTO repositories (name, path) VALUES ('repo', '/tmp/repo');
+		INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (1, 'orders', 'Orders', 8080, '{}');
+		INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (1, 'GET', '/orders', 'listOrders', '{}');
+		INSERT INTO saved_requests (endpoint_id, name) VALUES (1, 'List');
+
+		-- Left behind by deletes while foreign keys were off
+		INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (99, 'GET', '/gone', 'gone', '{}');
+		INSERT INTO saved_requests (endpoint_id, name) VALUES (99, 'Gone');
+		INSERT INTO requests (endpoint_id, environment) VALUES (99, 'LOCAL');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create baseline schema: %v", err)
+	}
+}
+
+func TestInitDB_UpgradesBaselineSchema(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "postwhale.db")
+	openBaseline(t, dbPath)
+
+	database, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to migrate baseline database: %v", err)
+	}
+	defer database.Close()
+
+	version, err := schemaVersion(database)
+	if err != nil || version != migrations[len(migrations)-1].version {
+		t.Errorf("Expected version %d, got %d (%v)", migrations[len(migrations)-1].version, version, err)
+	}
+
+	// Existing rows survive and read back with every current column
+	repos, err := GetRepositories(database)
+	if err != nil || len(repos) != 1 || repos[0].LayoutJSON != "{}" {
+		t.Errorf("Expected the repository with a default layout, got %+v (%v)", repos, err)
+	}
+	services, err := GetServicesByRepo(database, 1)
+	if err != nil || len(services) != 1 || services[0].SpecJSON != "{}" || services[0].FilesJSON != "{}" {
+		t.Errorf("Expected the service with default spec and files, got %+v (%v)", services, err)
+	}
+	endpoints, err := GetEndpointsByService(database, 1)
+	if err != nil || len(endpoints) != 1 || endpoints[0].SpecFile != "" {
+		t.Errorf("Expected the endpoint, got %+v (%v)", endpoints, err)
+	}
+	saved, err := GetSavedRequest(database, 1)
+	if err != nil || saved.AssertionsJSON != "[]" {
+		t.Errorf("Expected the saved request with default assertions, got %+v (%v)", saved, err)
+	}
+	var orphans int
+	database.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM endpoints WHERE path = '/gone') +
+		(SELECT COUNT(*) FROM saved_requests WHERE name = 'Gone') +
+		(SELECT COUNT(*) FROM requests)`).Scan(&orphans)
+	if orphans != 0 {
+		t.Errorf("Expected orphaned rows to be removed, found %d", orphans)
+	}
+	for _, table := range []string{"environments", "variables", "runs"} {
+		if _, err := database.Exec(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)); err != nil {
+			t.Errorf("Expected table %s to exist: %v", table, err)
+		}
+	}
+
+	// The pre-migration database was backed up untouched
+	backups, _ := filepath.Glob(dbPath + ".v0-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup, got %v", backups)
+	}
+	backup, err := sql.Open("sqlite3", backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var tables int
+	backup.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('schema_migrations', 'runs')").Scan(&tables)
+	var repoCount int
+	backup.QueryRow("SELECT COUNT(*) FROM repositories").Scan(&repoCount)
+	if tables != 0 || repoCount != 1 {
+		t.Errorf("Expected the backup to hold the baseline schema and data, got %d new tables and %d repositories", tables, repoCount)
+	}
+
+	// Reopening an up-to-date database doesn't migrate or back up again
+	database.Close()
+	database, err = InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if backups, _ := filepath.Glob(dbPath + ".v*.bak"); len(backups) != 1 {
+		t.Errorf("Expected no new backup, got %v", backups)
+	}
+}
+
+func TestInitDB_NewDatabaseIsNotBackedUp(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "postwhale.db")
+	database, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	database.Close()
+
+	if backups, _ := filepath.Glob(dbPath + ".v*.bak"); len(backups) != 0 {
+		t.Errorf("Expected no backup for a new database, got %v", backups)
+	}
+}
+
+func TestInitDB_RefusesNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "postwhale.db")
+	database, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	future := migrations[len(migrations)-1].version + 1
+	if _, err := database.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')", future); err != nil {
+		t.Fatal(err)
+	}
+	database.Close()
+
+	_, err = InitDB(dbPath)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a newer schema to be refused, got %v", err)
+	}
+}
+
+func TestMigrate_RollsBackFailedMigration(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(1)
+
+	list := []migration{
+		{1, "create a", execSQL("CREATE TABLE a (id INTEGER)")},
+		{2, "create b then fail", steps(
+			execSQL("CREATE TABLE b (id INTEGER)"),
+			execSQL("INSERT INTO missing VALUES (1)"),
+		)},
+	}
+	err = migrate(database, ":memory:", list)
+	if err == nil || !strings.Contains(err.Error(), "migration 2") {
+		t.Fatalf("Expected migration 2 to fail, got %v", err)
+	}
+
+	if version, _ := schemaVersion(database); version != 1 {
+		t.Errorf("Expected version 1 after the failure, got %d", version)
+	}
+	var count int
+	database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'").Scan(&count)
+	if count != 0 {
+		t.Error("Expected the failed migration's table to be rolled back")
+	}
+
+	// Fixing the migration applies it on the next open
+	list[1].up = execSQL("CREATE TABLE b (id INTEGER)")
+	if err := migrate(database, ":memory:", list); err != nil {
+		t.Fatalf("Expected the fixed migration to apply, got %v", err)
+	}
+	if version, _ := schemaVersion(database); version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+}
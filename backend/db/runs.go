@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+)
+
+// Run statuses
+const (
+	RunPassed    = "passed"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// AddRun stores the summary of a finished collection run
+func AddRun(db Querier, run Run) (int64, error) {
+	if run.Target == "" {
+		return 0, fmt.Errorf("run target cannot be empty")
+	}
+	if run.Status == "" {
+		return 0, fmt.Errorf("run status cannot be empty")
+	}
+	if run.SummaryJSON == "" {
+		run.SummaryJSON = "{}"
+	}
+
+	result, err := db.Exec(
+		`INSERT INTO runs (target, environment, status, total, passed, failed, skipped, duration_ms, summary_json, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Target, run.Environment, run.Status, run.Total, run.Passed, run.Failed, run.Skipped, run.DurationMs, run.SummaryJSON, run.StartedAt, run.FinishedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetRuns retrieves the most recent runs, newest first, without their summaries
+func GetRuns(db Querier, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := db.Query(
+		`SELECT id, target, environment, status, total, passed, failed, skipped, duration_ms, COALESCE(started_at, ''), COALESCE(finished_at, ''), created_at
+		FROM runs ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		var run Run
+		if err := rows.Scan(&run.ID, &run.Target, &run.Environment, &run.Status, &run.Total, &run.Passed, &run.Failed, &run.Skipped, &run.DurationMs, &run.StartedAt, &run.FinishedAt, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// GetRun retrieves a single run including its full summary
+func GetRun(db Querier, id int64) (Run, error) {
+	var run Run
+	err := db.QueryRow(
+		`SELECT id, target, environment, status, total, passed, failed, skipped, duration_ms, summary_json, COALESCE(started_at, ''), COALESCE(finished_at, ''), created_at
+		FROM runs WHERE id = ?`,
+		id,
+	).Scan(&run.ID, &run.Target, &run.Environment, &run.Status, &run.Total, &run.Passed, &run.Failed, &run.Skipped, &run.DurationMs, &run.SummaryJSON, &run.StartedAt, &run.FinishedAt, &run.CreatedAt)
+	return run, err
+}
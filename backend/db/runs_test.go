@@ -0,0 +1,58 @@
+package db
+
+import "testing"
+
+func TestAddRun(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	for _, status := range []string{RunPassed, RunFailed} {
+		_, err := AddRun(database, Run{
+			Target:      "service:fusion",
+			Environment: "STAGING",
+			Status:      status,
+			Total:       2,
+			Passed:      1,
+			Failed:      1,
+			SummaryJSON: `{"total": 2}`,
+			StartedAt:   "2024-01-01T00:00:00Z",
+		})
+		if err != nil {
+			t.Fatalf("Failed to add run: %v", err)
+		}
+	}
+
+	runs, err := GetRuns(database, 10)
+	if err != nil {
+		t.Fatalf("Failed to get runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != RunFailed {
+		t.Fatalf("Expected newest run first, got %+v", runs)
+	}
+
+	run, err := GetRun(database, runs[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run.SummaryJSON != `{"total": 2}` || run.Environment != "STAGING" {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+}
+
+func TestAddRun_Validation(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := AddRun(database, Run{Status: RunPassed}); err == nil {
+		t.Error("Expected error for empty target")
+	}
+	if _, err := AddRun(database, Run{Target: "repo:1"}); err == nil {
+		t.Error("Expected error for empty status")
+	}
+}
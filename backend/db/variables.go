@@ -0,0 +1,71 @@
+package db
+
+import (
+	"fmt"
+)
+
+// GlobalScope holds variables visible to every request
+const GlobalScope = "global"
+
+// ServiceScope returns the variable scope for a tw-config serviceId
+func ServiceScope(serviceID string) string {
+	return "service:" + serviceID
+}
+
+// SavedRequestScope returns the variable scope for a saved request
+func SavedRequestScope(savedRequestID int64) string {
+	return fmt.Sprintf("request:%d", savedRequestID)
+}
+
+// SetVariable creates or replaces a variable in a scope
+func SetVariable(db Querier, scope, name, value string) error {
+	if scope == "" {
+		return fmt.Errorf("variable scope cannot be empty")
+	}
+	if name == "" {
+		return fmt.Errorf("variable name cannot be empty")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO variables (scope, name, value)
+		VALUES (?, ?, ?)
+		ON CONFLICT(scope, name) DO UPDATE SET
+			value = excluded.value,
+			updated_at = CURRENT_TIMESTAMP
+	`, scope, name, value)
+	return err
+}
+
+// GetVariables retrieves all variables in a scope as a name -> value map
+func GetVariables(db Querier, scope string) (map[string]string, error) {
+	rows, err := db.Query("SELECT name, value FROM variables WHERE scope = ? ORDER BY name", scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vars := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// DeleteVariable removes a variable from a scope
+func DeleteVariable(db Querier, scope, name string) error {
+	if scope == "" || name == "" {
+		return fmt.Errorf("variable scope and name cannot be empty")
+	}
+
+	_, err := db.Exec("DELETE FROM variables WHERE scope = ? AND name = ?", scope, name)
+	return err
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	database, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := SetVariable(database, GlobalScope, "shopId", "a.myshopify.com"); err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+	if err := SetVariable(database, GlobalScope, "shopId", "b.myshopify.com"); err != nil {
+		t.Fatalf("Failed to overwrite variable: %v", err)
+	}
+	if err := SetVariable(database, ServiceScope("fusion"), "orderId", "123"); err != nil {
+		t.Fatalf("Failed to set service variable: %v", err)
+	}
+
+	global, err := GetVariables(database, GlobalScope)
+	if err != nil {
+		t.Fatalf("Failed to get variables: %v", err)
+	}
+	if len(global) != 1 || global["shopId"] != "b.myshopify.com" {
+		t.Errorf("Expected overwritten shopId, got %v", global)
+	}
+
+	if err := DeleteVariable(database, ServiceScope("fusion"), "orderId"); err != nil {
+		t.Fatalf("Failed to delete variable: %v", err)
+	}
+	service, _ := GetVariables(database, ServiceScope("fusion"))
+	if len(service) != 0 {
+		t.Errorf("Expected no service variables after delete, got %v", service)
+	}
+
+	if err := SetVariable(database, "", "x", "y"); err == nil {
+		t.Error("Expected error for empty scope")
+	}
+}
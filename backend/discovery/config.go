@@ -2,7 +2,10 @@ package discovery
 
 import (
 	"encoding/json"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
 )
 
 // ParseTWConfig parses a tw-config.json file
@@ -19,3 +22,103 @@ func ParseTWConfig(path string) (*TWConfig, error) {
 
 	return &config, nil
 }
+
+// ResolveEndpoints flattens every deployment endpoint in the config,
+// sorted by deployment then endpoint name so the result is stable
+func (c *TWConfig) ResolveEndpoints() []DeploymentEndpoint {
+	endpoints := []DeploymentEndpoint{}
+	if c == nil {
+		return endpoints
+	}
+
+	for key, deployment := range c.Deployments {
+		name := deployment.Name
+		if name == "" {
+			name = key
+		}
+		for endpointName, endpoint := range deployment.Endpoints {
+			if endpoint.URL == "" {
+				continue
+			}
+			endpoints = append(endpoints, DeploymentEndpoint{
+				Deployment:  name,
+				Name:        endpointName,
+				Type:        endpoint.Type,
+				URL:         endpoint.URL,
+				Cluster:     endpoint.Cluster,
+				Environment: deploymentEnvironment(key, name, endpoint.URL),
+			})
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Deployment != endpoints[j].Deployment {
+			return endpoints[i].Deployment < endpoints[j].Deployment
+		}
+		return endpoints[i].Name < endpoints[j].Name
+	})
+
+	return endpoints
+}
+
+// deploymentEnvironment infers the environment of a deployment
+// Deployments whose key, name or host mention staging are STAGING; everything else is PRODUCTION
+func deploymentEnvironment(key, name, rawURL string) string {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Hostname()
+	}
+
+	// Compare whole words so names like postgres don't count as stg
+	for _, s := range []string{key, name} {
+		for _, token := range strings.FieldsFunc(strings.ToLower(s), isNameSeparator) {
+			if token == "staging" || token == "stg" {
+				return "STAGING"
+			}
+		}
+	}
+	if strings.HasPrefix(host, "stg.") || strings.HasPrefix(host, "staging.") {
+		return "STAGING"
+	}
+
+	return "PRODUCTION"
+}
+
+// isNameSeparator reports whether r separates words in a deployment key or name
+func isNameSeparator(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == ' '
+}
+
+// SelectEndpoint picks the deployment endpoint to use for a request.
+// An explicit deployment in the selector overrides environment matching;
+// otherwise only deployments for env are considered, and LOCAL never matches.
+// defaultName is the endpoint name used when the selector doesn't specify one.
+func SelectEndpoint(endpoints []DeploymentEndpoint, env string, selector EndpointSelector, defaultName string) (DeploymentEndpoint, bool) {
+	if selector.Deployment == "" && env == "LOCAL" {
+		return DeploymentEndpoint{}, false
+	}
+
+	name := selector.Endpoint
+	if name == "" {
+		name = defaultName
+	}
+
+	for _, ep := range endpoints {
+		if selector.Deployment != "" {
+			if ep.Deployment != selector.Deployment {
+				continue
+			}
+		} else if ep.Environment != env {
+			continue
+		}
+		if name != "" && ep.Name != name {
+			continue
+		}
+		if selector.Cluster != "" && ep.Cluster != selector.Cluster {
+			continue
+		}
+		return ep, true
+	}
+
+	return DeploymentEndpoint{}, false
+}
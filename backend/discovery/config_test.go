@@ -51,3 +51,87 @@ func TestParseTWConfig(t *testing.T) {
 		t.Errorf("Expected internal URL 'http://fusion.srv.whale3.io', got '%s'", internalEndpoint.URL)
 	}
 }
+
+func testDeploymentConfig() *TWConfig {
+	return &TWConfig{
+		ServiceID: "fusion",
+		Deployments: map[string]Deployment{
+			"fusion": {
+				Name: "fusion",
+				Endpoints: map[string]Endpoint{
+					"internal": {Type: "internal", URL: "http://fusion.srv.whale3.io", Cluster: "prod-1"},
+					"public":   {Type: "public", URL: "https://api.triplewhale.com/api/v2/fusion"},
+				},
+			},
+			"fusion-staging": {
+				Endpoints: map[string]Endpoint{
+					"internal": {Type: "internal", URL: "http://stg.fusion.srv.whale3.io"},
+				},
+			},
+		},
+	}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	endpoints := testDeploymentConfig().ResolveEndpoints()
+
+	if len(endpoints) != 3 {
+		t.Fatalf("Expected 3 endpoints, got %d", len(endpoints))
+	}
+
+	// Sorted by deployment, then endpoint name
+	if endpoints[0].Deployment != "fusion" || endpoints[0].Name != "internal" {
+		t.Errorf("Unexpected first endpoint: %+v", endpoints[0])
+	}
+	if endpoints[0].Environment != "PRODUCTION" {
+		t.Errorf("Expected PRODUCTION, got %s", endpoints[0].Environment)
+	}
+	if endpoints[2].Deployment != "fusion-staging" || endpoints[2].Environment != "STAGING" {
+		t.Errorf("Expected staging deployment last, got %+v", endpoints[2])
+	}
+}
+
+func TestDeploymentEnvironment(t *testing.T) {
+	for _, tc := range []struct {
+		key, name, url string
+		want           string
+	}{
+		{"fusion-staging", "", "http://fusion.srv.whale3.io", "STAGING"},
+		{"orders_stg", "", "http://orders.srv.whale3.io", "STAGING"},
+		{"orders", "Orders Staging", "http://orders.srv.whale3.io", "STAGING"},
+		{"orders", "", "http://stg.orders.srv.whale3.io", "STAGING"},
+		{"postgres", "postgres", "http://postgres.srv.whale3.io", "PRODUCTION"},
+		{"stgateway", "", "http://gateway.srv.whale3.io", "PRODUCTION"},
+	} {
+		if got := deploymentEnvironment(tc.key, tc.name, tc.url); got != tc.want {
+			t.Errorf("deploymentEnvironment(%q, %q) = %s, want %s", tc.key, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSelectEndpoint(t *testing.T) {
+	endpoints := testDeploymentConfig().ResolveEndpoints()
+
+	ep, ok := SelectEndpoint(endpoints, "STAGING", EndpointSelector{}, "internal")
+	if !ok || ep.URL != "http://stg.fusion.srv.whale3.io" {
+		t.Errorf("Expected staging internal endpoint, got %+v (ok=%v)", ep, ok)
+	}
+
+	ep, ok = SelectEndpoint(endpoints, "PRODUCTION", EndpointSelector{}, "public")
+	if !ok || ep.URL != "https://api.triplewhale.com/api/v2/fusion" {
+		t.Errorf("Expected production public endpoint, got %+v (ok=%v)", ep, ok)
+	}
+
+	if _, ok := SelectEndpoint(endpoints, "LOCAL", EndpointSelector{}, "internal"); ok {
+		t.Error("Expected LOCAL to fall back to the local proxy")
+	}
+
+	ep, ok = SelectEndpoint(endpoints, "LOCAL", EndpointSelector{Deployment: "fusion", Cluster: "prod-1"}, "internal")
+	if !ok || ep.Cluster != "prod-1" {
+		t.Errorf("Expected explicit deployment to override environment, got %+v (ok=%v)", ep, ok)
+	}
+
+	if _, ok := SelectEndpoint(endpoints, "STAGING", EndpointSelector{}, "public"); ok {
+		t.Error("Expected no match when staging has no public endpoint")
+	}
+}
@@ -2,39 +2,75 @@ package discovery
 
 import (
 	"os"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
 
 // OpenAPISpec represents a simplified OpenAPI 3.0 spec
 type OpenAPISpec struct {
-	OpenAPI    string              `yaml:"openapi"`
-	Info       Info                `yaml:"info"`
-	Servers    []Server            `yaml:"servers"`
-	Paths      map[string]PathItem `yaml:"paths"`
-	Components Components          `yaml:"components"`
+	OpenAPI    string                `yaml:"openapi"`
+	Info       Info                  `yaml:"info"`
+	Servers    []Server              `yaml:"servers"`
+	Paths      map[string]PathItem   `yaml:"paths"`
+	Components Components            `yaml:"components"`
+	Security   []SecurityRequirement `yaml:"security"`
+
+	// Warnings lists $ref references that couldn't be resolved
+	Warnings []string `yaml:"-"`
+	// Files lists every document read while parsing: the spec itself and the
+	// files its $refs point to, as sorted absolute paths
+	Files []string `yaml:"-"`
 }
 
 // Info represents OpenAPI info section
 type Info struct {
-	Title       string `yaml:"title"`
-	Description string `yaml:"description"`
-	Version     string `yaml:"version"`
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Version     string `yaml:"version" json:"version"`
 }
 
 // Server represents OpenAPI server
 type Server struct {
-	URL         string `yaml:"url"`
-	Description string `yaml:"description"`
+	URL         string `yaml:"url" json:"url"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// SecurityScheme represents an OpenAPI security scheme
+type SecurityScheme struct {
+	Type             string               `yaml:"type" json:"type"`
+	Description      string               `yaml:"description" json:"description,omitempty"`
+	Name             string               `yaml:"name" json:"name,omitempty"`     // apiKey
+	In               string               `yaml:"in" json:"in,omitempty"`         // apiKey: header, query or cookie
+	Scheme           string               `yaml:"scheme" json:"scheme,omitempty"` // http: bearer, basic
+	BearerFormat     string               `yaml:"bearerFormat" json:"bearerFormat,omitempty"`
+	Flows            map[string]OAuthFlow `yaml:"flows" json:"flows,omitempty"`
+	OpenIDConnectURL string               `yaml:"openIdConnectUrl" json:"openIdConnectUrl,omitempty"`
 }
 
-// PathItem represents an OpenAPI path item
+// OAuthFlow represents an OAuth2 flow of a security scheme
+type OAuthFlow struct {
+	AuthorizationURL string            `yaml:"authorizationUrl" json:"authorizationUrl,omitempty"`
+	TokenURL         string            `yaml:"tokenUrl" json:"tokenUrl,omitempty"`
+	RefreshURL       string            `yaml:"refreshUrl" json:"refreshUrl,omitempty"`
+	Scopes           map[string]string `yaml:"scopes" json:"scopes,omitempty"`
+}
+
+// PathItem represents an OpenAPI path item. Summary, description, servers
+// and parameters apply to every operation on the path.
 type PathItem struct {
-	Get    *Operation `yaml:"get"`
-	Post   *Operation `yaml:"post"`
-	Put    *Operation `yaml:"put"`
-	Delete *Operation `yaml:"delete"`
-	Patch  *Operation `yaml:"patch"`
+	Summary     string        `yaml:"summary"`
+	Description string        `yaml:"description"`
+	Servers     []Server      `yaml:"servers"`
+	Parameters  []OAParameter `yaml:"parameters"`
+	Get         *Operation    `yaml:"get"`
+	Post        *Operation    `yaml:"post"`
+	Put         *Operation    `yaml:"put"`
+	Delete      *Operation    `yaml:"delete"`
+	Patch       *Operation    `yaml:"patch"`
+	Head        *Operation    `yaml:"head"`
+	Options     *Operation    `yaml:"options"`
+	Trace       *Operation    `yaml:"trace"`
 }
 
 // Operation represents an OpenAPI operation
@@ -43,64 +79,224 @@ type Operation struct {
 	Summary     string                `yaml:"summary"`
 	Description string                `yaml:"description"`
 	Tags        []string              `yaml:"tags"`
+	Deprecated  bool                  `yaml:"deprecated"`
 	Parameters  []OAParameter         `yaml:"parameters"`
 	RequestBody *OARequestBody        `yaml:"requestBody"`
 	Responses   map[string]OAResponse `yaml:"responses"`
+	Security    []SecurityRequirement `yaml:"security"`
+	Servers     []Server              `yaml:"servers"`
 }
 
 // OAParameter represents OpenAPI parameter
 type OAParameter struct {
-	Name     string   `yaml:"name"`
-	In       string   `yaml:"in"`
-	Required bool     `yaml:"required"`
-	Schema   OASchema `yaml:"schema"`
+	Name        string               `yaml:"name"`
+	In          string               `yaml:"in"`
+	Description string               `yaml:"description"`
+	Required    bool                 `yaml:"required"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Schema      OASchema             `yaml:"schema"`
+	Example     interface{}          `yaml:"example"`
+	Examples    map[string]OAExample `yaml:"examples"`
 }
 
 // OARequestBody represents OpenAPI request body
 type OARequestBody struct {
-	Required bool                   `yaml:"required"`
-	Content  map[string]OAMediaType `yaml:"content"`
+	Description string                 `yaml:"description"`
+	Required    bool                   `yaml:"required"`
+	Content     map[string]OAMediaType `yaml:"content"`
 }
 
 // OAMediaType represents OpenAPI media type
 type OAMediaType struct {
-	Schema  OASchema    `yaml:"schema"`
-	Example interface{} `yaml:"example"`
+	Schema   OASchema             `yaml:"schema"`
+	Example  interface{}          `yaml:"example"`
+	Examples map[string]OAExample `yaml:"examples"`
+}
+
+// OAExample represents a named OpenAPI example
+type OAExample struct {
+	Summary string      `yaml:"summary"`
+	Value   interface{} `yaml:"value"`
 }
 
 // OAResponse represents OpenAPI response
 type OAResponse struct {
 	Description string                 `yaml:"description"`
 	Content     map[string]OAMediaType `yaml:"content"`
+	Headers     map[string]OAHeader    `yaml:"headers"`
+}
+
+// OAHeader represents an OpenAPI response header
+type OAHeader struct {
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Schema      OASchema `yaml:"schema"`
 }
 
 // OASchema represents OpenAPI schema (simplified)
 type OASchema struct {
-	Type       string              `yaml:"type"`
-	Format     string              `yaml:"format"`
-	Required   []string            `yaml:"required"`
-	Properties map[string]OASchema `yaml:"properties"`
-	Items      *OASchema           `yaml:"items"`
-	Example    interface{}         `yaml:"example"`
-	Ref        string              `yaml:"$ref"`
+	Type                 string                  `yaml:"type"`
+	Format               string                  `yaml:"format"`
+	Description          string                  `yaml:"description"`
+	Required             []string                `yaml:"required"`
+	Properties           map[string]OASchema     `yaml:"properties"`
+	AdditionalProperties *OAAdditionalProperties `yaml:"additionalProperties"`
+	Items                *OASchema               `yaml:"items"`
+	AllOf                []OASchema              `yaml:"allOf"`
+	OneOf                []OASchema              `yaml:"oneOf"`
+	AnyOf                []OASchema              `yaml:"anyOf"`
+	Not                  *OASchema               `yaml:"not"`
+	Discriminator        *OADiscriminator        `yaml:"discriminator"`
+	Enum                 []interface{}           `yaml:"enum"`
+	Nullable             bool                    `yaml:"nullable"`
+	Minimum              *float64                `yaml:"minimum"`
+	Maximum              *float64                `yaml:"maximum"`
+	ExclusiveMinimum     OAExclusiveBound        `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum     OAExclusiveBound        `yaml:"exclusiveMaximum"`
+	MinLength            *int                    `yaml:"minLength"`
+	MaxLength            *int                    `yaml:"maxLength"`
+	MinItems             *int                    `yaml:"minItems"`
+	MaxItems             *int                    `yaml:"maxItems"`
+	Pattern              string                  `yaml:"pattern"`
+	Default              interface{}             `yaml:"default"`
+	ReadOnly             bool                    `yaml:"readOnly"`
+	WriteOnly            bool                    `yaml:"writeOnly"`
+	Example              interface{}             `yaml:"example"`
+	Ref                  string                  `yaml:"$ref"`
+
+	// XNullable is the Swagger 2.0 vendor extension for nullable
+	XNullable bool `yaml:"x-nullable"`
+}
+
+// OAAdditionalProperties is the additionalProperties keyword, which is
+// either a boolean or a schema for the extra values
+type OAAdditionalProperties struct {
+	Allowed bool
+	Schema  *OASchema
+}
+
+// UnmarshalYAML accepts both forms of additionalProperties
+func (a *OAAdditionalProperties) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!bool" {
+		return node.Decode(&a.Allowed)
+	}
+
+	var schema OASchema
+	if err := node.Decode(&schema); err != nil {
+		return err
+	}
+	a.Allowed = true
+	a.Schema = &schema
+	return nil
+}
+
+// OAExclusiveBound is exclusiveMinimum or exclusiveMaximum: a boolean that
+// makes minimum/maximum exclusive in OpenAPI 3.0 and Swagger 2.0, or the
+// exclusive bound itself in OpenAPI 3.1
+type OAExclusiveBound struct {
+	Exclusive bool
+	Value     *float64
+}
+
+// UnmarshalYAML accepts both forms of exclusiveMinimum and exclusiveMaximum
+func (b *OAExclusiveBound) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!bool" {
+		return node.Decode(&b.Exclusive)
+	}
+
+	var value float64
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	b.Exclusive = true
+	b.Value = &value
+	return nil
+}
+
+// exclusiveMinimum folds minimum and exclusiveMinimum into the 3.0 form
+// Schema uses; with both given in 3.1 form the stricter bound wins
+func exclusiveMinimum(minimum *float64, bound OAExclusiveBound) (*float64, bool) {
+	if bound.Value == nil {
+		return minimum, bound.Exclusive
+	}
+	if minimum != nil && *minimum > *bound.Value {
+		return minimum, false
+	}
+	return bound.Value, true
+}
+
+// exclusiveMaximum is exclusiveMinimum for the upper bound
+func exclusiveMaximum(maximum *float64, bound OAExclusiveBound) (*float64, bool) {
+	if bound.Value == nil {
+		return maximum, bound.Exclusive
+	}
+	if maximum != nil && *maximum < *bound.Value {
+		return maximum, false
+	}
+	return bound.Value, true
+}
+
+// OADiscriminator represents an OpenAPI discriminator object
+type OADiscriminator struct {
+	PropertyName string            `yaml:"propertyName"`
+	Mapping      map[string]string `yaml:"mapping"`
+}
+
+// UnmarshalYAML also accepts the Swagger 2.0 form, where the discriminator
+// is just the property name
+func (d *OADiscriminator) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&d.PropertyName)
+	}
+
+	type plain OADiscriminator
+	return node.Decode((*plain)(d))
 }
 
 // Components represents OpenAPI components section
 type Components struct {
-	Schemas map[string]OASchema `yaml:"schemas"`
+	Schemas         map[string]OASchema       `yaml:"schemas"`
+	Parameters      map[string]OAParameter    `yaml:"parameters"`
+	RequestBodies   map[string]OARequestBody  `yaml:"requestBodies"`
+	Responses       map[string]OAResponse     `yaml:"responses"`
+	Headers         map[string]OAHeader       `yaml:"headers"`
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
 }
 
-// ParseOpenAPI parses an OpenAPI YAML file
+// ParseOpenAPI parses an OpenAPI YAML or JSON file, inlining $ref references
+// to components and to relative files. Swagger 2.0 documents are converted to
+// the OpenAPI 3 model. References that can't be resolved are reported in
+// spec.Warnings rather than failing the parse.
 func ParseOpenAPI(path string) (*OpenAPISpec, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
 	var spec OpenAPISpec
-	if err := yaml.Unmarshal(data, &spec); err != nil {
+	if len(root.Content) == 0 {
+		spec.Files = []string{absPath(path)}
+		return &spec, nil
+	}
+
+	resolver := newRefResolver(path, &root)
+	doc := resolver.resolveDocument(path)
+	if isSwagger(doc) {
+		var sw SwaggerSpec
+		if err := doc.Decode(&sw); err != nil {
+			return nil, err
+		}
+		spec = *ConvertSwagger(&sw)
+	} else if err := doc.Decode(&spec); err != nil {
 		return nil, err
 	}
+	spec.Warnings = resolver.warnings
+	spec.Files = resolver.files()
 
 	return &spec, nil
 }
@@ -112,11 +308,14 @@ func ExtractEndpoints(spec *OpenAPISpec) []APIEndpoint {
 	for path, pathItem := range spec.Paths {
 		// Handle different HTTP methods
 		methods := map[string]*Operation{
-			"GET":    pathItem.Get,
-			"POST":   pathItem.Post,
-			"PUT":    pathItem.Put,
-			"DELETE": pathItem.Delete,
-			"PATCH":  pathItem.Patch,
+			"GET":     pathItem.Get,
+			"POST":    pathItem.Post,
+			"PUT":     pathItem.Put,
+			"DELETE":  pathItem.Delete,
+			"PATCH":   pathItem.Patch,
+			"HEAD":    pathItem.Head,
+			"OPTIONS": pathItem.Options,
+			"TRACE":   pathItem.Trace,
 		}
 
 		for method, operation := range methods {
@@ -129,29 +328,50 @@ func ExtractEndpoints(spec *OpenAPISpec) []APIEndpoint {
 				Method:      method,
 				Path:        path,
 				Summary:     operation.Summary,
+				Description: operation.Description,
 				Tags:        operation.Tags,
+				Deprecated:  operation.Deprecated,
+				Security:    operation.Security,
+				Servers:     operation.Servers,
+			}
+
+			// Path-level docs and servers apply unless the operation sets its own
+			if endpoint.Summary == "" {
+				endpoint.Summary = pathItem.Summary
+			}
+			if endpoint.Description == "" {
+				endpoint.Description = pathItem.Description
+			}
+			if endpoint.Servers == nil {
+				endpoint.Servers = pathItem.Servers
 			}
 
 			// Convert parameters
-			for _, param := range operation.Parameters {
+			for _, param := range mergeParameters(pathItem.Parameters, operation.Parameters) {
 				endpoint.Parameters = append(endpoint.Parameters, Parameter{
-					Name:     param.Name,
-					In:       param.In,
-					Required: param.Required,
-					Schema:   convertSchema(param.Schema),
+					Name:        param.Name,
+					In:          param.In,
+					Description: param.Description,
+					Required:    param.Required,
+					Deprecated:  param.Deprecated,
+					Schema:      convertSchema(param.Schema),
+					Example:     param.Example,
+					Examples:    convertExamples(param.Examples),
 				})
 			}
 
 			// Convert request body
 			if operation.RequestBody != nil {
 				endpoint.RequestBody = &RequestBody{
-					Required: operation.RequestBody.Required,
-					Content:  make(map[string]MediaType),
+					Description: operation.RequestBody.Description,
+					Required:    operation.RequestBody.Required,
+					Content:     make(map[string]MediaType),
 				}
 				for contentType, mediaType := range operation.RequestBody.Content {
 					endpoint.RequestBody.Content[contentType] = MediaType{
-						Schema:  convertSchema(mediaType.Schema),
-						Example: mediaType.Example,
+						Schema:   convertSchema(mediaType.Schema),
+						Example:  mediaType.Example,
+						Examples: convertExamples(mediaType.Examples),
 					}
 				}
 			}
@@ -162,13 +382,26 @@ func ExtractEndpoints(spec *OpenAPISpec) []APIEndpoint {
 				convertedContent := make(map[string]MediaType)
 				for contentType, mediaType := range response.Content {
 					convertedContent[contentType] = MediaType{
-						Schema:  convertSchema(mediaType.Schema),
-						Example: mediaType.Example,
+						Schema:   convertSchema(mediaType.Schema),
+						Example:  mediaType.Example,
+						Examples: convertExamples(mediaType.Examples),
+					}
+				}
+				var convertedHeaders map[string]Header
+				if len(response.Headers) > 0 {
+					convertedHeaders = make(map[string]Header)
+					for name, header := range response.Headers {
+						convertedHeaders[name] = Header{
+							Description: header.Description,
+							Required:    header.Required,
+							Schema:      convertSchema(header.Schema),
+						}
 					}
 				}
 				endpoint.Responses[statusCode] = Response{
 					Description: response.Description,
 					Content:     convertedContent,
+					Headers:     convertedHeaders,
 				}
 			}
 
@@ -176,18 +409,81 @@ func ExtractEndpoints(spec *OpenAPISpec) []APIEndpoint {
 		}
 	}
 
+	// Paths and methods come from maps; sort so scans are stable run to run
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+
 	return endpoints
 }
 
+// mergeParameters combines path-level and operation-level parameters. An
+// operation parameter with the same name and location replaces the path one.
+func mergeParameters(pathParams, opParams []OAParameter) []OAParameter {
+	if len(pathParams) == 0 {
+		return opParams
+	}
+
+	merged := make([]OAParameter, 0, len(pathParams)+len(opParams))
+	overridden := map[string]bool{}
+	for _, param := range opParams {
+		overridden[param.In+":"+param.Name] = true
+	}
+	for _, param := range pathParams {
+		if !overridden[param.In+":"+param.Name] {
+			merged = append(merged, param)
+		}
+	}
+	return append(merged, opParams...)
+}
+
+// ExtractServiceSpec extracts the service-level info, servers and security from an OpenAPI spec
+func ExtractServiceSpec(spec *OpenAPISpec) ServiceSpec {
+	return ServiceSpec{
+		Info:            spec.Info,
+		Servers:         spec.Servers,
+		SecuritySchemes: spec.Components.SecuritySchemes,
+		Security:        spec.Security,
+	}
+}
+
+// convertExamples converts named examples, keeping nil for none
+func convertExamples(oas map[string]OAExample) map[string]Example {
+	if len(oas) == 0 {
+		return nil
+	}
+	examples := make(map[string]Example, len(oas))
+	for name, example := range oas {
+		examples[name] = Example{Summary: example.Summary, Value: example.Value}
+	}
+	return examples
+}
+
 // convertSchema converts OASchema to Schema
 func convertSchema(oas OASchema) Schema {
 	schema := Schema{
-		Type:     oas.Type,
-		Format:   oas.Format,
-		Required: oas.Required,
-		Example:  oas.Example,
-		Ref:      oas.Ref,
+		Type:        oas.Type,
+		Format:      oas.Format,
+		Description: oas.Description,
+		Required:    oas.Required,
+		Enum:        oas.Enum,
+		Nullable:    oas.Nullable || oas.XNullable,
+		MinLength:   oas.MinLength,
+		MaxLength:   oas.MaxLength,
+		MinItems:    oas.MinItems,
+		MaxItems:    oas.MaxItems,
+		Pattern:     oas.Pattern,
+		Default:     oas.Default,
+		ReadOnly:    oas.ReadOnly,
+		WriteOnly:   oas.WriteOnly,
+		Example:     oas.Example,
+		Ref:         oas.Ref,
 	}
+	schema.Minimum, schema.ExclusiveMinimum = exclusiveMinimum(oas.Minimum, oas.ExclusiveMinimum)
+	schema.Maximum, schema.ExclusiveMaximum = exclusiveMaximum(oas.Maximum, oas.ExclusiveMaximum)
 
 	if oas.Properties != nil {
 		schema.Properties = make(map[string]Schema)
@@ -196,10 +492,46 @@ func convertSchema(oas OASchema) Schema {
 		}
 	}
 
+	if oas.AdditionalProperties != nil {
+		schema.AdditionalProperties = &AdditionalProperties{Allowed: oas.AdditionalProperties.Allowed}
+		if oas.AdditionalProperties.Schema != nil {
+			extra := convertSchema(*oas.AdditionalProperties.Schema)
+			schema.AdditionalProperties.Schema = &extra
+		}
+	}
+
 	if oas.Items != nil {
 		items := convertSchema(*oas.Items)
 		schema.Items = &items
 	}
 
+	schema.AllOf = convertSchemas(oas.AllOf)
+	schema.OneOf = convertSchemas(oas.OneOf)
+	schema.AnyOf = convertSchemas(oas.AnyOf)
+
+	if oas.Not != nil {
+		not := convertSchema(*oas.Not)
+		schema.Not = &not
+	}
+
+	if oas.Discriminator != nil {
+		schema.Discriminator = &Discriminator{
+			PropertyName: oas.Discriminator.PropertyName,
+			Mapping:      oas.Discriminator.Mapping,
+		}
+	}
+
 	return schema
 }
+
+// convertSchemas converts a list of subschemas, keeping nil for empty lists
+func convertSchemas(list []OASchema) []Schema {
+	if len(list) == 0 {
+		return nil
+	}
+	schemas := make([]Schema, len(list))
+	for i, oas := range list {
+		schemas[i] = convertSchema(oas)
+	}
+	return schemas
+}
@@ -1,6 +1,8 @@
 package discovery
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -79,3 +81,96 @@ func TestExtractEndpoints(t *testing.T) {
 		t.Errorf("Expected path '/orders', got '%s'", createOrderEndpoint.Path)
 	}
 }
+
+func TestExtractEndpoints_PathItemFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	content := `openapi: 3.0.0
+info:
+  title: Files
+paths:
+  /files/{id}:
+    summary: A stored file
+    servers:
+      - url: https://files.example.com
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+      - name: X-Trace
+        in: header
+        schema:
+          type: string
+    get:
+      summary: Download a file
+      servers:
+        - url: https://cdn.example.com
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: ok
+    head:
+      parameters:
+        - name: id
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    options:
+      responses:
+        "204":
+          description: allowed methods
+    trace:
+      responses:
+        "200":
+          description: echo
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseOpenAPI(path)
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+
+	endpoints := map[string]APIEndpoint{}
+	for _, endpoint := range ExtractEndpoints(spec) {
+		endpoints[endpoint.Method] = endpoint
+	}
+	for _, method := range []string{"GET", "HEAD", "OPTIONS", "TRACE"} {
+		if _, ok := endpoints[method]; !ok {
+			t.Errorf("Expected %s endpoint, got %v", method, endpoints)
+		}
+	}
+
+	get := endpoints["GET"]
+	if get.Summary != "Download a file" || len(get.Servers) != 1 || get.Servers[0].URL != "https://cdn.example.com" {
+		t.Errorf("Expected operation summary and servers to win, got %q %+v", get.Summary, get.Servers)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].Name != "X-Trace" || get.Parameters[1].Schema.Type != "integer" {
+		t.Errorf("Expected operation to override the path id parameter, got %+v", get.Parameters)
+	}
+
+	// Same name in a different location doesn't override
+	head := endpoints["HEAD"]
+	if len(head.Parameters) != 3 {
+		t.Errorf("Expected path and query id parameters, got %+v", head.Parameters)
+	}
+
+	options := endpoints["OPTIONS"]
+	if options.Summary != "A stored file" || len(options.Servers) != 1 || options.Servers[0].URL != "https://files.example.com" {
+		t.Errorf("Expected path-level summary and servers, got %q %+v", options.Summary, options.Servers)
+	}
+	if len(options.Parameters) != 2 {
+		t.Errorf("Expected path-level parameters, got %+v", options.Parameters)
+	}
+}
@@ -0,0 +1,261 @@
+package discovery
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// refResolver inlines $ref references in a parsed OpenAPI document.
+// References may point into the same document or into relative files
+// (e.g. ./common.yaml#/components/schemas/Error). Recursive references are
+// left as bare $ref nodes; references that can't be resolved are left in place
+// and recorded as warnings. A reference is expanded once and the result shared
+// by every place it appears, unless its expansion stopped at a recursive
+// reference, which makes the result depend on where it was reached from.
+type refResolver struct {
+	docs     map[string]*yaml.Node // parsed documents by absolute path
+	resolved map[string]*yaml.Node // expanded references by file#pointer
+	cycles   int                   // recursive references left in place so far
+	warnings []string
+	warned   map[string]bool
+}
+
+// newRefResolver creates a resolver whose root document is already parsed
+func newRefResolver(path string, root *yaml.Node) *refResolver {
+	return &refResolver{
+		docs:     map[string]*yaml.Node{absPath(path): root},
+		resolved: map[string]*yaml.Node{},
+		warned:   map[string]bool{},
+	}
+}
+
+// resolveDocument returns a copy of the document at path with every reference inlined
+func (r *refResolver) resolveDocument(path string) *yaml.Node {
+	file := absPath(path)
+	return r.resolve(r.docs[file], file, map[string]bool{})
+}
+
+// resolve copies node, replacing $ref mappings with the nodes they reference.
+// stack holds the references being expanded on the current branch, for cycle detection.
+func (r *refResolver) resolve(node *yaml.Node, file string, stack map[string]bool) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		return r.resolve(node.Alias, file, stack)
+	case yaml.DocumentNode, yaml.SequenceNode:
+		out := *node
+		out.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			out.Content[i] = r.resolve(child, file, stack)
+		}
+		return &out
+	case yaml.MappingNode:
+		if ref, ok := mappingValue(node, "$ref"); ok && ref.Kind == yaml.ScalarNode {
+			return r.resolveRef(node, ref.Value, file, stack)
+		}
+		out := *node
+		out.Content = make([]*yaml.Node, len(node.Content))
+		for i := 0; i < len(node.Content); i += 2 {
+			out.Content[i] = node.Content[i]
+			out.Content[i+1] = r.resolve(node.Content[i+1], file, stack)
+		}
+		return &out
+	default:
+		return node
+	}
+}
+
+// resolveRef expands a {$ref: ...} mapping. The referenced node's keys are
+// merged with any sibling keys of $ref (siblings win), and $ref itself is kept
+// so the origin of the inlined node stays visible.
+func (r *refResolver) resolveRef(node *yaml.Node, ref, file string, stack map[string]bool) *yaml.Node {
+	targetFile, pointer, err := r.locate(ref, file)
+	if err != nil {
+		r.warn(fmt.Sprintf("unresolved $ref %q in %s: %v", ref, filepath.Base(file), err))
+		return node
+	}
+
+	key := targetFile + "#" + pointer
+	if stack[key] {
+		// Recursive schema: stop expanding and leave the reference in place
+		r.cycles++
+		return node
+	}
+
+	resolved, ok := r.resolved[key]
+	if !ok {
+		doc, err := r.load(targetFile)
+		if err != nil {
+			r.warn(fmt.Sprintf("unresolved $ref %q in %s: %v", ref, filepath.Base(file), err))
+			return node
+		}
+
+		target, err := lookupPointer(doc, pointer)
+		if err != nil {
+			r.warn(fmt.Sprintf("unresolved $ref %q in %s: %v", ref, filepath.Base(file), err))
+			return node
+		}
+
+		cycles := r.cycles
+		stack[key] = true
+		resolved = r.resolve(target, targetFile, stack)
+		delete(stack, key)
+		if r.cycles == cycles {
+			r.resolved[key] = resolved
+		}
+	}
+
+	if resolved.Kind != yaml.MappingNode {
+		return resolved
+	}
+
+	// Merge: resolved keys first, then siblings of $ref override them
+	out := *resolved
+	out.Content = append([]*yaml.Node{}, resolved.Content...)
+	for i := 0; i < len(node.Content); i += 2 {
+		k := node.Content[i].Value
+		v := node.Content[i+1]
+		if k != "$ref" {
+			v = r.resolve(v, file, stack)
+		}
+		setMappingValue(&out, node.Content[i], v)
+	}
+	return &out
+}
+
+// locate splits a reference into the absolute file it points into and its JSON pointer
+func (r *refResolver) locate(ref, file string) (string, string, error) {
+	location, fragment, _ := strings.Cut(ref, "#")
+	if strings.Contains(location, "://") {
+		return "", "", fmt.Errorf("remote references are not supported")
+	}
+
+	target := file
+	if location != "" {
+		target = filepath.Join(filepath.Dir(file), filepath.FromSlash(location))
+	}
+
+	pointer, err := url.PathUnescape(fragment)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid fragment: %v", err)
+	}
+	return target, pointer, nil
+}
+
+// load returns a parsed document, reading it on first use
+func (r *refResolver) load(file string) (*yaml.Node, error) {
+	if doc, ok := r.docs[file]; ok {
+		return doc, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so this handles both
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filepath.Base(file), err)
+	}
+	r.docs[file] = &doc
+	return &doc, nil
+}
+
+// files returns the absolute paths of every document loaded, sorted
+func (r *refResolver) files() []string {
+	files := make([]string, 0, len(r.docs))
+	for file := range r.docs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// warn records a warning once
+func (r *refResolver) warn(msg string) {
+	if r.warned[msg] {
+		return
+	}
+	r.warned[msg] = true
+	r.warnings = append(r.warnings, msg)
+}
+
+// lookupPointer resolves a JSON pointer (RFC 6901) against a document node
+func lookupPointer(doc *yaml.Node, pointer string) (*yaml.Node, error) {
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if pointer == "" || pointer == "/" {
+		return node, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			next, ok := mappingValue(node, token)
+			if !ok {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			node = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			node = node.Content[i]
+		default:
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+	}
+	return node, nil
+}
+
+// mappingValue finds the value for a key in a mapping node
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// setMappingValue replaces the value for a key in a mapping node, or appends it
+func setMappingValue(node *yaml.Node, key, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key.Value {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, key, value)
+}
+
+// absPath makes a path absolute, falling back to the cleaned input
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
@@ -0,0 +1,389 @@
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const refsSpec = `openapi: 3.0.0
+info:
+  title: Orders
+  version: 1.0.0
+paths:
+  /orders/{id}:
+    put:
+      operationId: updateOrder
+      parameters:
+        - $ref: '#/components/parameters/OrderId'
+      requestBody:
+        $ref: '#/components/requestBodies/Order'
+      responses:
+        "200":
+          description: updated
+          headers:
+            X-Rate-Limit:
+              $ref: '#/components/headers/RateLimit'
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Order'
+        "404":
+          $ref: './common.yaml#/responses/NotFound'
+        "500":
+          $ref: '#/components/responses/Missing'
+components:
+  parameters:
+    OrderId:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: string
+  requestBodies:
+    Order:
+      required: true
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Order'
+  headers:
+    RateLimit:
+      required: true
+      schema:
+        type: integer
+  schemas:
+    Order:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: string
+        parent:
+          $ref: '#/components/schemas/Order'
+`
+
+const refsCommon = `responses:
+  NotFound:
+    description: not found
+    content:
+      application/json:
+        schema:
+          $ref: '#/schemas/Error'
+schemas:
+  Error:
+    type: object
+    properties:
+      message:
+        type: string
+`
+
+func writeRefsSpec(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(refsSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "common.yaml"), []byte(refsCommon), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "openapi.yaml")
+}
+
+func TestParseOpenAPI_ResolvesRefs(t *testing.T) {
+	spec, err := ParseOpenAPI(writeRefsSpec(t))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+
+	endpoints := ExtractEndpoints(spec)
+	if len(endpoints) != 1 {
+		t.Fatalf("Expected 1 endpoint, got %d", len(endpoints))
+	}
+	endpoint := endpoints[0]
+
+	if len(endpoint.Parameters) != 1 || endpoint.Parameters[0].Name != "id" || !endpoint.Parameters[0].Required {
+		t.Errorf("Expected resolved path parameter, got %+v", endpoint.Parameters)
+	}
+
+	if endpoint.RequestBody == nil || !endpoint.RequestBody.Required {
+		t.Fatalf("Expected resolved request body, got %+v", endpoint.RequestBody)
+	}
+	body := endpoint.RequestBody.Content["application/json"].Schema
+	if body.Type != "object" || body.Ref != "#/components/schemas/Order" {
+		t.Errorf("Expected inlined Order schema keeping its ref, got %+v", body)
+	}
+
+	// Recursive properties stop at the cycle and keep the bare reference
+	parent := body.Properties["parent"]
+	if parent.Ref != "#/components/schemas/Order" || parent.Type != "" {
+		t.Errorf("Expected recursive ref to be left unexpanded, got %+v", parent)
+	}
+
+	header := endpoint.Responses["200"].Headers["X-Rate-Limit"]
+	if !header.Required || header.Schema.Type != "integer" {
+		t.Errorf("Expected resolved response header, got %+v", header)
+	}
+
+	notFound := endpoint.Responses["404"]
+	if notFound.Description != "not found" {
+		t.Errorf("Expected response from external file, got %+v", notFound)
+	}
+	if notFound.Content["application/json"].Schema.Properties["message"].Type != "string" {
+		t.Errorf("Expected nested ref to resolve relative to the external file, got %+v", notFound.Content)
+	}
+}
+
+func TestParseOpenAPI_UnresolvedRefWarning(t *testing.T) {
+	spec, err := ParseOpenAPI(writeRefsSpec(t))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+
+	if len(spec.Warnings) != 1 || !strings.Contains(spec.Warnings[0], "#/components/responses/Missing") {
+		t.Errorf("Expected one warning for the missing response, got %v", spec.Warnings)
+	}
+}
+
+func TestLookupPointer_Escapes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openapi.yaml")
+	content := `openapi: 3.0.0
+paths:
+  /orders/{id}:
+    get:
+      operationId: getOrder
+      responses:
+        "200":
+          description: ok
+  /copy:
+    get:
+      responses:
+        "200":
+          $ref: '#/paths/~1orders~1%7Bid%7D/get/responses/200'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseOpenAPI(path)
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+	if len(spec.Warnings) != 0 {
+		t.Fatalf("Unexpected warnings: %v", spec.Warnings)
+	}
+	if got := spec.Paths["/copy"].Get.Responses["200"].Description; got != "ok" {
+		t.Errorf("Expected escaped pointer to resolve, got description %q", got)
+	}
+}
+
+func TestRefResolver_SharesExpansions(t *testing.T) {
+	// Each schema references the next twice, so expanding every reference
+	// separately would take 2^depth steps
+	const depth = 24
+	var b strings.Builder
+	b.WriteString("components:\n  schemas:\n")
+	for i := 0; i < depth; i++ {
+		fmt.Fprintf(&b, "    S%d:\n      type: object\n      properties:\n", i)
+		if i+1 < depth {
+			fmt.Fprintf(&b, "        left: {$ref: '#/components/schemas/S%d'}\n        right: {$ref: '#/components/schemas/S%d'}\n", i+1, i+1)
+		} else {
+			b.WriteString("        id: {type: string}\n")
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(b.String()), &root); err != nil {
+		t.Fatal(err)
+	}
+	resolver := newRefResolver("openapi.yaml", &root)
+	doc := resolver.resolveDocument("openapi.yaml")
+	if len(resolver.resolved) != depth-1 {
+		t.Errorf("Expected %d expanded references, got %d", depth-1, len(resolver.resolved))
+	}
+
+	props, err := lookupPointer(doc, "/components/schemas/S0/properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, _ := mappingValue(props, "left")
+	right, _ := mappingValue(props, "right")
+	leftProps, _ := mappingValue(left, "properties")
+	rightProps, _ := mappingValue(right, "properties")
+	if leftProps != rightProps {
+		t.Error("Expected both references to S1 to share one expansion")
+	}
+}
+
+func TestParseOpenAPI_SchemaKeywords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openapi.yaml")
+	content := `openapi: 3.0.0
+paths: {}
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        id:
+          type: string
+          readOnly: true
+    Pet:
+      description: A pet
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            status:
+              type: string
+              enum: [available, sold]
+              default: available
+            age:
+              type: integer
+              minimum: 0
+              maximum: 30
+              exclusiveMaximum: true
+            name:
+              type: string
+              minLength: 1
+              maxLength: 50
+              pattern: '^[a-z]+$'
+              nullable: true
+            password:
+              type: string
+              writeOnly: true
+            labels:
+              type: object
+              additionalProperties:
+                type: string
+            extras:
+              type: object
+              additionalProperties: false
+            photos:
+              type: array
+              minItems: 1
+              maxItems: 5
+              items:
+                type: string
+    Animal:
+      oneOf:
+        - $ref: '#/components/schemas/Pet'
+      anyOf:
+        - type: object
+      not:
+        type: string
+      discriminator:
+        propertyName: kind
+        mapping:
+          pet: '#/components/schemas/Pet'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseOpenAPI(path)
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+
+	pet := convertSchema(spec.Components.Schemas["Pet"])
+	if pet.Description != "A pet" || len(pet.AllOf) != 2 {
+		t.Fatalf("Expected allOf with 2 schemas, got %+v", pet)
+	}
+	if !pet.AllOf[0].Properties["id"].ReadOnly {
+		t.Error("Expected readOnly on base id")
+	}
+
+	props := pet.AllOf[1].Properties
+	status := props["status"]
+	if len(status.Enum) != 2 || status.Default != "available" {
+		t.Errorf("Expected enum and default, got %+v", status)
+	}
+	age := props["age"]
+	if age.Minimum == nil || *age.Minimum != 0 || age.Maximum == nil || *age.Maximum != 30 || !age.ExclusiveMaximum {
+		t.Errorf("Expected numeric bounds, got %+v", age)
+	}
+	name := props["name"]
+	if *name.MinLength != 1 || *name.MaxLength != 50 || name.Pattern != "^[a-z]+$" || !name.Nullable {
+		t.Errorf("Expected string constraints, got %+v", name)
+	}
+	if !props["password"].WriteOnly {
+		t.Error("Expected writeOnly on password")
+	}
+	if labels := props["labels"].AdditionalProperties; labels == nil || !labels.Allowed || labels.Schema.Type != "string" {
+		t.Errorf("Expected additionalProperties schema, got %+v", labels)
+	}
+	if extras := props["extras"].AdditionalProperties; extras == nil || extras.Allowed {
+		t.Errorf("Expected additionalProperties false, got %+v", extras)
+	}
+	if photos := props["photos"]; *photos.MinItems != 1 || *photos.MaxItems != 5 {
+		t.Errorf("Expected array bounds, got %+v", photos)
+	}
+
+	animal := convertSchema(spec.Components.Schemas["Animal"])
+	if len(animal.OneOf) != 1 || animal.OneOf[0].Ref != "#/components/schemas/Pet" || len(animal.OneOf[0].AllOf) != 2 {
+		t.Errorf("Expected resolved oneOf, got %+v", animal.OneOf)
+	}
+	if len(animal.AnyOf) != 1 || animal.Not == nil || animal.Not.Type != "string" {
+		t.Errorf("Expected anyOf and not, got %+v", animal)
+	}
+	if animal.Discriminator == nil || animal.Discriminator.PropertyName != "kind" || animal.Discriminator.Mapping["pet"] != "#/components/schemas/Pet" {
+		t.Errorf("Expected discriminator, got %+v", animal.Discriminator)
+	}
+}
+
+func TestParseOpenAPI_ExclusiveBounds(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openapi.yaml")
+	content := `openapi: 3.1.0
+paths: {}
+components:
+  schemas:
+    Order:
+      type: object
+      properties:
+        qty:
+          type: integer
+          exclusiveMinimum: 0
+          maximum: 100
+        discount:
+          type: number
+          minimum: 5
+          exclusiveMinimum: 1
+          exclusiveMaximum: 50
+        legacy:
+          type: number
+          minimum: 0
+          exclusiveMinimum: true
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseOpenAPI(path)
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+	props := convertSchema(spec.Components.Schemas["Order"]).Properties
+
+	// 3.1: the number is the exclusive bound
+	qty := props["qty"]
+	if qty.Minimum == nil || *qty.Minimum != 0 || !qty.ExclusiveMinimum || qty.Maximum == nil || *qty.Maximum != 100 || qty.ExclusiveMaximum {
+		t.Errorf("Expected qty > 0 and <= 100, got %+v", qty)
+	}
+	// With both, the stricter bound wins
+	discount := props["discount"]
+	if *discount.Minimum != 5 || discount.ExclusiveMinimum || *discount.Maximum != 50 || !discount.ExclusiveMaximum {
+		t.Errorf("Expected discount >= 5 and < 50, got %+v", discount)
+	}
+	// 3.0: the boolean makes minimum exclusive
+	legacy := props["legacy"]
+	if *legacy.Minimum != 0 || !legacy.ExclusiveMinimum {
+		t.Errorf("Expected legacy > 0, got %+v", legacy)
+	}
+}
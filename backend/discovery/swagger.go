@@ -0,0 +1,371 @@
+package discovery
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SwaggerSpec represents a simplified Swagger 2.0 spec
+type SwaggerSpec struct {
+	Swagger             string                           `yaml:"swagger"`
+	Info                Info                             `yaml:"info"`
+	Host                string                           `yaml:"host"`
+	BasePath            string                           `yaml:"basePath"`
+	Schemes             []string                         `yaml:"schemes"`
+	Consumes            []string                         `yaml:"consumes"`
+	Produces            []string                         `yaml:"produces"`
+	Paths               map[string]SwaggerPathItem       `yaml:"paths"`
+	Definitions         map[string]OASchema              `yaml:"definitions"`
+	SecurityDefinitions map[string]SwaggerSecurityScheme `yaml:"securityDefinitions"`
+	Security            []SecurityRequirement            `yaml:"security"`
+}
+
+// SwaggerPathItem represents a Swagger 2.0 path item
+type SwaggerPathItem struct {
+	Parameters []SwaggerParameter `yaml:"parameters"`
+	Get        *SwaggerOperation  `yaml:"get"`
+	Post       *SwaggerOperation  `yaml:"post"`
+	Put        *SwaggerOperation  `yaml:"put"`
+	Delete     *SwaggerOperation  `yaml:"delete"`
+	Patch      *SwaggerOperation  `yaml:"patch"`
+	Head       *SwaggerOperation  `yaml:"head"`
+	Options    *SwaggerOperation  `yaml:"options"`
+}
+
+// SwaggerOperation represents a Swagger 2.0 operation
+type SwaggerOperation struct {
+	OperationID string                     `yaml:"operationId"`
+	Summary     string                     `yaml:"summary"`
+	Description string                     `yaml:"description"`
+	Tags        []string                   `yaml:"tags"`
+	Deprecated  bool                       `yaml:"deprecated"`
+	Consumes    []string                   `yaml:"consumes"`
+	Produces    []string                   `yaml:"produces"`
+	Parameters  []SwaggerParameter         `yaml:"parameters"`
+	Responses   map[string]SwaggerResponse `yaml:"responses"`
+	Security    []SecurityRequirement      `yaml:"security"`
+}
+
+// SwaggerParameter represents a Swagger 2.0 parameter. Body parameters carry
+// a schema; the others describe their type inline.
+type SwaggerParameter struct {
+	Name         string    `yaml:"name"`
+	In           string    `yaml:"in"`
+	Description  string    `yaml:"description"`
+	Required     bool      `yaml:"required"`
+	Schema       *OASchema `yaml:"schema"`
+	SwaggerItems `yaml:",inline"`
+}
+
+// SwaggerItems holds the inline type keywords of Swagger 2.0 parameters,
+// headers and items
+type SwaggerItems struct {
+	Type             string        `yaml:"type"`
+	Format           string        `yaml:"format"`
+	Items            *OASchema     `yaml:"items"`
+	Enum             []interface{} `yaml:"enum"`
+	Default          interface{}   `yaml:"default"`
+	Minimum          *float64      `yaml:"minimum"`
+	Maximum          *float64      `yaml:"maximum"`
+	ExclusiveMinimum bool          `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum bool          `yaml:"exclusiveMaximum"`
+	MinLength        *int          `yaml:"minLength"`
+	MaxLength        *int          `yaml:"maxLength"`
+	MinItems         *int          `yaml:"minItems"`
+	MaxItems         *int          `yaml:"maxItems"`
+	Pattern          string        `yaml:"pattern"`
+	Example          interface{}   `yaml:"x-example"`
+}
+
+// SwaggerResponse represents a Swagger 2.0 response
+type SwaggerResponse struct {
+	Description string                   `yaml:"description"`
+	Schema      *OASchema                `yaml:"schema"`
+	Headers     map[string]SwaggerHeader `yaml:"headers"`
+	Examples    map[string]interface{}   `yaml:"examples"` // keyed by mime type
+}
+
+// SwaggerHeader represents a Swagger 2.0 response header
+type SwaggerHeader struct {
+	Description  string `yaml:"description"`
+	SwaggerItems `yaml:",inline"`
+}
+
+// SwaggerSecurityScheme represents a Swagger 2.0 security definition
+type SwaggerSecurityScheme struct {
+	Type             string            `yaml:"type"` // basic, apiKey or oauth2
+	Description      string            `yaml:"description"`
+	Name             string            `yaml:"name"`
+	In               string            `yaml:"in"`
+	Flow             string            `yaml:"flow"`
+	AuthorizationURL string            `yaml:"authorizationUrl"`
+	TokenURL         string            `yaml:"tokenUrl"`
+	Scopes           map[string]string `yaml:"scopes"`
+}
+
+// swaggerFlows maps Swagger 2.0 oauth2 flow names to their OpenAPI 3 names
+var swaggerFlows = map[string]string{
+	"implicit":    "implicit",
+	"password":    "password",
+	"application": "clientCredentials",
+	"accessCode":  "authorizationCode",
+}
+
+// isSwagger reports whether a parsed document declares itself as Swagger 2.0
+func isSwagger(doc *yaml.Node) bool {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	_, ok := mappingValue(doc, "swagger")
+	return ok
+}
+
+// ConvertSwagger converts a Swagger 2.0 spec to the OpenAPI 3 model.
+// basePath is prefixed to every path, body and formData parameters become
+// request bodies, and consumes/produces become the media types of request
+// and response content.
+func ConvertSwagger(sw *SwaggerSpec) *OpenAPISpec {
+	spec := &OpenAPISpec{
+		Info:     sw.Info,
+		Paths:    make(map[string]PathItem),
+		Security: sw.Security,
+		Components: Components{
+			Schemas: sw.Definitions,
+		},
+	}
+
+	if sw.Host != "" {
+		schemes := sw.Schemes
+		if len(schemes) == 0 {
+			schemes = []string{"https"}
+		}
+		for _, scheme := range schemes {
+			spec.Servers = append(spec.Servers, Server{URL: scheme + "://" + sw.Host})
+		}
+	}
+
+	if len(sw.SecurityDefinitions) > 0 {
+		spec.Components.SecuritySchemes = make(map[string]SecurityScheme)
+		for name, def := range sw.SecurityDefinitions {
+			spec.Components.SecuritySchemes[name] = convertSwaggerSecurity(def)
+		}
+	}
+
+	basePath := strings.TrimSuffix(sw.BasePath, "/")
+	for path, item := range sw.Paths {
+		pathParams := item.Parameters
+		convert := func(op *SwaggerOperation) *Operation {
+			if op == nil {
+				return nil
+			}
+			// Path-level parameters may be body or formData, so they are
+			// merged before conversion rather than kept on the path item
+			merged := *op
+			merged.Parameters = mergeSwaggerParameters(pathParams, op.Parameters)
+			return convertSwaggerOperation(&merged, sw.Consumes, sw.Produces)
+		}
+		spec.Paths[basePath+path] = PathItem{
+			Get:     convert(item.Get),
+			Post:    convert(item.Post),
+			Put:     convert(item.Put),
+			Delete:  convert(item.Delete),
+			Patch:   convert(item.Patch),
+			Head:    convert(item.Head),
+			Options: convert(item.Options),
+		}
+	}
+
+	return spec
+}
+
+// mergeSwaggerParameters combines path-level and operation-level parameters,
+// letting the operation override by name and location
+func mergeSwaggerParameters(pathParams, opParams []SwaggerParameter) []SwaggerParameter {
+	if len(pathParams) == 0 {
+		return opParams
+	}
+
+	merged := make([]SwaggerParameter, 0, len(pathParams)+len(opParams))
+	overridden := map[string]bool{}
+	for _, param := range opParams {
+		overridden[param.In+":"+param.Name] = true
+	}
+	for _, param := range pathParams {
+		if !overridden[param.In+":"+param.Name] {
+			merged = append(merged, param)
+		}
+	}
+	return append(merged, opParams...)
+}
+
+// convertSwaggerOperation converts one operation; operation-level
+// consumes/produces override the spec-level ones
+func convertSwaggerOperation(op *SwaggerOperation, consumes, produces []string) *Operation {
+	if op.Consumes != nil {
+		consumes = op.Consumes
+	}
+	if op.Produces != nil {
+		produces = op.Produces
+	}
+
+	operation := &Operation{
+		OperationID: op.OperationID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Security:    op.Security,
+		Responses:   make(map[string]OAResponse),
+	}
+
+	var form []SwaggerParameter
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "body":
+			body := &OARequestBody{
+				Description: param.Description,
+				Required:    param.Required,
+				Content:     make(map[string]OAMediaType),
+			}
+			var schema OASchema
+			if param.Schema != nil {
+				schema = *param.Schema
+			}
+			for _, contentType := range mediaTypesOr(consumes, "application/json") {
+				body.Content[contentType] = OAMediaType{Schema: schema}
+			}
+			operation.RequestBody = body
+		case "formData":
+			form = append(form, param)
+		default:
+			operation.Parameters = append(operation.Parameters, OAParameter{
+				Name:        param.Name,
+				In:          param.In,
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      param.SwaggerItems.schema(),
+				Example:     param.Example,
+			})
+		}
+	}
+
+	if len(form) > 0 && operation.RequestBody == nil {
+		operation.RequestBody = convertSwaggerForm(form, consumes)
+	}
+
+	for status, response := range op.Responses {
+		converted := OAResponse{Description: response.Description}
+		if response.Schema != nil {
+			converted.Content = make(map[string]OAMediaType)
+			for _, contentType := range mediaTypesOr(produces, "application/json") {
+				converted.Content[contentType] = OAMediaType{
+					Schema:  *response.Schema,
+					Example: response.Examples[contentType],
+				}
+			}
+		}
+		if len(response.Headers) > 0 {
+			converted.Headers = make(map[string]OAHeader)
+			for name, header := range response.Headers {
+				converted.Headers[name] = OAHeader{
+					Description: header.Description,
+					Schema:      header.SwaggerItems.schema(),
+				}
+			}
+		}
+		operation.Responses[status] = converted
+	}
+
+	return operation
+}
+
+// convertSwaggerForm builds a request body from formData parameters, one
+// property per parameter. File uploads default to multipart/form-data.
+func convertSwaggerForm(params []SwaggerParameter, consumes []string) *OARequestBody {
+	schema := OASchema{Type: "object", Properties: make(map[string]OASchema)}
+	defaultType := "application/x-www-form-urlencoded"
+	required := false
+	for _, param := range params {
+		property := param.SwaggerItems.schema()
+		property.Description = param.Description
+		if param.Type == "file" {
+			property.Type = "string"
+			property.Format = "binary"
+			defaultType = "multipart/form-data"
+		}
+		schema.Properties[param.Name] = property
+		if param.Required {
+			schema.Required = append(schema.Required, param.Name)
+			required = true
+		}
+	}
+
+	var formTypes []string
+	for _, contentType := range consumes {
+		if contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data" {
+			formTypes = append(formTypes, contentType)
+		}
+	}
+
+	body := &OARequestBody{Required: required, Content: make(map[string]OAMediaType)}
+	for _, contentType := range mediaTypesOr(formTypes, defaultType) {
+		body.Content[contentType] = OAMediaType{Schema: schema}
+	}
+	return body
+}
+
+// convertSwaggerSecurity converts a security definition to an OpenAPI 3 scheme
+func convertSwaggerSecurity(def SwaggerSecurityScheme) SecurityScheme {
+	scheme := SecurityScheme{
+		Type:        def.Type,
+		Description: def.Description,
+		Name:        def.Name,
+		In:          def.In,
+	}
+
+	switch def.Type {
+	case "basic":
+		scheme.Type = "http"
+		scheme.Scheme = "basic"
+	case "oauth2":
+		flow := swaggerFlows[def.Flow]
+		if flow == "" {
+			flow = def.Flow
+		}
+		scheme.Flows = map[string]OAuthFlow{flow: {
+			AuthorizationURL: def.AuthorizationURL,
+			TokenURL:         def.TokenURL,
+			Scopes:           def.Scopes,
+		}}
+	}
+	return scheme
+}
+
+// schema builds the schema described by inline type keywords
+func (s SwaggerItems) schema() OASchema {
+	return OASchema{
+		Type:             s.Type,
+		Format:           s.Format,
+		Items:            s.Items,
+		Enum:             s.Enum,
+		Default:          s.Default,
+		Minimum:          s.Minimum,
+		Maximum:          s.Maximum,
+		ExclusiveMinimum: OAExclusiveBound{Exclusive: s.ExclusiveMinimum},
+		ExclusiveMaximum: OAExclusiveBound{Exclusive: s.ExclusiveMaximum},
+		MinLength:        s.MinLength,
+		MaxLength:        s.MaxLength,
+		MinItems:         s.MinItems,
+		MaxItems:         s.MaxItems,
+		Pattern:          s.Pattern,
+	}
+}
+
+// mediaTypesOr returns types, or fallback when none are declared
+func mediaTypesOr(types []string, fallback string) []string {
+	if len(types) == 0 {
+		return []string{fallback}
+	}
+	return types
+}
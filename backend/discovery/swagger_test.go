@@ -0,0 +1,170 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const swaggerSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "Legacy Orders", "version": "1.2.0"},
+  "host": "orders.example.com",
+  "basePath": "/api/v1",
+  "schemes": ["https"],
+  "consumes": ["application/json"],
+  "produces": ["application/json"],
+  "securityDefinitions": {
+    "token": {"type": "apiKey", "name": "X-Token", "in": "header"},
+    "oauth": {"type": "oauth2", "flow": "accessCode", "authorizationUrl": "https://auth.example.com/authorize", "tokenUrl": "https://auth.example.com/token", "scopes": {"orders:read": "Read orders"}}
+  },
+  "security": [{"token": []}],
+  "paths": {
+    "/orders/{id}": {
+      "put": {
+        "operationId": "updateOrder",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "integer", "minimum": 1},
+          {"name": "tags", "in": "query", "type": "array", "items": {"type": "string"}},
+          {"name": "order", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Order"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "updated",
+            "schema": {"$ref": "#/definitions/Order"},
+            "headers": {"X-Rate-Limit": {"type": "integer"}},
+            "examples": {"application/json": {"id": "o_1"}}
+          }
+        }
+      }
+    },
+    "/orders/{id}/receipt": {
+      "parameters": [
+        {"name": "id", "in": "path", "required": true, "type": "integer"}
+      ],
+      "head": {
+        "operationId": "receiptExists",
+        "responses": {"200": {"description": "exists"}}
+      },
+      "post": {
+        "operationId": "uploadReceipt",
+        "consumes": ["multipart/form-data"],
+        "parameters": [
+          {"name": "file", "in": "formData", "required": true, "type": "file"},
+          {"name": "note", "in": "formData", "type": "string", "maxLength": 200}
+        ],
+        "responses": {"204": {"description": "stored"}}
+      }
+    }
+  },
+  "definitions": {
+    "Order": {
+      "type": "object",
+      "required": ["id"],
+      "discriminator": "kind",
+      "properties": {
+        "id": {"type": "string"},
+        "kind": {"type": "string"},
+        "note": {"type": "string", "x-nullable": true}
+      }
+    }
+  }
+}`
+
+func TestParseOpenAPI_Swagger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swagger.json")
+	if err := os.WriteFile(path, []byte(swaggerSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := ParseOpenAPI(path)
+	if err != nil {
+		t.Fatalf("ParseOpenAPI failed: %v", err)
+	}
+	if len(spec.Warnings) != 0 {
+		t.Fatalf("Unexpected warnings: %v", spec.Warnings)
+	}
+
+	if spec.Info.Title != "Legacy Orders" {
+		t.Errorf("Expected info title, got %q", spec.Info.Title)
+	}
+	if len(spec.Servers) != 1 || spec.Servers[0].URL != "https://orders.example.com" {
+		t.Errorf("Expected server from host and schemes, got %+v", spec.Servers)
+	}
+	oauth := spec.Components.SecuritySchemes["oauth"]
+	if oauth.Type != "oauth2" || oauth.Flows["authorizationCode"].TokenURL != "https://auth.example.com/token" {
+		t.Errorf("Expected converted oauth2 flow, got %+v", oauth)
+	}
+
+	endpoints := map[string]APIEndpoint{}
+	for _, endpoint := range ExtractEndpoints(spec) {
+		endpoints[endpoint.OperationID] = endpoint
+	}
+	if len(endpoints) != 3 {
+		t.Fatalf("Expected 3 endpoints, got %d", len(endpoints))
+	}
+
+	update := endpoints["updateOrder"]
+	if update.Method != "PUT" || update.Path != "/api/v1/orders/{id}" {
+		t.Errorf("Expected basePath to prefix the path, got %s %s", update.Method, update.Path)
+	}
+	if len(update.Parameters) != 2 {
+		t.Fatalf("Expected body parameter to be moved out of parameters, got %+v", update.Parameters)
+	}
+	if id := update.Parameters[0].Schema; id.Type != "integer" || id.Minimum == nil || *id.Minimum != 1 {
+		t.Errorf("Expected inline parameter type as schema, got %+v", id)
+	}
+	if tags := update.Parameters[1].Schema; tags.Type != "array" || tags.Items == nil || tags.Items.Type != "string" {
+		t.Errorf("Expected array parameter schema, got %+v", tags)
+	}
+
+	if update.RequestBody == nil || !update.RequestBody.Required {
+		t.Fatalf("Expected request body from body parameter, got %+v", update.RequestBody)
+	}
+	order := update.RequestBody.Content["application/json"].Schema
+	if order.Type != "object" || order.Ref != "#/definitions/Order" {
+		t.Errorf("Expected resolved definition, got %+v", order)
+	}
+	if order.Discriminator == nil || order.Discriminator.PropertyName != "kind" {
+		t.Errorf("Expected string discriminator, got %+v", order.Discriminator)
+	}
+	if !order.Properties["note"].Nullable {
+		t.Error("Expected x-nullable to mark the property nullable")
+	}
+
+	ok := update.Responses["200"]
+	media, found := ok.Content["application/json"]
+	if !found || media.Schema.Ref != "#/definitions/Order" {
+		t.Errorf("Expected response content from produces, got %+v", ok.Content)
+	}
+	if example, _ := media.Example.(map[string]interface{}); example["id"] != "o_1" {
+		t.Errorf("Expected response example, got %v", media.Example)
+	}
+	if ok.Headers["X-Rate-Limit"].Schema.Type != "integer" {
+		t.Errorf("Expected response header schema, got %+v", ok.Headers)
+	}
+
+	upload := endpoints["uploadReceipt"]
+	if upload.RequestBody == nil {
+		t.Fatal("Expected request body from formData parameters")
+	}
+	form, found := upload.RequestBody.Content["multipart/form-data"]
+	if !found || !upload.RequestBody.Required {
+		t.Fatalf("Expected required multipart body, got %+v", upload.RequestBody)
+	}
+	if file := form.Schema.Properties["file"]; file.Type != "string" || file.Format != "binary" {
+		t.Errorf("Expected file property as binary string, got %+v", file)
+	}
+	if len(form.Schema.Required) != 1 || form.Schema.Required[0] != "file" {
+		t.Errorf("Expected only file to be required, got %v", form.Schema.Required)
+	}
+	if len(upload.Parameters) != 1 || upload.Parameters[0].Name != "id" {
+		t.Errorf("Expected path-level id parameter, got %+v", upload.Parameters)
+	}
+	if exists := endpoints["receiptExists"]; exists.Method != "HEAD" || len(exists.Parameters) != 1 {
+		t.Errorf("Expected HEAD endpoint with the path-level parameter, got %+v", exists)
+	}
+	if len(upload.Responses["204"].Content) != 0 {
+		t.Errorf("Expected no content for schemaless response, got %+v", upload.Responses["204"].Content)
+	}
+}
@@ -26,6 +26,23 @@ type Endpoint struct {
 	Cluster string `json:"cluster,omitempty"`
 }
 
+// DeploymentEndpoint is a named deployment endpoint flattened out of tw-config.json
+type DeploymentEndpoint struct {
+	Deployment  string `json:"deployment"`
+	Name        string `json:"name"` // endpoint key, e.g. internal or public
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	Cluster     string `json:"cluster,omitempty"`
+	Environment string `json:"environment"` // STAGING or PRODUCTION
+}
+
+// EndpointSelector picks a deployment endpoint; empty fields match anything
+type EndpointSelector struct {
+	Deployment string
+	Endpoint   string
+	Cluster    string
+}
+
 // Service represents a discovered service
 type Service struct {
 	ID        string
@@ -36,51 +53,126 @@ type Service struct {
 	Endpoints []APIEndpoint
 }
 
+// ServiceSpec is the service-level part of an OpenAPI spec
+type ServiceSpec struct {
+	Info            Info                      `json:"info"`
+	Servers         []Server                  `json:"servers,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+	Security        []SecurityRequirement     `json:"security,omitempty"`
+}
+
+// SecurityRequirement maps security scheme names to required scopes
+type SecurityRequirement map[string][]string
+
 // APIEndpoint represents an OpenAPI endpoint
 type APIEndpoint struct {
-	OperationID string
-	Method      string
-	Path        string
-	Summary     string
-	Tags        []string
-	Parameters  []Parameter
-	RequestBody *RequestBody
-	Responses   map[string]Response
+	OperationID string                `json:"operationId,omitempty"`
+	Method      string                `json:"method"`
+	Path        string                `json:"path"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses,omitempty"`
+	Security    []SecurityRequirement `json:"security,omitempty"` // nil inherits the service security
+	Servers     []Server              `json:"servers,omitempty"`  // nil inherits the service servers
+	Source      *SpecSource           `json:"source,omitempty"`
+}
+
+// SpecSource records which spec file an endpoint was read from
+type SpecSource struct {
+	File       string `json:"file"`                 // relative to the service directory
+	Visibility string `json:"visibility,omitempty"` // private or public, from the file name
 }
 
 // Parameter represents an endpoint parameter
 type Parameter struct {
-	Name     string
-	In       string // path, query, header
-	Required bool
-	Schema   Schema
+	Name        string             `json:"name"`
+	In          string             `json:"in"` // path, query, header
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Schema      Schema             `json:"schema"`
+	Example     interface{}        `json:"example,omitempty"`
+	Examples    map[string]Example `json:"examples,omitempty"`
 }
 
 // RequestBody represents request body schema
 type RequestBody struct {
-	Required bool
-	Content  map[string]MediaType
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
 }
 
 // MediaType represents a media type with schema
 type MediaType struct {
-	Schema  Schema
-	Example interface{}
+	Schema   Schema             `json:"schema"`
+	Example  interface{}        `json:"example,omitempty"`
+	Examples map[string]Example `json:"examples,omitempty"`
+}
+
+// Example is a named example value
+type Example struct {
+	Summary string      `json:"summary,omitempty"`
+	Value   interface{} `json:"value"`
 }
 
 // Response represents an endpoint response
 type Response struct {
-	Description string
-	Content     map[string]MediaType
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+}
+
+// Header represents a documented response header
+type Header struct {
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
 }
 
 // Schema represents a JSON schema
 type Schema struct {
-	Type       string
-	Format     string
-	Required   []string
-	Properties map[string]Schema
-	Items      *Schema
-	Example    interface{}
-	Ref        string
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Properties           map[string]Schema     `json:"properties,omitempty"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"` // nil when not specified
+	Items                *Schema               `json:"items,omitempty"`
+	AllOf                []Schema              `json:"allOf,omitempty"`
+	OneOf                []Schema              `json:"oneOf,omitempty"`
+	AnyOf                []Schema              `json:"anyOf,omitempty"`
+	Not                  *Schema               `json:"not,omitempty"`
+	Discriminator        *Discriminator        `json:"discriminator,omitempty"`
+	Enum                 []interface{}         `json:"enum,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool                  `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool                  `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	MaxItems             *int                  `json:"maxItems,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+	Default              interface{}           `json:"default,omitempty"`
+	ReadOnly             bool                  `json:"readOnly,omitempty"`
+	WriteOnly            bool                  `json:"writeOnly,omitempty"`
+	Example              interface{}           `json:"example,omitempty"`
+	Ref                  string                `json:"ref,omitempty"` // $ref the schema was resolved from
+}
+
+// AdditionalProperties describes which properties beyond Properties an object may have
+type AdditionalProperties struct {
+	Allowed bool    `json:"allowed"`
+	Schema  *Schema `json:"schema,omitempty"` // schema for the extra values, if given
+}
+
+// Discriminator selects a oneOf/anyOf alternative by the value of a property
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"` // property value -> schema $ref
 }
@@ -0,0 +1,120 @@
+package environment
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/templating"
+)
+
+// ServiceOverride customizes an environment for a single service
+type ServiceOverride struct {
+	BaseURLTemplate string            `json:"baseUrlTemplate,omitempty"`
+	Variables       map[string]string `json:"variables,omitempty"`
+}
+
+// Definition is a user-defined environment with its JSON columns decoded
+type Definition struct {
+	ID               int64                      `json:"id"`
+	Name             string                     `json:"name"`
+	BaseURLTemplate  string                     `json:"baseUrlTemplate"`
+	Variables        map[string]string          `json:"variables"`
+	ServiceOverrides map[string]ServiceOverride `json:"serviceOverrides"`
+}
+
+// FromRow decodes a database row into a Definition
+func FromRow(row db.Environment) (Definition, error) {
+	def := Definition{
+		ID:               row.ID,
+		Name:             row.Name,
+		BaseURLTemplate:  row.BaseURLTemplate,
+		Variables:        map[string]string{},
+		ServiceOverrides: map[string]ServiceOverride{},
+	}
+
+	if row.VariablesJSON != "" {
+		if err := json.Unmarshal([]byte(row.VariablesJSON), &def.Variables); err != nil {
+			return def, fmt.Errorf("invalid variables for environment %s: %w", row.Name, err)
+		}
+	}
+	if row.ServiceOverridesJSON != "" {
+		if err := json.Unmarshal([]byte(row.ServiceOverridesJSON), &def.ServiceOverrides); err != nil {
+			return def, fmt.Errorf("invalid service overrides for environment %s: %w", row.Name, err)
+		}
+	}
+
+	return def, nil
+}
+
+// ToRow encodes a Definition for storage
+func (d Definition) ToRow() (db.Environment, error) {
+	variables := d.Variables
+	if variables == nil {
+		variables = map[string]string{}
+	}
+	overrides := d.ServiceOverrides
+	if overrides == nil {
+		overrides = map[string]ServiceOverride{}
+	}
+
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return db.Environment{}, err
+	}
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		return db.Environment{}, err
+	}
+
+	return db.Environment{
+		ID:                   d.ID,
+		Name:                 d.Name,
+		BaseURLTemplate:      d.BaseURLTemplate,
+		VariablesJSON:        string(variablesJSON),
+		ServiceOverridesJSON: string(overridesJSON),
+	}, nil
+}
+
+// Load reads and decodes an environment from the database
+func Load(database *sql.DB, id int64) (Definition, error) {
+	row, err := db.GetEnvironment(database, id)
+	if err != nil {
+		return Definition{}, fmt.Errorf("environment not found: %d", id)
+	}
+	return FromRow(row)
+}
+
+// VariablesFor returns the variables visible to a service in this environment.
+// serviceId and port are always defined; service overrides win over environment variables.
+func (d Definition) VariablesFor(serviceID string, port int) map[string]string {
+	vars := map[string]string{
+		"serviceId": serviceID,
+		"port":      strconv.Itoa(port),
+	}
+	for k, v := range d.Variables {
+		vars[k] = v
+	}
+	if override, ok := d.ServiceOverrides[serviceID]; ok {
+		for k, v := range override.Variables {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// BaseURL expands the base URL template for a service, honoring its override
+func (d Definition) BaseURL(serviceID string, port int) (string, error) {
+	tmpl := d.BaseURLTemplate
+	if override, ok := d.ServiceOverrides[serviceID]; ok && override.BaseURLTemplate != "" {
+		tmpl = override.BaseURLTemplate
+	}
+
+	url, err := templating.Expand(tmpl, templating.MapLookup(d.VariablesFor(serviceID, port)))
+	if err != nil {
+		return "", fmt.Errorf("environment %s: %w", d.Name, err)
+	}
+	return url, nil
+}
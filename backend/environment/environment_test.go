@@ -0,0 +1,71 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/triplewhale/postwhale/db"
+)
+
+func TestBaseURL(t *testing.T) {
+	def := Definition{
+		Name:            "preview",
+		BaseURLTemplate: "https://{{host}}/{{serviceId}}",
+		Variables:       map[string]string{"host": "preview.example.com"},
+		ServiceOverrides: map[string]ServiceOverride{
+			"moby":  {BaseURLTemplate: "http://localhost:{{port}}"},
+			"atlas": {Variables: map[string]string{"host": "tunnel.example.com"}},
+		},
+	}
+
+	cases := map[string]string{
+		"fusion": "https://preview.example.com/fusion",
+		"moby":   "http://localhost:8080",
+		"atlas":  "https://tunnel.example.com/atlas",
+	}
+	for serviceID, expected := range cases {
+		url, err := def.BaseURL(serviceID, 8080)
+		if err != nil {
+			t.Fatalf("BaseURL(%s) failed: %v", serviceID, err)
+		}
+		if url != expected {
+			t.Errorf("BaseURL(%s) = %q, want %q", serviceID, url, expected)
+		}
+	}
+}
+
+func TestBaseURL_UnresolvedVariable(t *testing.T) {
+	def := Definition{Name: "broken", BaseURLTemplate: "https://{{host}}"}
+
+	if _, err := def.BaseURL("fusion", 0); err == nil {
+		t.Error("Expected error for unresolved host variable")
+	}
+}
+
+func TestRowRoundTrip(t *testing.T) {
+	def := Definition{
+		Name:             "tunnel",
+		BaseURLTemplate:  "https://{{host}}",
+		Variables:        map[string]string{"host": "abc.ngrok.io"},
+		ServiceOverrides: map[string]ServiceOverride{"fusion": {BaseURLTemplate: "http://localhost:3000"}},
+	}
+
+	row, err := def.ToRow()
+	if err != nil {
+		t.Fatalf("ToRow failed: %v", err)
+	}
+
+	decoded, err := FromRow(row)
+	if err != nil {
+		t.Fatalf("FromRow failed: %v", err)
+	}
+	if decoded.Variables["host"] != "abc.ngrok.io" {
+		t.Errorf("Expected host variable to round-trip, got %v", decoded.Variables)
+	}
+	if decoded.ServiceOverrides["fusion"].BaseURLTemplate != "http://localhost:3000" {
+		t.Errorf("Expected override to round-trip, got %v", decoded.ServiceOverrides)
+	}
+
+	if _, err := FromRow(db.Environment{Name: "bad", VariablesJSON: "not json"}); err == nil {
+		t.Error("Expected error for invalid variables JSON")
+	}
+}
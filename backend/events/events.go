@@ -0,0 +1,105 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AllTopics subscribes to every topic
+const AllTopics = "*"
+
+// Topics published by the backend
+const (
+	TopicRepositoryChanged = "repositoryChanged"
+)
+
+// Event is a message published on a topic
+type Event struct {
+	Topic string
+	Data  interface{}
+}
+
+// Publisher is what subsystems publish events through. Publishing never
+// blocks on the client and is a no-op when nobody subscribed to the topic.
+type Publisher interface {
+	Publish(topic string, data interface{})
+}
+
+// Discard is a Publisher that drops every event
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(string, interface{}) {}
+
+// Bus tracks which topics the client subscribed to and hands events on
+// those topics to a sink. Subscriptions are counted per topic, so several
+// consumers in the client can subscribe and unsubscribe independently.
+type Bus struct {
+	mu     sync.RWMutex
+	topics map[string]int
+	sink   func(Event)
+}
+
+// NewBus creates a Bus that delivers subscribed events to sink
+func NewBus(sink func(Event)) *Bus {
+	return &Bus{
+		topics: make(map[string]int),
+		sink:   sink,
+	}
+}
+
+// Subscribe starts delivering events on topic. Each Subscribe needs its own
+// Unsubscribe before delivery stops.
+func (b *Bus) Subscribe(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic cannot be empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.topics[topic]++
+	return nil
+}
+
+// Unsubscribe releases one subscription to topic; delivery stops when none
+// are left. Unsubscribing from a topic that isn't subscribed is a no-op, and
+// unsubscribing from AllTopics leaves topics subscribed by name alone.
+func (b *Bus) Unsubscribe(topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] > 1 {
+		b.topics[topic]--
+		return
+	}
+	delete(b.topics, topic)
+}
+
+// Subscribed reports whether events on topic are delivered
+func (b *Bus) Subscribed(topic string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.topics[topic] > 0 || b.topics[AllTopics] > 0
+}
+
+// Topics returns the subscribed topics in sorted order
+func (b *Bus) Topics() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	topics := make([]string, 0, len(b.topics))
+	for topic := range b.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Publish delivers an event to the sink if its topic is subscribed
+func (b *Bus) Publish(topic string, data interface{}) {
+	if b.sink == nil || !b.Subscribed(topic) {
+		return
+	}
+	b.sink(Event{Topic: topic, Data: data})
+}
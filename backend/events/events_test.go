@@ -0,0 +1,86 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBus_DeliversSubscribedTopics(t *testing.T) {
+	var got []Event
+	bus := NewBus(func(e Event) { got = append(got, e) })
+
+	bus.Publish(TopicRepositoryChanged, 1)
+	if len(got) != 0 {
+		t.Fatalf("Expected nothing before subscribing, got %v", got)
+	}
+
+	if err := bus.Subscribe(TopicRepositoryChanged); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	bus.Publish(TopicRepositoryChanged, 2)
+	bus.Publish("other", 3)
+	if !reflect.DeepEqual(got, []Event{{Topic: TopicRepositoryChanged, Data: 2}}) {
+		t.Errorf("Expected only the subscribed topic, got %v", got)
+	}
+
+	bus.Unsubscribe(TopicRepositoryChanged)
+	bus.Publish(TopicRepositoryChanged, 4)
+	if len(got) != 1 {
+		t.Errorf("Expected nothing after unsubscribing, got %v", got)
+	}
+}
+
+func TestBus_AllTopics(t *testing.T) {
+	var got []string
+	bus := NewBus(func(e Event) { got = append(got, e.Topic) })
+
+	bus.Subscribe(AllTopics)
+	bus.Subscribe("b")
+	bus.Publish("a", nil)
+	bus.Publish("b", nil)
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Expected every topic, got %v", got)
+	}
+	if topics := bus.Topics(); !reflect.DeepEqual(topics, []string{"*", "b"}) {
+		t.Errorf("Expected sorted topics, got %v", topics)
+	}
+
+	// Topics subscribed by name stay subscribed
+	bus.Unsubscribe(AllTopics)
+	if bus.Subscribed("a") || !bus.Subscribed("b") {
+		t.Errorf("Expected only b to stay subscribed, got %v", bus.Topics())
+	}
+}
+
+func TestBus_CountsSubscriptions(t *testing.T) {
+	bus := NewBus(nil)
+
+	// Two consumers subscribe; one leaving doesn't silence the other
+	bus.Subscribe("a")
+	bus.Subscribe("a")
+	bus.Unsubscribe("a")
+	if !bus.Subscribed("a") {
+		t.Error("Expected a to stay subscribed while a subscription is left")
+	}
+	bus.Unsubscribe("a")
+	if bus.Subscribed("a") {
+		t.Error("Expected a to be unsubscribed once every subscription is released")
+	}
+
+	// Extra unsubscribes don't go below zero
+	bus.Unsubscribe("a")
+	bus.Subscribe("a")
+	if !bus.Subscribed("a") {
+		t.Error("Expected a to be subscribed after an unmatched unsubscribe")
+	}
+}
+
+func TestBus_SubscribeEmptyTopic(t *testing.T) {
+	bus := NewBus(nil)
+	if err := bus.Subscribe(""); err == nil {
+		t.Error("Expected an error for an empty topic")
+	}
+	// A bus without a sink drops events
+	bus.Subscribe("a")
+	bus.Publish("a", nil)
+}
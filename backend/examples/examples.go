@@ -0,0 +1,445 @@
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+// Mode selects which optional parts of a request are generated
+type Mode string
+
+const (
+	ModeRequired Mode = "required" // only required parameters and properties
+	ModeFull     Mode = "full"     // every documented parameter and property
+)
+
+// maxDepth bounds nesting so deeply recursive schemas still terminate
+const maxDepth = 8
+
+// Param is a query parameter or header in the {key, value, enabled} shape saved requests use
+type Param struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Request is an example request generated from an endpoint spec
+type Request struct {
+	PathParams  map[string]string `json:"pathParams"`
+	QueryParams []Param           `json:"queryParams"`
+	Headers     []Param           `json:"headers"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        string            `json:"body"`
+}
+
+// ParseMode validates a mode name; empty means ModeFull
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case "", ModeFull:
+		return ModeFull, nil
+	case ModeRequired:
+		return ModeRequired, nil
+	default:
+		return "", fmt.Errorf("unknown example mode %q (expected %q or %q)", mode, ModeRequired, ModeFull)
+	}
+}
+
+// Generate builds an example request for an endpoint. Explicit examples in
+// the spec win; everything else is synthesized from the schemas.
+func Generate(endpoint discovery.APIEndpoint, mode Mode) Request {
+	req := Request{
+		PathParams:  map[string]string{},
+		QueryParams: []Param{},
+		Headers:     []Param{},
+	}
+
+	for _, param := range endpoint.Parameters {
+		if !param.Required && param.In != "path" && mode == ModeRequired {
+			continue
+		}
+
+		value := parameterValue(param, mode)
+		switch param.In {
+		case "path":
+			req.PathParams[param.Name] = paramString(value)
+		case "query":
+			// Arrays are exploded into repeated keys, the OpenAPI default for query parameters
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					req.QueryParams = append(req.QueryParams, Param{Key: param.Name, Value: paramString(item), Enabled: true})
+				}
+				continue
+			}
+			req.QueryParams = append(req.QueryParams, Param{Key: param.Name, Value: paramString(value), Enabled: true})
+		case "header":
+			// OpenAPI ignores these as header parameters
+			switch strings.ToLower(param.Name) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			req.Headers = append(req.Headers, Param{Key: param.Name, Value: paramString(value), Enabled: true})
+		}
+	}
+
+	if endpoint.RequestBody != nil {
+		if contentType, media, ok := bodyMediaType(endpoint.RequestBody.Content); ok {
+			req.ContentType = contentType
+			req.Body = encodeBody(contentType, mediaValue(media, mode))
+			req.Headers = append(req.Headers, Param{Key: "Content-Type", Value: contentType, Enabled: true})
+		}
+	}
+
+	return req
+}
+
+// Value synthesizes an example value for a schema
+func Value(schema discovery.Schema, mode Mode) interface{} {
+	return value(schema, mode, 0)
+}
+
+// value synthesizes an example, preferring example, default and enum over the type
+func value(schema discovery.Schema, mode Mode, depth int) interface{} {
+	if depth > maxDepth || isBareRef(schema) {
+		return nil
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+
+	if len(schema.AllOf) > 0 {
+		return allOfValue(schema, mode, depth)
+	}
+
+	options := schema.OneOf
+	if len(options) == 0 {
+		options = schema.AnyOf
+	}
+	if len(options) > 0 {
+		v := value(options[0], mode, depth+1)
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		// Copy so examples taken from the spec aren't modified
+		merged := objectValue(schema, mode, depth)
+		for k, prop := range obj {
+			merged[k] = prop
+		}
+		if schema.Discriminator != nil {
+			merged[schema.Discriminator.PropertyName] = discriminatorValue(schema.Discriminator, options[0])
+		}
+		return merged
+	}
+
+	switch schema.Type {
+	case "object":
+		return objectValue(schema, mode, depth)
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		items := []interface{}{}
+		count := 1
+		if schema.MinItems != nil && *schema.MinItems > count {
+			count = *schema.MinItems
+		}
+		for i := 0; i < count; i++ {
+			items = append(items, value(*schema.Items, mode, depth+1))
+		}
+		return items
+	case "string":
+		return stringValue(schema)
+	case "integer":
+		return int64(numberValue(schema, 1))
+	case "number":
+		return numberValue(schema, 0.5)
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	}
+
+	// Untyped schemas with properties are objects
+	if len(schema.Properties) > 0 {
+		return objectValue(schema, mode, depth)
+	}
+	return nil
+}
+
+// allOfValue merges the examples of every allOf member with the schema's own properties
+func allOfValue(schema discovery.Schema, mode Mode, depth int) interface{} {
+	merged := map[string]interface{}{}
+	var scalar interface{}
+	for _, sub := range schema.AllOf {
+		v := value(sub, mode, depth+1)
+		if obj, ok := v.(map[string]interface{}); ok {
+			for k, prop := range obj {
+				merged[k] = prop
+			}
+		} else if v != nil && scalar == nil {
+			scalar = v
+		}
+	}
+	for k, prop := range objectValue(schema, mode, depth) {
+		merged[k] = prop
+	}
+
+	if len(merged) == 0 && scalar != nil {
+		return scalar
+	}
+	return merged
+}
+
+// objectValue builds an object from the schema's properties. Read-only
+// properties are left out since they are never sent in a request.
+func objectValue(schema discovery.Schema, mode Mode, depth int) map[string]interface{} {
+	obj := map[string]interface{}{}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	for name, prop := range schema.Properties {
+		if prop.ReadOnly || isBareRef(prop) {
+			continue
+		}
+		if mode == ModeRequired && !required[name] {
+			continue
+		}
+		obj[name] = value(prop, mode, depth+1)
+	}
+
+	// Maps without declared properties get one sample entry
+	if extra := schema.AdditionalProperties; extra != nil && extra.Schema != nil && len(schema.Properties) == 0 && mode == ModeFull {
+		obj["key"] = value(*extra.Schema, mode, depth+1)
+	}
+
+	return obj
+}
+
+// discriminatorValue finds the discriminator value that selects option:
+// its key in the mapping, else the schema name at the end of its $ref
+func discriminatorValue(d *discovery.Discriminator, option discovery.Schema) string {
+	names := make([]string, 0, len(d.Mapping))
+	for name := range d.Mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if d.Mapping[name] == option.Ref {
+			return name
+		}
+	}
+	if i := strings.LastIndex(option.Ref, "/"); i >= 0 {
+		return option.Ref[i+1:]
+	}
+	return option.Ref
+}
+
+// stringValue synthesizes a string for common formats, respecting length limits
+func stringValue(schema discovery.Schema) string {
+	var s string
+	switch schema.Format {
+	case "uuid":
+		s = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "date-time":
+		s = "2024-01-01T12:00:00Z"
+	case "date":
+		s = "2024-01-01"
+	case "time":
+		s = "12:00:00"
+	case "email":
+		s = "user@example.com"
+	case "uri", "url":
+		s = "https://example.com"
+	case "hostname":
+		s = "example.com"
+	case "ipv4":
+		s = "192.168.0.1"
+	case "ipv6":
+		s = "::1"
+	case "byte":
+		s = "ZXhhbXBsZQ=="
+	case "password":
+		s = "password"
+	default:
+		s = "string"
+	}
+
+	if schema.MinLength != nil && len(s) < *schema.MinLength {
+		s += strings.Repeat("x", *schema.MinLength-len(s))
+	}
+	if schema.MaxLength != nil && len(s) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+	return s
+}
+
+// numberValue picks fallback when allowed, otherwise a value inside the bounds
+func numberValue(schema discovery.Schema, fallback float64) float64 {
+	v := fallback
+	if min := schema.Minimum; min != nil && (v < *min || (schema.ExclusiveMinimum && v <= *min)) {
+		v = *min
+		if schema.ExclusiveMinimum {
+			v++
+		}
+	}
+	if max := schema.Maximum; max != nil && (v > *max || (schema.ExclusiveMaximum && v >= *max)) {
+		v = *max
+		if schema.ExclusiveMaximum {
+			v--
+		}
+	}
+	return v
+}
+
+// parameterValue uses a parameter's own example before its schema
+func parameterValue(param discovery.Parameter, mode Mode) interface{} {
+	if param.Example != nil {
+		return param.Example
+	}
+	if example, ok := firstExample(param.Examples); ok {
+		return example
+	}
+	return Value(param.Schema, mode)
+}
+
+// mediaValue uses a media type's example before its schema
+func mediaValue(media discovery.MediaType, mode Mode) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+	if example, ok := firstExample(media.Examples); ok {
+		return example
+	}
+	return Value(media.Schema, mode)
+}
+
+// firstExample returns the value of the alphabetically first named example
+func firstExample(examples map[string]discovery.Example) (interface{}, bool) {
+	if len(examples) == 0 {
+		return nil, false
+	}
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return examples[names[0]].Value, true
+}
+
+// bodyMediaType prefers JSON, then form encodings, then whatever is documented first
+func bodyMediaType(content map[string]discovery.MediaType) (string, discovery.MediaType, bool) {
+	if media, ok := content["application/json"]; ok {
+		return "application/json", media, true
+	}
+
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+
+	for _, contentType := range types {
+		if strings.Contains(contentType, "json") {
+			return contentType, content[contentType], true
+		}
+	}
+	if media, ok := content["application/x-www-form-urlencoded"]; ok {
+		return "application/x-www-form-urlencoded", media, true
+	}
+	if len(types) > 0 {
+		return types[0], content[types[0]], true
+	}
+	return "", discovery.MediaType{}, false
+}
+
+// encodeBody renders an example value for a content type
+func encodeBody(contentType string, v interface{}) string {
+	if contentType == "application/x-www-form-urlencoded" {
+		if obj, ok := jsonValue(v).(map[string]interface{}); ok {
+			form := url.Values{}
+			for k, field := range obj {
+				form.Set(k, paramString(field))
+			}
+			return form.Encode()
+		}
+	}
+
+	if s, ok := v.(string); ok && !strings.Contains(contentType, "json") {
+		return s
+	}
+
+	data, err := json.MarshalIndent(jsonValue(v), "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// jsonValue converts YAML-decoded examples into values encoding/json can
+// marshal; mappings with non-string keys decode to map[interface{}]interface{}
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(t))
+		for k, field := range t {
+			obj[fmt.Sprint(k)] = jsonValue(field)
+		}
+		return obj
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(t))
+		for k, field := range t {
+			obj[k] = jsonValue(field)
+		}
+		return obj
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, item := range t {
+			list[i] = jsonValue(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// paramString renders a value for a path, query or header parameter
+func paramString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, item := range t {
+			parts[i] = paramString(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}, map[interface{}]interface{}:
+		data, _ := json.Marshal(jsonValue(t))
+		return string(data)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// isBareRef reports whether schema is an unexpanded (recursive) reference
+func isBareRef(schema discovery.Schema) bool {
+	return schema.Ref != "" && schema.Type == "" && len(schema.Properties) == 0 &&
+		schema.Items == nil && len(schema.AllOf) == 0 && len(schema.OneOf) == 0 &&
+		len(schema.AnyOf) == 0 && len(schema.Enum) == 0
+}
@@ -0,0 +1,168 @@
+package examples
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
+
+func orderEndpoint() discovery.APIEndpoint {
+	base := discovery.Schema{
+		Type:     "object",
+		Required: []string{"shopId"},
+		Properties: map[string]discovery.Schema{
+			"id":     {Type: "string", Format: "uuid", ReadOnly: true},
+			"shopId": {Type: "string", Example: "shop_1"},
+		},
+	}
+	order := discovery.Schema{
+		AllOf: []discovery.Schema{base, {
+			Type:     "object",
+			Required: []string{"qty", "status"},
+			Properties: map[string]discovery.Schema{
+				"qty":      {Type: "integer", Minimum: floatPtr(1), ExclusiveMinimum: true},
+				"status":   {Type: "string", Enum: []interface{}{"open", "closed"}},
+				"email":    {Type: "string", Format: "email"},
+				"placedAt": {Type: "string", Format: "date-time"},
+				"code":     {Type: "string", MinLength: intPtr(8)},
+				"tags":     {Type: "array", Items: &discovery.Schema{Type: "string"}},
+				"parent":   {Ref: "#/components/schemas/Order"},
+			},
+		}},
+	}
+
+	return discovery.APIEndpoint{
+		Method: "POST",
+		Path:   "/shops/{shopId}/orders",
+		Parameters: []discovery.Parameter{
+			{Name: "shopId", In: "path", Required: true, Schema: discovery.Schema{Type: "string"}, Example: "shop_1"},
+			{Name: "dryRun", In: "query", Schema: discovery.Schema{Type: "boolean"}},
+			{Name: "fields", In: "query", Required: true, Schema: discovery.Schema{Type: "array", Items: &discovery.Schema{Type: "string", Enum: []interface{}{"id", "qty"}}}},
+			{Name: "X-Request-Id", In: "header", Schema: discovery.Schema{Type: "string", Format: "uuid"}},
+			{Name: "Authorization", In: "header", Required: true, Schema: discovery.Schema{Type: "string"}},
+		},
+		RequestBody: &discovery.RequestBody{
+			Required: true,
+			Content:  map[string]discovery.MediaType{"application/json": {Schema: order}},
+		},
+	}
+}
+
+func TestGenerate_Full(t *testing.T) {
+	req := Generate(orderEndpoint(), ModeFull)
+
+	if req.PathParams["shopId"] != "shop_1" {
+		t.Errorf("Expected path param from example, got %v", req.PathParams)
+	}
+	if len(req.QueryParams) != 2 || req.QueryParams[0].Value != "true" || req.QueryParams[1] != (Param{Key: "fields", Value: "id", Enabled: true}) {
+		t.Errorf("Unexpected query params: %+v", req.QueryParams)
+	}
+	if len(req.Headers) != 2 || req.Headers[0].Key != "X-Request-Id" || req.Headers[1].Value != "application/json" {
+		t.Errorf("Expected request id and content type headers, got %+v", req.Headers)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %q: %v", req.Body, err)
+	}
+	expected := map[string]interface{}{
+		"shopId":   "shop_1",
+		"qty":      float64(2),
+		"status":   "open",
+		"email":    "user@example.com",
+		"placedAt": "2024-01-01T12:00:00Z",
+		"code":     "stringxx",
+		"tags":     []interface{}{"string"},
+	}
+	if len(body) != len(expected) {
+		t.Errorf("Expected %d fields (no readOnly id, no recursive parent), got %v", len(expected), body)
+	}
+	for k, want := range expected {
+		got, _ := json.Marshal(body[k])
+		wantJSON, _ := json.Marshal(want)
+		if string(got) != string(wantJSON) {
+			t.Errorf("body[%s] = %s, want %s", k, got, wantJSON)
+		}
+	}
+}
+
+func TestGenerate_Required(t *testing.T) {
+	req := Generate(orderEndpoint(), ModeRequired)
+
+	if len(req.QueryParams) != 1 || req.QueryParams[0].Key != "fields" {
+		t.Errorf("Expected only the required query param, got %+v", req.QueryParams)
+	}
+	if len(req.Headers) != 1 {
+		t.Errorf("Expected only the content type header, got %+v", req.Headers)
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal([]byte(req.Body), &body)
+	if len(body) != 3 || body["shopId"] == nil || body["qty"] == nil || body["status"] == nil {
+		t.Errorf("Expected only required fields, got %v", body)
+	}
+}
+
+func TestGenerate_MediaExamples(t *testing.T) {
+	endpoint := discovery.APIEndpoint{
+		RequestBody: &discovery.RequestBody{Content: map[string]discovery.MediaType{
+			"application/json": {
+				Schema: discovery.Schema{Type: "object"},
+				Examples: map[string]discovery.Example{
+					"b-large": {Value: map[string]interface{}{"qty": 100}},
+					"a-small": {Value: map[string]interface{}{"qty": 1}},
+				},
+			},
+		}},
+	}
+
+	if req := Generate(endpoint, ModeFull); req.Body != "{\n  \"qty\": 1\n}" {
+		t.Errorf("Expected first named example, got %q", req.Body)
+	}
+
+	form := discovery.APIEndpoint{
+		RequestBody: &discovery.RequestBody{Content: map[string]discovery.MediaType{
+			"application/x-www-form-urlencoded": {Schema: discovery.Schema{
+				Type:       "object",
+				Properties: map[string]discovery.Schema{"name": {Type: "string"}, "age": {Type: "integer", Maximum: floatPtr(0)}},
+			}},
+		}},
+	}
+	if req := Generate(form, ModeFull); req.Body != "age=0&name=string" || req.ContentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected form encoded body, got %q (%s)", req.Body, req.ContentType)
+	}
+}
+
+func TestValue_OneOfDiscriminator(t *testing.T) {
+	schema := discovery.Schema{
+		OneOf: []discovery.Schema{
+			{Ref: "#/components/schemas/Cat", Type: "object", Properties: map[string]discovery.Schema{"lives": {Type: "integer"}}},
+			{Ref: "#/components/schemas/Dog", Type: "object"},
+		},
+		Discriminator: &discovery.Discriminator{PropertyName: "kind", Mapping: map[string]string{"cat": "#/components/schemas/Cat"}},
+	}
+
+	v, ok := Value(schema, ModeFull).(map[string]interface{})
+	if !ok || v["kind"] != "cat" || v["lives"] != int64(1) {
+		t.Errorf("Expected first alternative tagged by the discriminator, got %v", v)
+	}
+
+	schema.Discriminator.Mapping = nil
+	if v := Value(schema, ModeFull).(map[string]interface{}); v["kind"] != "Cat" {
+		t.Errorf("Expected implicit schema name, got %v", v["kind"])
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(""); err != nil || mode != ModeFull {
+		t.Errorf("Expected empty mode to default to full, got %q (%v)", mode, err)
+	}
+	if _, err := ParseMode("minimal"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,111 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/jsonpath"
+)
+
+// Sources a rule can read from
+const (
+	SourceBody   = "body"
+	SourceHeader = "header"
+	SourceStatus = "status"
+)
+
+// Rule extracts a value from a response into a variable
+type Rule struct {
+	Variable string `json:"variable" yaml:"variable"`
+	Source   string `json:"source" yaml:"source"`                 // body, header or status
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"` // JSONPath for body, header name for header
+	Scope    string `json:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+// Result is the outcome of applying a single rule
+type Result struct {
+	Variable string `json:"variable"`
+	Scope    string `json:"scope"`
+	Value    string `json:"value,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ParseRules decodes rules stored as JSON; empty input means no rules
+func ParseRules(rulesJSON string) ([]Rule, error) {
+	rules := []Rule{}
+	if rulesJSON == "" {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return nil, fmt.Errorf("invalid extraction rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Apply evaluates every rule against the response.
+// defaultScope is used for rules that don't name a scope.
+func Apply(rules []Rule, response client.Response, defaultScope string) []Result {
+	results := make([]Result, 0, len(rules))
+
+	// Decode the body lazily and only once
+	var doc interface{}
+	var docErr error
+	decoded := false
+
+	for _, rule := range rules {
+		result := Result{Variable: rule.Variable, Scope: rule.Scope}
+		if result.Scope == "" {
+			result.Scope = defaultScope
+		}
+
+		switch rule.Source {
+		case SourceStatus:
+			result.Value = strconv.Itoa(response.StatusCode)
+			result.OK = response.StatusCode != 0
+			if !result.OK {
+				result.Error = "no status code in response"
+			}
+		case SourceHeader:
+			values := response.HeaderValues(rule.Path)
+			if len(values) > 0 {
+				result.Value = values[0]
+				result.OK = true
+			} else {
+				result.Error = fmt.Sprintf("header not found: %s", rule.Path)
+			}
+		case SourceBody:
+			if !decoded {
+				doc, docErr = jsonpath.Decode([]byte(response.Body))
+				decoded = true
+			}
+			if docErr != nil {
+				result.Error = fmt.Sprintf("response body is not JSON: %v", docErr)
+				break
+			}
+			value, ok, err := jsonpath.First(doc, rule.Path)
+			switch {
+			case err != nil:
+				result.Error = err.Error()
+			case !ok:
+				result.Error = fmt.Sprintf("no match for %s", rule.Path)
+			default:
+				result.Value = jsonpath.String(value)
+				result.OK = true
+			}
+		default:
+			result.Error = fmt.Sprintf("unknown extraction source: %q", rule.Source)
+		}
+
+		if rule.Variable == "" {
+			result.OK = false
+			result.Error = "extraction rule has no variable name"
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
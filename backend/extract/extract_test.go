@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/triplewhale/postwhale/client"
+)
+
+func TestApply(t *testing.T) {
+	response := client.Response{
+		StatusCode: 201,
+		Headers:    map[string][]string{"Location": {"/orders/ord_1"}},
+		Body:       `{"order": {"id": "ord_1", "total": 42.5, "tags": ["a"]}}`,
+	}
+
+	rules := []Rule{
+		{Variable: "orderId", Source: SourceBody, Path: "$.order.id"},
+		{Variable: "total", Source: SourceBody, Path: "$.order.total", Scope: "service:fusion"},
+		{Variable: "tags", Source: SourceBody, Path: "$.order.tags"},
+		{Variable: "location", Source: SourceHeader, Path: "location"},
+		{Variable: "status", Source: SourceStatus},
+		{Variable: "missing", Source: SourceBody, Path: "$.nope"},
+	}
+
+	results := Apply(rules, response, "global")
+
+	expected := []Result{
+		{Variable: "orderId", Scope: "global", Value: "ord_1", OK: true},
+		{Variable: "total", Scope: "service:fusion", Value: "42.5", OK: true},
+		{Variable: "tags", Scope: "global", Value: `["a"]`, OK: true},
+		{Variable: "location", Scope: "global", Value: "/orders/ord_1", OK: true},
+		{Variable: "status", Scope: "global", Value: "201", OK: true},
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want)
+		}
+	}
+
+	if results[5].OK || results[5].Error == "" {
+		t.Errorf("Expected missing path to fail with an error, got %+v", results[5])
+	}
+}
+
+func TestApply_NonJSONBody(t *testing.T) {
+	results := Apply([]Rule{{Variable: "id", Source: SourceBody, Path: "$.id"}}, client.Response{Body: "<html>"}, "global")
+
+	if results[0].OK {
+		t.Error("Expected extraction from non-JSON body to fail")
+	}
+}
+
+func TestApply_LargeIntegerID(t *testing.T) {
+	// Above 2^53, so float64 would round it to 9007199254740992
+	response := client.Response{Body: `{"id": 9007199254740993}`}
+	results := Apply([]Rule{{Variable: "id", Source: SourceBody, Path: "$.id"}}, response, "global")
+
+	if !results[0].OK || results[0].Value != "9007199254740993" {
+		t.Errorf("Expected the exact ID, got %+v", results[0])
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(`[{"variable": "orderId", "source": "body", "path": "$.id"}]`)
+	if err != nil || len(rules) != 1 || rules[0].Variable != "orderId" {
+		t.Errorf("Unexpected rules: %+v (err=%v)", rules, err)
+	}
+
+	if rules, err := ParseRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("Expected empty rules for empty input, got %+v (err=%v)", rules, err)
+	}
+}
@@ -0,0 +1,140 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/environment"
+)
+
+// handleSaveEnvironment creates a new user-defined environment
+func (h *Handler) handleSaveEnvironment(data json.RawMessage) IPCResponse {
+	var input environment.Definition
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	row, err := input.ToRow()
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid environment: %v", err),
+		}
+	}
+
+	id, err := db.AddEnvironment(h.database, row)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to save environment: %v", err),
+		}
+	}
+
+	return h.environmentResponse(id)
+}
+
+// handleGetEnvironments retrieves all user-defined environments
+func (h *Handler) handleGetEnvironments() IPCResponse {
+	rows, err := db.GetEnvironments(h.database)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get environments: %v", err),
+		}
+	}
+
+	result := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		def, err := environment.FromRow(row)
+		if err != nil {
+			return IPCResponse{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		result = append(result, def)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// handleUpdateEnvironment updates an existing environment
+func (h *Handler) handleUpdateEnvironment(data json.RawMessage) IPCResponse {
+	var input environment.Definition
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	row, err := input.ToRow()
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid environment: %v", err),
+		}
+	}
+
+	if err := db.UpdateEnvironment(h.database, row); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to update environment: %v", err),
+		}
+	}
+
+	return h.environmentResponse(input.ID)
+}
+
+// handleDeleteEnvironment deletes an environment
+func (h *Handler) handleDeleteEnvironment(data json.RawMessage) IPCResponse {
+	var input struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if err := db.DeleteEnvironment(h.database, input.ID); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to delete environment: %v", err),
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"deleted": true,
+		},
+	}
+}
+
+// environmentResponse returns the stored environment as decoded JSON
+func (h *Handler) environmentResponse(id int64) IPCResponse {
+	def, err := environment.Load(h.database, id)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    def,
+	}
+}
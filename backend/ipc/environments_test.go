@@ -0,0 +1,97 @@
+package ipc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleRequest_EnvironmentCRUD(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	saveResponse := handler.HandleRequest(IPCRequest{
+		Action: "saveEnvironment",
+		Data: json.RawMessage(`{
+			"name": "preview",
+			"baseUrlTemplate": "https://{{host}}/{{serviceId}}",
+			"variables": {"host": "preview.example.com"},
+			"serviceOverrides": {"fusion": {"baseUrlTemplate": "http://localhost:3000"}}
+		}`),
+	})
+	if !saveResponse.Success {
+		t.Fatalf("Expected success, got error: %s", saveResponse.Error)
+	}
+
+	getResponse := handler.HandleRequest(IPCRequest{Action: "getEnvironments", Data: json.RawMessage(`{}`)})
+	if !getResponse.Success {
+		t.Fatalf("Expected success, got error: %s", getResponse.Error)
+	}
+	environments := getResponse.Data.([]interface{})
+	if len(environments) != 1 {
+		t.Fatalf("Expected 1 environment, got %d", len(environments))
+	}
+
+	updateResponse := handler.HandleRequest(IPCRequest{
+		Action: "updateEnvironment",
+		Data:   json.RawMessage(`{"id": 1, "name": "preview-2", "baseUrlTemplate": "https://{{host}}"}`),
+	})
+	if !updateResponse.Success {
+		t.Fatalf("Expected success, got error: %s", updateResponse.Error)
+	}
+
+	deleteResponse := handler.HandleRequest(IPCRequest{Action: "deleteEnvironment", Data: json.RawMessage(`{"id": 1}`)})
+	if !deleteResponse.Success {
+		t.Fatalf("Expected success, got error: %s", deleteResponse.Error)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestWithEnvironment(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fusion/health" {
+			t.Errorf("Expected /fusion/health, got %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	saveData, _ := json.Marshal(map[string]interface{}{
+		"name":            "tunnel",
+		"baseUrlTemplate": "{{base}}/{{serviceId}}",
+		"variables":       map[string]string{"base": server.URL},
+	})
+	saveResponse := handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: saveData})
+	if !saveResponse.Success {
+		t.Fatalf("Failed to save environment: %s", saveResponse.Error)
+	}
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/health", "method": "GET", "environmentId": 1}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["statusCode"] != 200 {
+		t.Errorf("Expected status 200, got %v (error: %v)", dataMap["statusCode"], dataMap["error"])
+	}
+}
+
+func TestHandleRequest_ExecuteRequestUnknownEnvironment(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/health", "method": "GET", "environmentId": 99}`),
+	})
+	if response.Success {
+		t.Error("Expected failure for unknown environment")
+	}
+}
@@ -0,0 +1,79 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// topicsInput is the data of subscribe and unsubscribe requests
+type topicsInput struct {
+	Topics []string `json:"topics"`
+}
+
+// handleSubscribe subscribes the client to event topics. Subscriptions are
+// counted, so every subscribe should be paired with an unsubscribe.
+func (h *Handler) handleSubscribe(data json.RawMessage) IPCResponse {
+	var input topicsInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if len(input.Topics) == 0 {
+		return IPCResponse{
+			Success: false,
+			Error:   "topics are required",
+		}
+	}
+
+	// Check every topic first so a bad one doesn't leave the rest subscribed
+	for _, topic := range input.Topics {
+		if topic == "" {
+			return IPCResponse{
+				Success: false,
+				Error:   "topic cannot be empty",
+			}
+		}
+	}
+	for _, topic := range input.Topics {
+		h.events.Subscribe(topic)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"topics": h.events.Topics(),
+		},
+	}
+}
+
+// handleUnsubscribe unsubscribes the client from event topics
+func (h *Handler) handleUnsubscribe(data json.RawMessage) IPCResponse {
+	var input topicsInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if len(input.Topics) == 0 {
+		return IPCResponse{
+			Success: false,
+			Error:   "topics are required",
+		}
+	}
+
+	for _, topic := range input.Topics {
+		h.events.Unsubscribe(topic)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"topics": h.events.Topics(),
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package ipc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestHandleRequest_SubscribeAndUnsubscribe(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	var buf bytes.Buffer
+	handler.SetWriter(NewWriter(&buf))
+
+	// Nothing is sent before subscribing
+	handler.Events().Publish("repositoryChanged", map[string]interface{}{"id": 1})
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no event before subscribing, got %s", buf.String())
+	}
+
+	response := handler.HandleRequest(IPCRequest{
+		Action:    "subscribe",
+		Data:      json.RawMessage(`{"topics": ["repositoryChanged", "other"]}`),
+		RequestID: "sub-1",
+	})
+	if !response.Success {
+		t.Fatalf("Subscribe failed: %s", response.Error)
+	}
+	if topics := response.Data.(map[string]interface{})["topics"]; !reflect.DeepEqual(topics, []string{"other", "repositoryChanged"}) {
+		t.Errorf("Expected both topics subscribed, got %v", topics)
+	}
+
+	handler.Events().Publish("repositoryChanged", map[string]interface{}{"id": 1})
+	var event map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("Expected an event line, got %q: %v", buf.String(), err)
+	}
+	if event["event"] != "repositoryChanged" || event["requestId"] != nil || event["success"] != nil {
+		t.Errorf("Expected an event without response fields, got %v", event)
+	}
+
+	response = handler.HandleRequest(IPCRequest{Action: "unsubscribe", Data: json.RawMessage(`{"topics": ["repositoryChanged"]}`)})
+	if !response.Success {
+		t.Fatalf("Unsubscribe failed: %s", response.Error)
+	}
+	buf.Reset()
+	handler.Events().Publish("repositoryChanged", nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no event after unsubscribing, got %s", buf.String())
+	}
+
+	// StrictMode subscribes, unsubscribes and subscribes again; requests run
+	// concurrently, so the unsubscribe can be handled last
+	for _, action := range []string{"subscribe", "subscribe", "unsubscribe"} {
+		handler.HandleRequest(IPCRequest{Action: action, Data: json.RawMessage(`{"topics": ["repositoryChanged"]}`)})
+	}
+	handler.Events().Publish("repositoryChanged", nil)
+	if buf.Len() == 0 {
+		t.Error("Expected an event while a subscription is left")
+	}
+
+	// Topics are required
+	for _, action := range []string{"subscribe", "unsubscribe"} {
+		if resp := handler.HandleRequest(IPCRequest{Action: action, Data: json.RawMessage(`{}`)}); resp.Success {
+			t.Errorf("Expected %s without topics to fail", action)
+		}
+	}
+	if resp := handler.HandleRequest(IPCRequest{Action: "subscribe", Data: json.RawMessage(`{"topics": [""]}`)}); resp.Success {
+		t.Error("Expected an empty topic to fail")
+	}
+}
@@ -0,0 +1,103 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/examples"
+)
+
+// handleGenerateExampleRequest builds an example request from an endpoint's stored spec
+func (h *Handler) handleGenerateExampleRequest(data json.RawMessage) IPCResponse {
+	var input struct {
+		EndpointID int64  `json:"endpointId"`
+		Mode       string `json:"mode"` // "required" or "full" (default)
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	mode, err := examples.ParseMode(input.Mode)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	if _, err := db.GetEndpoint(h.database, input.EndpointID); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("endpoint not found: %d", input.EndpointID),
+		}
+	}
+
+	example := h.exampleRequest(input.EndpointID, mode)
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"endpointId":  input.EndpointID,
+			"mode":        mode,
+			"pathParams":  example.PathParams,
+			"queryParams": example.QueryParams,
+			"headers":     example.Headers,
+			"contentType": example.ContentType,
+			"body":        example.Body,
+		},
+	}
+}
+
+// exampleRequest generates an example for an endpoint; endpoints without a
+// stored spec produce an empty request
+func (h *Handler) exampleRequest(endpointID int64, mode examples.Mode) examples.Request {
+	spec := h.endpointSpec(endpointID)
+	if spec == nil {
+		spec = &discovery.APIEndpoint{}
+	}
+	return examples.Generate(*spec, mode)
+}
+
+// applyExampleDefaults fills the parts of a new saved request the caller left
+// out with a generated example, so new requests start from a valid payload.
+// Parts the example doesn't cover are stored empty.
+func (h *Handler) applyExampleDefaults(data json.RawMessage, saved *db.SavedRequest) {
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return
+	}
+
+	example := h.exampleRequest(saved.EndpointID, examples.ModeFull)
+	if _, ok := present["pathParamsJson"]; !ok {
+		saved.PathParamsJSON = "{}"
+		if len(example.PathParams) > 0 {
+			saved.PathParamsJSON = mustJSON(example.PathParams)
+		}
+	}
+	if _, ok := present["queryParamsJson"]; !ok {
+		saved.QueryParamsJSON = "[]"
+		if len(example.QueryParams) > 0 {
+			saved.QueryParamsJSON = mustJSON(example.QueryParams)
+		}
+	}
+	if _, ok := present["headersJson"]; !ok {
+		saved.HeadersJSON = "[]"
+		if len(example.Headers) > 0 {
+			saved.HeadersJSON = mustJSON(example.Headers)
+		}
+	}
+	if _, ok := present["body"]; !ok {
+		saved.Body = example.Body
+	}
+}
+
+// mustJSON marshals values that can't fail to encode
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
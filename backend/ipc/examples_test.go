@@ -0,0 +1,94 @@
+package ipc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/triplewhale/postwhale/examples"
+)
+
+// seedSpecEndpoint stores a POST endpoint whose spec has a path param and a JSON body
+func seedSpecEndpoint(t *testing.T, handler *Handler) {
+	t.Helper()
+
+	spec := `{"method": "POST", "path": "/shops/{shopId}/orders",
+		"parameters": [
+			{"name": "shopId", "in": "path", "required": true, "schema": {"type": "string"}, "example": "shop_1"},
+			{"name": "notify", "in": "query", "schema": {"type": "boolean"}}
+		],
+		"requestBody": {"required": true, "content": {"application/json": {"schema": {
+			"type": "object", "required": ["qty"],
+			"properties": {"qty": {"type": "integer", "minimum": 1}, "note": {"type": "string"}}
+		}}}}}`
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "orders", "Orders", 8080, "{}")
+	if _, err := handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "POST", "/shops/{shopId}/orders", "createOrder", spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleRequest_GenerateExampleRequest(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+	seedSpecEndpoint(t, handler)
+
+	response := handler.HandleRequest(IPCRequest{Action: "generateExampleRequest", Data: json.RawMessage(`{"endpointId": 1}`)})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["pathParams"].(map[string]string)["shopId"] != "shop_1" {
+		t.Errorf("Expected path param example, got %v", dataMap["pathParams"])
+	}
+	if query := dataMap["queryParams"].([]examples.Param); len(query) != 1 || query[0].Key != "notify" {
+		t.Errorf("Expected optional query param in full mode, got %v", query)
+	}
+	if body := dataMap["body"].(string); !strings.Contains(body, `"qty": 1`) || !strings.Contains(body, `"note"`) {
+		t.Errorf("Expected full body, got %s", body)
+	}
+
+	response = handler.HandleRequest(IPCRequest{Action: "generateExampleRequest", Data: json.RawMessage(`{"endpointId": 1, "mode": "required"}`)})
+	dataMap = response.Data.(map[string]interface{})
+	if len(dataMap["queryParams"].([]examples.Param)) != 0 || strings.Contains(dataMap["body"].(string), "note") {
+		t.Errorf("Expected required-only example, got %v", dataMap)
+	}
+
+	if resp := handler.HandleRequest(IPCRequest{Action: "generateExampleRequest", Data: json.RawMessage(`{"endpointId": 1, "mode": "everything"}`)}); resp.Success {
+		t.Error("Expected error for unknown mode")
+	}
+	if resp := handler.HandleRequest(IPCRequest{Action: "generateExampleRequest", Data: json.RawMessage(`{"endpointId": 9}`)}); resp.Success {
+		t.Error("Expected error for unknown endpoint")
+	}
+}
+
+func TestHandleRequest_SaveSavedRequestExampleDefaults(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+	seedSpecEndpoint(t, handler)
+
+	// Fields left out are generated
+	response := handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: json.RawMessage(`{"endpointId": 1, "name": "New"}`)})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["pathParamsJson"] != `{"shopId":"shop_1"}` || !strings.Contains(dataMap["body"].(string), `"qty"`) {
+		t.Errorf("Expected example defaults, got %v", dataMap)
+	}
+
+	// Fields sent explicitly, even empty, are kept
+	response = handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: json.RawMessage(`{"endpointId": 1, "name": "Blank", "body": "", "pathParamsJson": "{}"}`)})
+	dataMap = response.Data.(map[string]interface{})
+	if dataMap["body"] != "" || dataMap["pathParamsJson"] != "{}" {
+		t.Errorf("Expected explicit fields to be kept, got %v", dataMap)
+	}
+
+	// Without a spec, fields left out are stored empty
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/health", "health", "")
+	response = handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: json.RawMessage(`{"endpointId": 2, "name": "New"}`)})
+	dataMap = response.Data.(map[string]interface{})
+	if dataMap["pathParamsJson"] != "{}" || dataMap["queryParamsJson"] != "[]" || dataMap["headersJson"] != "[]" {
+		t.Errorf("Expected empty defaults, got %v", dataMap)
+	}
+}
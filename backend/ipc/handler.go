@@ -1,6 +1,7 @@
 package ipc
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,12 +9,18 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/triplewhale/postwhale/assertions"
 	"github.com/triplewhale/postwhale/client"
 	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/events"
+	"github.com/triplewhale/postwhale/extract"
 	"github.com/triplewhale/postwhale/portability"
 	"github.com/triplewhale/postwhale/scanner"
+	"github.com/triplewhale/postwhale/validation"
 )
 
 // IPCRequest represents an incoming IPC message
@@ -31,21 +38,99 @@ type IPCResponse struct {
 	RequestID interface{} `json:"requestId,omitempty"`
 }
 
+// IPCEvent is an unsolicited message that isn't tied to any request, such
+// as a repository changing on disk. Event is the topic it was published on;
+// only topics the client subscribed to are sent. Clients tell events apart
+// from responses by the presence of the event field.
+type IPCEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// IPCProgress is an intermediate message for a long-running request.
+// It carries the request's requestId but is not its final response; clients
+// tell the two apart by the presence of the progress field.
+type IPCProgress struct {
+	RequestID interface{} `json:"requestId,omitempty"`
+	Progress  interface{} `json:"progress"`
+}
+
 // Handler manages IPC requests and database operations
 type Handler struct {
 	database *sql.DB
+
+	// inflight maps requestId to the cancel func of an executing HTTP request.
+	// Requests are dispatched concurrently, so a cancel can overtake the
+	// request it names; cancelled records those until the request begins.
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelFunc
+	cancelled  map[string]time.Time
+
+	// out receives progress messages and events; nil drops them
+	out *Writer
+
+	// events delivers published events on subscribed topics to out
+	events *events.Bus
+
+	// syncMu serializes writes of scan results, which may come from a
+	// refresh request and the watcher at the same time
+	syncMu sync.Mutex
 }
 
 // NewHandler creates a new IPC handler with the specified database path
 func NewHandler(dbPath string) *Handler {
-	database, err := db.InitDB(dbPath)
+	h, err := OpenHandler(dbPath)
 	if err != nil {
 		// For testing with :memory:, this should not fail
-		panic(fmt.Sprintf("failed to initialize database: %v", err))
+		panic(err.Error())
+	}
+	return h
+}
+
+// OpenHandler creates a new IPC handler, returning an error if the database
+// can't be opened or migrated
+func OpenHandler(dbPath string) (*Handler, error) {
+	database, err := db.InitDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	h := &Handler{
+		database:  database,
+		inflight:  make(map[string]context.CancelFunc),
+		cancelled: make(map[string]time.Time),
 	}
+	h.events = events.NewBus(h.writeEvent)
+	return h, nil
+}
+
+// SetWriter sets where progress messages for long-running requests and events are written
+func (h *Handler) SetWriter(out *Writer) {
+	h.out = out
+}
 
-	return &Handler{
-		database: database,
+// sendProgress writes a progress message for a request, if a writer is set
+func (h *Handler) sendProgress(requestID interface{}, progress interface{}) {
+	if h.out == nil {
+		return
+	}
+	if err := h.out.WriteProgress(IPCProgress{RequestID: requestID, Progress: progress}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing progress: %v\n", err)
+	}
+}
+
+// Events returns the publisher subsystems use to notify the client
+func (h *Handler) Events() events.Publisher {
+	return h.events
+}
+
+// writeEvent writes a published event, if a writer is set
+func (h *Handler) writeEvent(event events.Event) {
+	if h.out == nil {
+		return
+	}
+	if err := h.out.WriteEvent(IPCEvent{Event: event.Topic, Data: event.Data}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing event: %v\n", err)
 	}
 }
 
@@ -55,6 +140,7 @@ func (h *Handler) Close() error {
 }
 
 // HandleRequest processes an IPC request and returns a response
+// It is safe to call concurrently from multiple goroutines
 func (h *Handler) HandleRequest(request IPCRequest) IPCResponse {
 	var response IPCResponse
 
@@ -71,10 +157,18 @@ func (h *Handler) HandleRequest(request IPCRequest) IPCResponse {
 		response = h.handleGetAllServices()
 	case "getEndpoints":
 		response = h.handleGetEndpoints(request.Data)
+	case "getEndpointSpec":
+		response = h.handleGetEndpointSpec(request.Data)
+	case "generateExampleRequest":
+		response = h.handleGenerateExampleRequest(request.Data)
 	case "getAllEndpoints":
 		response = h.handleGetAllEndpoints()
 	case "executeRequest":
-		response = h.handleExecuteRequest(request.Data)
+		response = h.handleExecuteRequest(request.RequestID, request.Data)
+	case "previewRequest":
+		response = h.handlePreviewRequest(request.Data)
+	case "cancelRequest":
+		response = h.handleCancelRequest(request.Data)
 	case "getRequestHistory":
 		response = h.handleGetRequestHistory(request.Data)
 	case "scanDirectory":
@@ -83,6 +177,8 @@ func (h *Handler) HandleRequest(request IPCRequest) IPCResponse {
 		response = h.handleCheckPath(request.Data)
 	case "refreshRepository":
 		response = h.handleRefreshRepository(request.Data)
+	case "updateRepositoryLayout":
+		response = h.handleUpdateRepositoryLayout(request.Data)
 	case "saveSavedRequest":
 		response = h.handleSaveSavedRequest(request.Data)
 	case "getSavedRequests":
@@ -101,8 +197,32 @@ func (h *Handler) HandleRequest(request IPCRequest) IPCResponse {
 		response = h.handleExportRepoSavedRequests(request.Data)
 	case "importRepoSavedRequests":
 		response = h.handleImportRepoSavedRequests(request.Data)
+	case "saveEnvironment":
+		response = h.handleSaveEnvironment(request.Data)
+	case "getEnvironments":
+		response = h.handleGetEnvironments()
+	case "updateEnvironment":
+		response = h.handleUpdateEnvironment(request.Data)
+	case "deleteEnvironment":
+		response = h.handleDeleteEnvironment(request.Data)
+	case "setVariable":
+		response = h.handleSetVariable(request.Data)
+	case "getVariables":
+		response = h.handleGetVariables(request.Data)
+	case "deleteVariable":
+		response = h.handleDeleteVariable(request.Data)
+	case "runCollection":
+		response = h.handleRunCollection(request.RequestID, request.Data)
+	case "getRuns":
+		response = h.handleGetRuns(request.Data)
+	case "getRun":
+		response = h.handleGetRun(request.Data)
 	case "runShellCommand":
 		response = h.handleRunShellCommand(request.Data)
+	case "subscribe":
+		response = h.handleSubscribe(request.Data)
+	case "unsubscribe":
+		response = h.handleUnsubscribe(request.Data)
 	default:
 		response = IPCResponse{
 			Success: false,
@@ -118,7 +238,8 @@ func (h *Handler) HandleRequest(request IPCRequest) IPCResponse {
 // handleAddRepository adds a repository and scans it for services
 func (h *Handler) handleAddRepository(data json.RawMessage) IPCResponse {
 	var input struct {
-		Path string `json:"path"`
+		Path   string         `json:"path"`
+		Layout scanner.Layout `json:"layout"`
 	}
 
 	if err := json.Unmarshal(data, &input); err != nil {
@@ -137,58 +258,46 @@ func (h *Handler) handleAddRepository(data json.RawMessage) IPCResponse {
 		}
 	}
 
-	// Scan repository for services
-	scanResult := scanner.ScanRepository(absPath)
-	if len(scanResult.Errors) > 0 {
+	if err := input.Layout.Validate(); err != nil {
 		return IPCResponse{
 			Success: false,
-			Error:   fmt.Sprintf("scan failed: %s", scanResult.Errors[0]),
+			Error:   fmt.Sprintf("invalid layout: %v", err),
 		}
 	}
 
-	// Add repository to database (use path as name for now)
-	repoID, err := db.AddRepository(h.database, db.Repository{
-		Name: filepath.Base(absPath),
-		Path: absPath,
-	})
-	if err != nil {
+	// Scan repository for services
+	scanResult := scanner.ScanRepositoryWith(absPath, scanner.ScanOptions{Layout: input.Layout})
+	if len(scanResult.Errors) > 0 && len(scanResult.Services) == 0 {
 		return IPCResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to add repository: %v", err),
+			Error:   fmt.Sprintf("scan failed: %s", scanResult.Errors[0]),
 		}
 	}
 
-	// Add discovered services to database
-	for _, svc := range scanResult.Services {
-		serviceID, err := db.AddService(h.database, db.Service{
-			RepoID:     repoID,
-			ServiceID:  svc.ServiceID,
-			Name:       svc.Name,
-			Port:       svc.Port,
-			ConfigJSON: "{}",
+	// Add the repository and its discovered services in one transaction, so a
+	// failure doesn't leave a repository with half its services
+	var repoID int64
+	var skipped []string
+	h.syncMu.Lock()
+	err = db.WithTx(h.database, func(tx *sql.Tx) error {
+		// Use path as name for now
+		id, err := db.AddRepository(tx, db.Repository{
+			Name:       filepath.Base(absPath),
+			Path:       absPath,
+			LayoutJSON: specJSON(input.Layout),
 		})
 		if err != nil {
-			return IPCResponse{
-				Success: false,
-				Error:   fmt.Sprintf("failed to add service %s: %v", svc.ServiceID, err),
-			}
+			return fmt.Errorf("failed to add repository: %v", err)
 		}
-
-		// Add endpoints for this service
-		for _, endpoint := range svc.Endpoints {
-			_, err := db.AddEndpoint(h.database, db.Endpoint{
-				ServiceID:   serviceID,
-				Method:      endpoint.Method,
-				Path:        endpoint.Path,
-				OperationID: endpoint.OperationID,
-				SpecJSON:    "{}",
-			})
-			if err != nil {
-				return IPCResponse{
-					Success: false,
-					Error:   fmt.Sprintf("failed to add endpoint %s: %v", endpoint.Path, err),
-				}
-			}
+		repoID = id
+		_, skipped, err = syncRepository(tx, repoID, scanResult.Services)
+		return err
+	})
+	h.syncMu.Unlock()
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
 		}
 	}
 
@@ -213,9 +322,11 @@ func (h *Handler) handleAddRepository(data json.RawMessage) IPCResponse {
 	return IPCResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"id":   addedRepo.ID,
-			"name": addedRepo.Name,
-			"path": addedRepo.Path,
+			"id":       addedRepo.ID,
+			"name":     addedRepo.Name,
+			"path":     addedRepo.Path,
+			"layout":   repositoryLayout(*addedRepo),
+			"warnings": append(append(scanResult.Errors, scanResult.Warnings...), skipped...),
 		},
 	}
 }
@@ -234,9 +345,10 @@ func (h *Handler) handleGetRepositories() IPCResponse {
 	result := make([]interface{}, len(repos))
 	for i, repo := range repos {
 		result[i] = map[string]interface{}{
-			"id":   repo.ID,
-			"name": repo.Name,
-			"path": repo.Path,
+			"id":     repo.ID,
+			"name":   repo.Name,
+			"path":   repo.Path,
+			"layout": repositoryLayout(repo),
 		}
 	}
 
@@ -299,11 +411,12 @@ func (h *Handler) handleGetServices(data json.RawMessage) IPCResponse {
 	result := make([]interface{}, len(services))
 	for i, svc := range services {
 		result[i] = map[string]interface{}{
-			"id":        svc.ID,
-			"repoId":    svc.RepoID,
-			"serviceId": svc.ServiceID,
-			"name":      svc.Name,
-			"port":      svc.Port,
+			"id":          svc.ID,
+			"repoId":      svc.RepoID,
+			"serviceId":   svc.ServiceID,
+			"name":        svc.Name,
+			"port":        svc.Port,
+			"deployments": serviceDeployments(svc.ConfigJSON),
 		}
 	}
 
@@ -326,11 +439,12 @@ func (h *Handler) handleGetAllServices() IPCResponse {
 	result := make([]interface{}, len(services))
 	for i, svc := range services {
 		result[i] = map[string]interface{}{
-			"id":        svc.ID,
-			"repoId":    svc.RepoID,
-			"serviceId": svc.ServiceID,
-			"name":      svc.Name,
-			"port":      svc.Port,
+			"id":          svc.ID,
+			"repoId":      svc.RepoID,
+			"serviceId":   svc.ServiceID,
+			"name":        svc.Name,
+			"port":        svc.Port,
+			"deployments": serviceDeployments(svc.ConfigJSON),
 		}
 	}
 
@@ -370,6 +484,7 @@ func (h *Handler) handleGetEndpoints(data json.RawMessage) IPCResponse {
 			"operationId": ep.OperationID,
 			"method":      ep.Method,
 			"path":        ep.Path,
+			"source":      endpointSource(ep),
 		}
 	}
 
@@ -397,6 +512,7 @@ func (h *Handler) handleGetAllEndpoints() IPCResponse {
 			"operationId": ep.OperationID,
 			"method":      ep.Method,
 			"path":        ep.Path,
+			"source":      endpointSource(ep),
 		}
 	}
 
@@ -406,18 +522,11 @@ func (h *Handler) handleGetAllEndpoints() IPCResponse {
 	}
 }
 
-// handleExecuteRequest executes an HTTP request
-func (h *Handler) handleExecuteRequest(data json.RawMessage) IPCResponse {
+// handleGetEndpointSpec returns the parsed OpenAPI operation for an endpoint
+// together with its service's info, servers and security schemes
+func (h *Handler) handleGetEndpointSpec(data json.RawMessage) IPCResponse {
 	var input struct {
-		ServiceID   string            `json:"serviceId"`
-		Port        int               `json:"port"`
-		Endpoint    string            `json:"endpoint"`
-		Method      string            `json:"method"`
-		Environment string            `json:"environment"`
-		Headers     map[string]string `json:"headers"`
-		Body        string            `json:"body"`
-		EndpointID  int64             `json:"endpointId,omitempty"`
-		AuthEnabled bool              `json:"authEnabled"`
+		EndpointID int64 `json:"endpointId"`
 	}
 
 	if err := json.Unmarshal(data, &input); err != nil {
@@ -427,21 +536,191 @@ func (h *Handler) handleExecuteRequest(data json.RawMessage) IPCResponse {
 		}
 	}
 
-	// Build client config
-	config := client.RequestConfig{
-		ServiceID:   input.ServiceID,
-		Port:        input.Port,
-		Endpoint:    input.Endpoint,
-		Method:      input.Method,
-		Environment: client.Environment(input.Environment),
-		Headers:     input.Headers,
-		Body:        input.Body,
-		Timeout:     30 * time.Second,
-		AuthEnabled: input.AuthEnabled,
+	ep, err := db.GetEndpoint(h.database, input.EndpointID)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("endpoint not found: %d", input.EndpointID),
+		}
+	}
+
+	svc, err := db.GetServiceByEndpoint(h.database, ep.ID)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get service: %v", err),
+		}
+	}
+
+	// Endpoints scanned before specs were stored have "{}"; fall back to the row
+	spec := discovery.APIEndpoint{}
+	_ = json.Unmarshal([]byte(ep.SpecJSON), &spec)
+	spec.Method = ep.Method
+	spec.Path = ep.Path
+	spec.OperationID = ep.OperationID
+
+	serviceSpec := discovery.ServiceSpec{}
+	_ = json.Unmarshal([]byte(svc.SpecJSON), &serviceSpec)
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"endpointId": ep.ID,
+			"spec":       spec,
+			"service": map[string]interface{}{
+				"id":              svc.ID,
+				"serviceId":       svc.ServiceID,
+				"name":            svc.Name,
+				"info":            serviceSpec.Info,
+				"servers":         serviceSpec.Servers,
+				"securitySchemes": serviceSpec.SecuritySchemes,
+				"security":        serviceSpec.Security,
+			},
+		},
+	}
+}
+
+// requestKey normalizes a requestId so the same JSON value always maps to the same key
+func requestKey(requestID interface{}) string {
+	if requestID == nil {
+		return ""
+	}
+	return fmt.Sprint(requestID)
+}
+
+// earlyCancelTTL is how long a cancel for a request that hasn't begun is kept
+const earlyCancelTTL = time.Minute
+
+// beginRequest registers a cancellable context for the given requestId
+// The returned func must be called once the request completes
+func (h *Handler) beginRequest(requestID interface{}) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	key := requestKey(requestID)
+	if key == "" {
+		return ctx, cancel
+	}
+
+	now := time.Now()
+	h.inflightMu.Lock()
+	h.inflight[key] = cancel
+	if at, ok := h.cancelled[key]; ok && now.Sub(at) <= earlyCancelTTL {
+		// The cancel arrived first
+		cancel()
+	}
+	delete(h.cancelled, key)
+	h.pruneCancelled(now)
+	h.inflightMu.Unlock()
+
+	return ctx, func() {
+		h.inflightMu.Lock()
+		delete(h.inflight, key)
+		h.inflightMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelInflight cancels the in-flight request with the given requestId
+// Returns false if no such request is running; the cancel is then recorded
+// and applied if the request begins within earlyCancelTTL
+func (h *Handler) cancelInflight(requestID interface{}) bool {
+	key := requestKey(requestID)
+	now := time.Now()
+
+	h.inflightMu.Lock()
+	cancel, ok := h.inflight[key]
+	if !ok {
+		h.pruneCancelled(now)
+		h.cancelled[key] = now
+	}
+	h.inflightMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// pruneCancelled drops recorded cancels older than earlyCancelTTL.
+// The caller must hold inflightMu.
+func (h *Handler) pruneCancelled(now time.Time) {
+	for id, at := range h.cancelled {
+		if now.Sub(at) > earlyCancelTTL {
+			delete(h.cancelled, id)
+		}
+	}
+}
+
+// handleCancelRequest aborts an in-flight executeRequest
+func (h *Handler) handleCancelRequest(data json.RawMessage) IPCResponse {
+	var input struct {
+		RequestID interface{} `json:"requestId"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if input.RequestID == nil {
+		return IPCResponse{
+			Success: false,
+			Error:   "requestId is required",
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"cancelled": h.cancelInflight(input.RequestID),
+		},
+	}
+}
+
+// handleExecuteRequest executes an HTTP request
+// The request can be aborted by a cancelRequest action carrying the same requestId
+func (h *Handler) handleExecuteRequest(requestID interface{}, data json.RawMessage) IPCResponse {
+	var input executeRequestInput
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	mode, err := validation.ParseMode(input.Validation)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	// Resolve the target URL and {{variables}}
+	prepared, err := h.prepareRequest(input)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	config := prepared.config
+
+	// Check the request against the endpoint's spec before sending it
+	var requestIssues []validation.Issue
+	if mode != validation.ModeOff {
+		requestIssues = h.validateRequest(input.EndpointID, prepared)
+		if mode == validation.ModeBlock && len(requestIssues) > 0 {
+			return blockedResponse(requestIssues)
+		}
 	}
 
 	// Execute the HTTP request
-	response := client.ExecuteRequest(config)
+	ctx, done := h.beginRequest(requestID)
+	defer done()
+	response := client.ExecuteRequest(ctx, config)
 
 	result := map[string]interface{}{
 		"statusCode":    response.StatusCode,
@@ -450,22 +729,59 @@ func (h *Handler) handleExecuteRequest(data json.RawMessage) IPCResponse {
 		"body":          response.Body,
 		"responseTime":  response.ResponseTime.Milliseconds(),
 		"remoteAddress": response.RemoteAddress,
+		"timing":        timingResult(response.Timing),
 	}
 
 	if response.Error != "" {
 		result["error"] = response.Error
 	}
 
+	if prepared.target != nil {
+		result["deployment"] = prepared.target
+	}
+	if requestIssues != nil {
+		result["requestIssues"] = requestIssues
+	}
+
+	if response.Cancelled {
+		result["cancelled"] = true
+		return IPCResponse{
+			Success: true,
+			Data:    result,
+		}
+	}
+
+	// Check the response against the documented responses so contract drift
+	// shows up in the result and in history
+	if mode != validation.ModeOff && response.Error == "" {
+		if issues := h.validateResponse(input.EndpointID, response); issues != nil {
+			result["responseIssues"] = issues
+		}
+	}
+
+	// Chain values from the response into variables for subsequent requests,
+	// then check the saved request's assertions. A request that failed has
+	// nothing to extract, and its assertions all fail.
+	if input.SavedRequestID > 0 {
+		if response.Error == "" {
+			result["extractions"] = h.applyExtractions(input.SavedRequestID, response)
+		}
+		if results, ok := h.evaluateAssertions(input.SavedRequestID, input.EndpointID, response); ok {
+			result["assertions"] = results
+			result["assertionsPassed"] = assertions.Passed(results)
+		}
+	}
+
 	// Save to request history if endpointId provided
 	if input.EndpointID > 0 {
-		headersJSON, _ := json.Marshal(input.Headers)
+		headersJSON, _ := json.Marshal(config.Headers)
 		responseJSON, _ := json.Marshal(result)
 
 		_, _ = db.AddRequest(h.database, db.Request{
 			EndpointID:  input.EndpointID,
-			Environment: input.Environment,
+			Environment: prepared.environment,
 			Headers:     string(headersJSON),
-			Body:        input.Body,
+			Body:        config.Body,
 			Response:    string(responseJSON),
 		})
 	}
@@ -476,6 +792,151 @@ func (h *Handler) handleExecuteRequest(data json.RawMessage) IPCResponse {
 	}
 }
 
+// resolveDeployment looks up the stored tw-config.json for the request's service
+// and selects the deployment endpoint to target, if the config declares one
+func (h *Handler) resolveDeployment(endpointID int64, serviceID, env string, selector discovery.EndpointSelector, authEnabled bool) (discovery.DeploymentEndpoint, bool) {
+	var svc db.Service
+	var err error
+	if endpointID > 0 {
+		svc, err = db.GetServiceByEndpoint(h.database, endpointID)
+	} else {
+		svc, err = db.GetServiceByServiceID(h.database, serviceID)
+	}
+	if err != nil {
+		return discovery.DeploymentEndpoint{}, false
+	}
+
+	defaultName := "internal"
+	if authEnabled {
+		defaultName = "public"
+	}
+
+	return discovery.SelectEndpoint(serviceDeployments(svc.ConfigJSON), env, selector, defaultName)
+}
+
+// configJSON serializes a service's tw-config.json for storage
+func configJSON(config *discovery.TWConfig) string {
+	if config == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// specJSON serializes a parsed OpenAPI spec fragment for storage
+func specJSON(spec interface{}) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// specSource splits an endpoint's spec source into its stored columns
+func specSource(endpoint discovery.APIEndpoint) (file, visibility string) {
+	if endpoint.Source == nil {
+		return "", ""
+	}
+	return endpoint.Source.File, endpoint.Source.Visibility
+}
+
+// endpointSource returns a stored endpoint's spec source, or nil when unknown
+func endpointSource(ep db.Endpoint) *discovery.SpecSource {
+	if ep.SpecFile == "" {
+		return nil
+	}
+	return &discovery.SpecSource{File: ep.SpecFile, Visibility: ep.Visibility}
+}
+
+// serviceDeployments parses a stored config_json into its deployment endpoints
+func serviceDeployments(configJSON string) []discovery.DeploymentEndpoint {
+	var config discovery.TWConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return []discovery.DeploymentEndpoint{}
+	}
+	return config.ResolveEndpoints()
+}
+
+// applyExtractions runs a saved request's extraction rules and persists the extracted variables
+func (h *Handler) applyExtractions(savedRequestID int64, response client.Response) []extract.Result {
+	saved, err := db.GetSavedRequest(h.database, savedRequestID)
+	if err != nil {
+		return []extract.Result{}
+	}
+
+	rules, err := extract.ParseRules(saved.ExtractionsJSON)
+	if err != nil {
+		return []extract.Result{{OK: false, Error: err.Error()}}
+	}
+
+	results := extract.Apply(rules, response, db.GlobalScope)
+	for i := range results {
+		if !results[i].OK {
+			continue
+		}
+		if err := db.SetVariable(h.database, results[i].Scope, results[i].Variable, results[i].Value); err != nil {
+			results[i].OK = false
+			results[i].Error = fmt.Sprintf("failed to store variable: %v", err)
+		}
+	}
+
+	return results
+}
+
+// evaluateAssertions checks a saved request's assertions against the response.
+// ok is false when the saved request has no assertions.
+func (h *Handler) evaluateAssertions(savedRequestID, endpointID int64, response client.Response) ([]assertions.Result, bool) {
+	saved, err := db.GetSavedRequest(h.database, savedRequestID)
+	if err != nil {
+		return nil, false
+	}
+
+	list, err := assertions.ParseAssertions(saved.AssertionsJSON)
+	if err != nil {
+		return []assertions.Result{{Passed: false, Message: err.Error()}}, true
+	}
+	if len(list) == 0 {
+		return nil, false
+	}
+
+	if endpointID == 0 {
+		endpointID = saved.EndpointID
+	}
+	return assertions.Evaluate(list, response, h.endpointSpec(endpointID)), true
+}
+
+// endpointSpec decodes the stored OpenAPI operation for an endpoint, if any
+func (h *Handler) endpointSpec(endpointID int64) *discovery.APIEndpoint {
+	ep, err := db.GetEndpoint(h.database, endpointID)
+	if err != nil {
+		return nil
+	}
+	var spec discovery.APIEndpoint
+	if err := json.Unmarshal([]byte(ep.SpecJSON), &spec); err != nil {
+		return nil
+	}
+	return &spec
+}
+
+// timingResult converts a phase breakdown to fractional milliseconds for the frontend
+func timingResult(t client.Timing) map[string]interface{} {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	return map[string]interface{}{
+		"dnsLookup":        ms(t.DNSLookup),
+		"tcpConnect":       ms(t.TCPConnect),
+		"tlsHandshake":     ms(t.TLSHandshake),
+		"requestWrite":     ms(t.RequestWrite),
+		"timeToFirstByte":  ms(t.TimeToFirstByte),
+		"contentDownload":  ms(t.ContentDownload),
+		"connectionReused": t.ConnReused,
+	}
+}
+
 // handleGetRequestHistory retrieves request history
 func (h *Handler) handleGetRequestHistory(data json.RawMessage) IPCResponse {
 	var input struct {
@@ -573,12 +1034,8 @@ func (h *Handler) handleScanDirectory(data json.RawMessage) IPCResponse {
 	for _, entry := range entries {
 		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			subPath := filepath.Join(path, entry.Name())
-			// Check if it has a services directory (valid TW repo)
-			servicesPath := filepath.Join(subPath, "services")
-			hasServices := false
-			if info, err := os.Stat(servicesPath); err == nil && info.IsDir() {
-				hasServices = true
-			}
+			// Check if it has services under its scan layout (valid TW repo)
+			hasServices := scanner.HasServices(subPath)
 			subdirs = append(subdirs, map[string]interface{}{
 				"name":        entry.Name(),
 				"path":        subPath,
@@ -654,6 +1111,12 @@ func (h *Handler) handleRefreshRepository(data json.RawMessage) IPCResponse {
 		}
 	}
 
+	return h.refreshRepositoryByID(input.ID, true)
+}
+
+// refreshRepositoryByID rescans a stored repository and describes the result.
+// A full rescan re-parses every service even if its files didn't change.
+func (h *Handler) refreshRepositoryByID(id int64, incremental bool) IPCResponse {
 	// Get the repository from database
 	repos, err := db.GetRepositories(h.database)
 	if err != nil {
@@ -665,7 +1128,7 @@ func (h *Handler) handleRefreshRepository(data json.RawMessage) IPCResponse {
 
 	var repo *db.Repository
 	for i := range repos {
-		if repos[i].ID == input.ID {
+		if repos[i].ID == id {
 			repo = &repos[i]
 			break
 		}
@@ -674,118 +1137,27 @@ func (h *Handler) handleRefreshRepository(data json.RawMessage) IPCResponse {
 	if repo == nil {
 		return IPCResponse{
 			Success: false,
-			Error:   fmt.Sprintf("repository not found: %d", input.ID),
+			Error:   fmt.Sprintf("repository not found: %d", id),
 		}
 	}
 
-	// Re-scan the repository
-	scanResult := scanner.ScanRepository(repo.Path)
-	if len(scanResult.Errors) > 0 && len(scanResult.Services) == 0 {
-		return IPCResponse{
-			Success: false,
-			Error:   fmt.Sprintf("scan failed: %s", scanResult.Errors[0]),
-		}
-	}
-
-	// Get existing services for this repo to track what to remove
-	existingServices, err := db.GetServicesByRepo(h.database, input.ID)
+	diff, warnings, err := h.refreshRepository(*repo, incremental)
 	if err != nil {
 		return IPCResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to get existing services: %v", err),
-		}
-	}
-
-	// Track which services still exist after scan
-	scannedServiceIDs := make(map[string]bool)
-	for _, svc := range scanResult.Services {
-		scannedServiceIDs[svc.ServiceID] = true
-	}
-
-	// Remove services that no longer exist in the repository
-	for _, existingSvc := range existingServices {
-		if !scannedServiceIDs[existingSvc.ServiceID] {
-			_, _ = h.database.Exec("DELETE FROM services WHERE id = ?", existingSvc.ID)
-		}
-	}
-
-	// Upsert discovered services to database (preserves IDs via unique constraint)
-	servicesAdded := 0
-	endpointsAdded := 0
-	for _, svc := range scanResult.Services {
-		// Use INSERT OR REPLACE to preserve IDs when service already exists
-		result, err := h.database.Exec(`
-			INSERT INTO services (repo_id, service_id, name, port, config_json)
-			VALUES (?, ?, ?, ?, ?)
-			ON CONFLICT(repo_id, service_id) DO UPDATE SET
-				name = excluded.name,
-				port = excluded.port,
-				config_json = excluded.config_json
-		`, input.ID, svc.ServiceID, svc.Name, svc.Port, "{}")
-		if err != nil {
-			continue // Skip services that fail to add
-		}
-
-		// Get the service ID (either newly inserted or existing)
-		serviceID, err := result.LastInsertId()
-		if err != nil {
-			// If LastInsertId fails (happens on UPDATE), find the service by unique key
-			rows, err := h.database.Query("SELECT id FROM services WHERE repo_id = ? AND service_id = ?", input.ID, svc.ServiceID)
-			if err != nil {
-				continue
-			}
-			if rows.Next() {
-				rows.Scan(&serviceID)
-			}
-			rows.Close()
-		}
-		servicesAdded++
-
-		// Get existing endpoints for this service to track what to remove
-		existingEndpoints, err := db.GetEndpointsByService(h.database, serviceID)
-		if err != nil {
-			continue
-		}
-
-		// Track which endpoints still exist after scan
-		scannedEndpoints := make(map[string]bool)
-		for _, endpoint := range svc.Endpoints {
-			key := endpoint.Method + ":" + endpoint.Path
-			scannedEndpoints[key] = true
-		}
-
-		// Remove endpoints that no longer exist for this service
-		for _, existingEp := range existingEndpoints {
-			key := existingEp.Method + ":" + existingEp.Path
-			if !scannedEndpoints[key] {
-				_, _ = h.database.Exec("DELETE FROM endpoints WHERE id = ?", existingEp.ID)
-			}
-		}
-
-		// Upsert endpoints for this service (preserves IDs via unique constraint)
-		for _, endpoint := range svc.Endpoints {
-			_, err := h.database.Exec(`
-				INSERT INTO endpoints (service_id, method, path, operation_id, spec_json)
-				VALUES (?, ?, ?, ?, ?)
-				ON CONFLICT(service_id, method, path) DO UPDATE SET
-					operation_id = excluded.operation_id,
-					spec_json = excluded.spec_json
-			`, serviceID, endpoint.Method, endpoint.Path, endpoint.OperationID, "{}")
-			if err == nil {
-				endpointsAdded++
-			}
+			Error:   err.Error(),
 		}
 	}
 
 	return IPCResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"id":             repo.ID,
-			"name":           repo.Name,
-			"path":           repo.Path,
-			"servicesAdded":  servicesAdded,
-			"endpointsAdded": endpointsAdded,
-			"warnings":       scanResult.Errors,
+			"id":       repo.ID,
+			"name":     repo.Name,
+			"path":     repo.Path,
+			"layout":   repositoryLayout(*repo),
+			"diff":     diff,
+			"warnings": warnings,
 		},
 	}
 }
@@ -799,6 +1171,8 @@ func (h *Handler) handleSaveSavedRequest(data json.RawMessage) IPCResponse {
 		QueryParamsJSON string `json:"queryParamsJson"`
 		HeadersJSON     string `json:"headersJson"`
 		Body            string `json:"body"`
+		ExtractionsJSON string `json:"extractionsJson,omitempty"`
+		AssertionsJSON  string `json:"assertionsJson,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &input); err != nil {
@@ -815,6 +1189,23 @@ func (h *Handler) handleSaveSavedRequest(data json.RawMessage) IPCResponse {
 		QueryParamsJSON: input.QueryParamsJSON,
 		HeadersJSON:     input.HeadersJSON,
 		Body:            input.Body,
+		ExtractionsJSON: input.ExtractionsJSON,
+		AssertionsJSON:  input.AssertionsJSON,
+	}
+	h.applyExampleDefaults(data, &savedRequest)
+
+	if _, err := extract.ParseRules(savedRequest.ExtractionsJSON); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	if _, err := assertions.ParseAssertions(savedRequest.AssertionsJSON); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
 	}
 
 	id, err := db.AddSavedRequest(h.database, savedRequest)
@@ -831,10 +1222,12 @@ func (h *Handler) handleSaveSavedRequest(data json.RawMessage) IPCResponse {
 			"id":              id,
 			"endpointId":      input.EndpointID,
 			"name":            input.Name,
-			"pathParamsJson":  input.PathParamsJSON,
-			"queryParamsJson": input.QueryParamsJSON,
-			"headersJson":     input.HeadersJSON,
-			"body":            input.Body,
+			"pathParamsJson":  savedRequest.PathParamsJSON,
+			"queryParamsJson": savedRequest.QueryParamsJSON,
+			"headersJson":     savedRequest.HeadersJSON,
+			"body":            savedRequest.Body,
+			"extractionsJson": input.ExtractionsJSON,
+			"assertionsJson":  input.AssertionsJSON,
 		},
 	}
 }
@@ -871,6 +1264,8 @@ func (h *Handler) handleGetSavedRequests(data json.RawMessage) IPCResponse {
 			"queryParamsJson": req.QueryParamsJSON,
 			"headersJson":     req.HeadersJSON,
 			"body":            req.Body,
+			"extractionsJson": req.ExtractionsJSON,
+			"assertionsJson":  req.AssertionsJSON,
 			"createdAt":       req.CreatedAt,
 		}
 	}
@@ -901,6 +1296,8 @@ func (h *Handler) handleGetAllSavedRequests() IPCResponse {
 			"queryParamsJson": req.QueryParamsJSON,
 			"headersJson":     req.HeadersJSON,
 			"body":            req.Body,
+			"extractionsJson": req.ExtractionsJSON,
+			"assertionsJson":  req.AssertionsJSON,
 			"createdAt":       req.CreatedAt,
 		}
 	}
@@ -921,6 +1318,8 @@ func (h *Handler) handleUpdateSavedRequest(data json.RawMessage) IPCResponse {
 		QueryParamsJSON string `json:"queryParamsJson"`
 		HeadersJSON     string `json:"headersJson"`
 		Body            string `json:"body"`
+		ExtractionsJSON string `json:"extractionsJson,omitempty"`
+		AssertionsJSON  string `json:"assertionsJson,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &input); err != nil {
@@ -938,6 +1337,22 @@ func (h *Handler) handleUpdateSavedRequest(data json.RawMessage) IPCResponse {
 		QueryParamsJSON: input.QueryParamsJSON,
 		HeadersJSON:     input.HeadersJSON,
 		Body:            input.Body,
+		ExtractionsJSON: input.ExtractionsJSON,
+		AssertionsJSON:  input.AssertionsJSON,
+	}
+
+	if _, err := extract.ParseRules(savedRequest.ExtractionsJSON); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	if _, err := assertions.ParseAssertions(savedRequest.AssertionsJSON); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
 	}
 
 	err := db.UpdateSavedRequest(h.database, savedRequest)
@@ -958,6 +1373,8 @@ func (h *Handler) handleUpdateSavedRequest(data json.RawMessage) IPCResponse {
 			"queryParamsJson": input.QueryParamsJSON,
 			"headersJson":     input.HeadersJSON,
 			"body":            input.Body,
+			"extractionsJson": input.ExtractionsJSON,
+			"assertionsJson":  input.AssertionsJSON,
 		},
 	}
 }
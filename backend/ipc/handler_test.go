@@ -2,7 +2,12 @@ package ipc
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/triplewhale/postwhale/discovery"
 )
 
 func TestHandleRequest_InvalidAction(t *testing.T) {
@@ -454,3 +459,257 @@ func TestHandleRequest_DeleteSavedRequest(t *testing.T) {
 		t.Errorf("Expected saved request to be deleted, but found %d", count)
 	}
 }
+
+func TestHandleRequest_ImportSavedRequestsSkipsInvalid(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	saved := `version: 1
+service_id: orders
+saved_requests:
+  - name: List
+    endpoint: {method: GET, path: /orders}
+    assertions:
+      - type: statusEquals
+        expected: "200"
+  - name: No range
+    endpoint: {method: GET, path: /orders}
+    assertions:
+      - type: statusInRange
+  - name: Bad pattern
+    endpoint: {method: GET, path: /orders}
+    assertions:
+      - type: headerMatches
+        path: Content-Type
+        pattern: "("
+  - name: Unknown type
+    endpoint: {method: GET, path: /orders}
+    assertions:
+      - type: bodyContains
+`
+	if err := os.WriteFile(filepath.Join(repo, "services", "orders", "postwhale.saved.yml"), []byte(saved), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+
+	response := handler.HandleRequest(IPCRequest{Action: "importSavedRequests", Data: json.RawMessage(`{"serviceId": 1}`)})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	result := response.Data.(map[string]interface{})
+	if result["added"] != 1 || result["skipped"] != 3 || len(result["errors"].([]string)) != 3 {
+		t.Errorf("Expected 1 added and 3 skipped, got %+v", result)
+	}
+
+	var count int
+	handler.database.QueryRow("SELECT COUNT(*) FROM saved_requests").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected only the valid request to be stored, got %d", count)
+	}
+}
+
+func TestHandleRequest_CancelRequest(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// Register an in-flight request the same way handleExecuteRequest does
+	ctx, done := handler.beginRequest(float64(42))
+	defer done()
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "cancelRequest",
+		Data:   json.RawMessage(`{"requestId": 42}`),
+	})
+
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["cancelled"] != true {
+		t.Errorf("Expected cancelled=true, got %v", dataMap["cancelled"])
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected request context to be cancelled")
+	}
+}
+
+func TestHandleRequest_CancelUnknownRequest(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "cancelRequest",
+		Data:   json.RawMessage(`{"requestId": "missing"}`),
+	})
+
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["cancelled"] != false {
+		t.Errorf("Expected cancelled=false for unknown request, got %v", dataMap["cancelled"])
+	}
+}
+
+func TestHandleRequest_CancelBeforeRequestBegins(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// Requests are dispatched concurrently, so the cancel can be handled first
+	handler.HandleRequest(IPCRequest{
+		Action: "cancelRequest",
+		Data:   json.RawMessage(`{"requestId": "early"}`),
+	})
+
+	ctx, done := handler.beginRequest("early")
+	defer done()
+	if ctx.Err() == nil {
+		t.Error("Expected a request cancelled before it began to start cancelled")
+	}
+
+	// The recorded cancel is used up
+	ctx, done = handler.beginRequest("early")
+	defer done()
+	if ctx.Err() != nil {
+		t.Error("Expected a reused requestId not to be cancelled again")
+	}
+}
+
+func TestHandleRequest_StaleCancelIsIgnored(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// A cancel for a request that already finished is recorded like an early
+	// one, but must not cancel a much later request reusing the id
+	handler.HandleRequest(IPCRequest{
+		Action: "cancelRequest",
+		Data:   json.RawMessage(`{"requestId": "reused"}`),
+	})
+	handler.inflightMu.Lock()
+	handler.cancelled["reused"] = time.Now().Add(-2 * earlyCancelTTL)
+	handler.inflightMu.Unlock()
+
+	ctx, done := handler.beginRequest("reused")
+	defer done()
+	if ctx.Err() != nil {
+		t.Error("Expected a cancel older than earlyCancelTTL to be ignored")
+	}
+	handler.inflightMu.Lock()
+	left := len(handler.cancelled)
+	handler.inflightMu.Unlock()
+	if left != 0 {
+		t.Errorf("Expected the stale cancel to be dropped, %d left", left)
+	}
+}
+
+func TestHandleRequest_GetEndpointSpec(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	repo := t.TempDir()
+	svcDir := filepath.Join(repo, "services", "orders")
+	if err := os.MkdirAll(svcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	openapi := `openapi: 3.0.0
+info:
+  title: Orders
+  version: 2.1.0
+servers:
+  - url: https://orders.example.com
+security:
+  - bearer: []
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      summary: List orders
+      description: Lists orders for a shop
+      tags: [orders]
+      deprecated: true
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            default: 20
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+`
+	files := map[string]string{
+		"tw-config.json": `{"serviceId": "orders", "env": {"PORT": 8080}}`,
+		"openapi.yaml":   openapi,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(svcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+
+	response := handler.HandleRequest(IPCRequest{Action: "getEndpointSpec", Data: json.RawMessage(`{"endpointId": 1}`)})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	spec := dataMap["spec"].(discovery.APIEndpoint)
+	if spec.Summary != "List orders" || spec.Description != "Lists orders for a shop" || !spec.Deprecated || len(spec.Tags) != 1 {
+		t.Errorf("Expected operation docs, got %+v", spec)
+	}
+	if len(spec.Parameters) != 1 || spec.Parameters[0].Schema.Default != float64(20) {
+		t.Errorf("Expected query parameter with default, got %+v", spec.Parameters)
+	}
+	if spec.Responses["200"].Content["application/json"].Schema.Items == nil {
+		t.Errorf("Expected response schema, got %+v", spec.Responses)
+	}
+
+	service := dataMap["service"].(map[string]interface{})
+	if info := service["info"].(discovery.Info); info.Version != "2.1.0" {
+		t.Errorf("Expected service info, got %+v", info)
+	}
+	if servers := service["servers"].([]discovery.Server); len(servers) != 1 || servers[0].URL != "https://orders.example.com" {
+		t.Errorf("Expected servers, got %+v", servers)
+	}
+	schemes := service["securitySchemes"].(map[string]discovery.SecurityScheme)
+	if schemes["bearer"].Scheme != "bearer" || len(service["security"].([]discovery.SecurityRequirement)) != 1 {
+		t.Errorf("Expected security schemes, got %+v", service)
+	}
+
+	if spec.Source == nil || spec.Source.File != "openapi.yaml" {
+		t.Errorf("Expected spec source, got %+v", spec.Source)
+	}
+	endpoints := handler.HandleRequest(IPCRequest{Action: "getAllEndpoints"}).Data.([]interface{})
+	if source := endpoints[0].(map[string]interface{})["source"].(*discovery.SpecSource); source == nil || source.File != "openapi.yaml" {
+		t.Errorf("Expected endpoint source in listing, got %+v", source)
+	}
+
+	missing := handler.HandleRequest(IPCRequest{Action: "getEndpointSpec", Data: json.RawMessage(`{"endpointId": 99}`)})
+	if missing.Success {
+		t.Error("Expected error for unknown endpoint")
+	}
+}
@@ -0,0 +1,51 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/scanner"
+)
+
+// repositoryLayout returns the scan layout stored for a repository; an
+// unreadable value is treated as no overrides
+func repositoryLayout(repo db.Repository) scanner.Layout {
+	var layout scanner.Layout
+	if repo.LayoutJSON != "" {
+		json.Unmarshal([]byte(repo.LayoutJSON), &layout)
+	}
+	return layout
+}
+
+// handleUpdateRepositoryLayout stores a repository's scan layout and rescans
+// it in full, since the layout decides which files make up each service
+func (h *Handler) handleUpdateRepositoryLayout(data json.RawMessage) IPCResponse {
+	var input struct {
+		ID     int64          `json:"id"`
+		Layout scanner.Layout `json:"layout"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if err := input.Layout.Validate(); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid layout: %v", err),
+		}
+	}
+
+	if err := db.UpdateRepositoryLayout(h.database, input.ID, specJSON(input.Layout)); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to update repository layout: %v", err),
+		}
+	}
+
+	return h.refreshRepositoryByID(input.ID, false)
+}
@@ -0,0 +1,71 @@
+package ipc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/portability"
+)
+
+func TestHandleRequest_RepositoryLayout(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// Services live under apps/ instead of services/
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	writeSyncService(t, repo, "billing", syncSpec)
+	if err := os.Rename(filepath.Join(repo, "services"), filepath.Join(repo, "apps")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default layout doesn't find them
+	data, _ := json.Marshal(map[string]interface{}{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); resp.Success {
+		t.Fatal("Expected the default layout to find no services directory")
+	}
+
+	data, _ = json.Marshal(map[string]interface{}{"path": repo, "layout": map[string]interface{}{"serviceRoots": []string{"apps/*"}}})
+	resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data})
+	if !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+	repoID := resp.Data.(map[string]interface{})["id"].(int64)
+
+	services, _ := db.GetServicesByRepo(handler.database, repoID)
+	if len(services) != 2 || services[0].Dir != "apps/billing" {
+		t.Fatalf("Expected 2 services under apps/, got %+v", services)
+	}
+
+	// Saved request files are read from the scanned directory
+	_, svcPath, err := portability.GetServicePath(handler.database, services[0].ID)
+	if err != nil || svcPath != filepath.Join(repo, "apps", "billing") {
+		t.Errorf("Expected the service path under apps/, got %q (%v)", svcPath, err)
+	}
+
+	// Changing the stored layout rescans in full
+	data, _ = json.Marshal(map[string]interface{}{"id": repoID, "layout": map[string]interface{}{"serviceRoots": []string{"apps/*"}, "exclude": []string{"billing"}}})
+	resp = handler.HandleRequest(IPCRequest{Action: "updateRepositoryLayout", Data: data})
+	if !resp.Success {
+		t.Fatalf("Failed to update layout: %s", resp.Error)
+	}
+	diff := resp.Data.(map[string]interface{})["diff"].(RepositoryDiff)
+	if len(diff.ServicesRemoved) != 1 || diff.ServicesRemoved[0] != "billing" {
+		t.Errorf("Expected billing removed, got %+v", diff)
+	}
+
+	repos := handler.HandleRequest(IPCRequest{Action: "getRepositories"}).Data.([]interface{})
+	layout, _ := json.Marshal(repos[0].(map[string]interface{})["layout"])
+	if string(layout) != `{"serviceRoots":["apps/*"],"exclude":["billing"]}` {
+		t.Errorf("Expected the stored layout, got %s", layout)
+	}
+
+	// Invalid layouts are rejected before anything is stored
+	data, _ = json.Marshal(map[string]interface{}{"id": repoID, "layout": map[string]interface{}{"serviceRoots": []string{"../*"}}})
+	if resp := handler.HandleRequest(IPCRequest{Action: "updateRepositoryLayout", Data: data}); resp.Success {
+		t.Error("Expected an invalid layout to be rejected")
+	}
+}
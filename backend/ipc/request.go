@@ -0,0 +1,243 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/environment"
+	"github.com/triplewhale/postwhale/templating"
+	"github.com/triplewhale/postwhale/validation"
+)
+
+// executeRequestInput is the payload shared by executeRequest and previewRequest
+type executeRequestInput struct {
+	ServiceID   string            `json:"serviceId"`
+	Port        int               `json:"port"`
+	Endpoint    string            `json:"endpoint"`
+	Method      string            `json:"method"`
+	Environment string            `json:"environment"`
+	Headers     map[string]string `json:"headers"`
+	Body        string            `json:"body"`
+	EndpointID  int64             `json:"endpointId,omitempty"`
+	AuthEnabled bool              `json:"authEnabled"`
+	// Optional deployment target from tw-config.json; defaults to the
+	// environment's internal endpoint (public when auth is enabled)
+	Deployment         string `json:"deployment,omitempty"`
+	DeploymentEndpoint string `json:"deploymentEndpoint,omitempty"`
+	Cluster            string `json:"cluster,omitempty"`
+	// Optional user-defined environment; takes precedence over environment and deployment
+	EnvironmentID int64 `json:"environmentId,omitempty"`
+	// Optional {param} values for the endpoint path and query params to append
+	PathParams  map[string]string       `json:"pathParams,omitempty"`
+	QueryParams []templating.QueryParam `json:"queryParams,omitempty"`
+	// Optional saved request whose variable scope applies
+	SavedRequestID int64 `json:"savedRequestId,omitempty"`
+	// Spec validation before sending: "off", "warn" (default) or "block"
+	Validation string `json:"validation,omitempty"`
+}
+
+// preparedRequest is a request with its target and {{variables}} resolved
+type preparedRequest struct {
+	config      client.RequestConfig
+	environment string // environment name recorded in history
+	target      *discovery.DeploymentEndpoint
+	resolved    templating.Request // path, params and headers after {{variable}} expansion
+}
+
+// prepareRequest resolves the target URL and expands {{variables}} in every part of the request.
+// On unresolved variables it returns the best-effort request along with a *templating.RequestError.
+func (h *Handler) prepareRequest(input executeRequestInput) (preparedRequest, error) {
+	prepared := preparedRequest{
+		config: client.RequestConfig{
+			ServiceID:   input.ServiceID,
+			Port:        input.Port,
+			Endpoint:    input.Endpoint,
+			Method:      input.Method,
+			Environment: client.Environment(input.Environment),
+			Headers:     input.Headers,
+			Body:        input.Body,
+			Timeout:     30 * time.Second,
+			AuthEnabled: input.AuthEnabled,
+		},
+		environment: input.Environment,
+	}
+
+	// Scopes from least to most specific: global, environment, service, saved request
+	scopes := []templating.Scope{}
+	globalVars, err := db.GetVariables(h.database, db.GlobalScope)
+	if err != nil {
+		return prepared, fmt.Errorf("failed to load global variables: %w", err)
+	}
+	scopes = append(scopes, templating.Scope{Name: "global", Vars: globalVars})
+
+	if input.EnvironmentID > 0 {
+		// User-defined environments resolve the URL through their base URL template
+		env, err := environment.Load(h.database, input.EnvironmentID)
+		if err != nil {
+			return prepared, err
+		}
+		baseURL, err := env.BaseURL(input.ServiceID, input.Port)
+		if err != nil {
+			return prepared, err
+		}
+		prepared.config.BaseURL = baseURL
+		prepared.environment = env.Name
+		scopes = append(scopes, templating.Scope{Name: "environment", Vars: env.VariablesFor(input.ServiceID, input.Port)})
+	} else {
+		// Prefer the URL declared in the service's tw-config.json deployments
+		selector := discovery.EndpointSelector{
+			Deployment: input.Deployment,
+			Endpoint:   input.DeploymentEndpoint,
+			Cluster:    input.Cluster,
+		}
+		if target, ok := h.resolveDeployment(input.EndpointID, input.ServiceID, input.Environment, selector, input.AuthEnabled); ok {
+			prepared.config.BaseURL = target.URL
+			prepared.target = &target
+		}
+	}
+
+	serviceVars, err := db.GetVariables(h.database, db.ServiceScope(input.ServiceID))
+	if err != nil {
+		return prepared, fmt.Errorf("failed to load service variables: %w", err)
+	}
+	scopes = append(scopes, templating.Scope{Name: "service", Vars: serviceVars})
+
+	if input.SavedRequestID > 0 {
+		requestVars, err := db.GetVariables(h.database, db.SavedRequestScope(input.SavedRequestID))
+		if err != nil {
+			return prepared, fmt.Errorf("failed to load saved request variables: %w", err)
+		}
+		scopes = append(scopes, templating.Scope{Name: "savedRequest", Vars: requestVars})
+	}
+
+	resolved, err := templating.NewResolver(scopes...).ResolveRequest(templating.Request{
+		Path:        input.Endpoint,
+		PathParams:  input.PathParams,
+		QueryParams: input.QueryParams,
+		Headers:     input.Headers,
+		Body:        input.Body,
+	})
+	prepared.resolved = resolved
+	prepared.config.Endpoint = resolved.URL()
+	prepared.config.Headers = resolved.Headers
+	prepared.config.Body = resolved.Body
+
+	return prepared, err
+}
+
+// handlePreviewRequest resolves a request without sending it
+// Unresolved variables are reported rather than treated as a failure
+func (h *Handler) handlePreviewRequest(data json.RawMessage) IPCResponse {
+	var input executeRequestInput
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	prepared, err := h.prepareRequest(input)
+	unresolved := []templating.Unresolved{}
+	if err != nil {
+		requestErr, ok := err.(*templating.RequestError)
+		if !ok {
+			return IPCResponse{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		unresolved = requestErr.Unresolved
+	}
+
+	result := map[string]interface{}{
+		"method":      prepared.config.Method,
+		"url":         client.BuildURL(prepared.config),
+		"headers":     prepared.config.Headers,
+		"body":        prepared.config.Body,
+		"environment": prepared.environment,
+		"unresolved":  unresolved,
+	}
+	if prepared.target != nil {
+		result["deployment"] = prepared.target
+	}
+	if issues := h.validateRequest(input.EndpointID, prepared); issues != nil {
+		result["requestIssues"] = issues
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// validateRequest checks a prepared request against its endpoint's stored spec.
+// It returns nil when there is no endpoint to check against.
+func (h *Handler) validateRequest(endpointID int64, prepared preparedRequest) []validation.Issue {
+	if endpointID <= 0 {
+		return nil
+	}
+	spec := h.endpointSpec(endpointID)
+	if spec == nil {
+		return nil
+	}
+
+	// The app sends query parameters as part of the endpoint path
+	query := url.Values{}
+	if _, rawQuery, ok := strings.Cut(prepared.resolved.Path, "?"); ok {
+		rawQuery, _, _ = strings.Cut(rawQuery, "#")
+		if parsed, err := url.ParseQuery(rawQuery); err == nil {
+			query = parsed
+		}
+	}
+	for _, q := range prepared.resolved.QueryParams {
+		if q.Enabled && q.Key != "" {
+			query.Add(q.Key, q.Value)
+		}
+	}
+
+	return validation.ValidateRequest(spec, validation.OutgoingRequest{
+		Path:       prepared.resolved.Path,
+		PathParams: prepared.resolved.PathParams,
+		Query:      query,
+		Headers:    prepared.config.Headers,
+		Body:       prepared.config.Body,
+	})
+}
+
+// validateResponse checks a response against its endpoint's stored spec.
+// It returns nil when there is no endpoint to check against.
+func (h *Handler) validateResponse(endpointID int64, response client.Response) []validation.Issue {
+	if endpointID <= 0 {
+		return nil
+	}
+	spec := h.endpointSpec(endpointID)
+	if spec == nil {
+		return nil
+	}
+
+	return validation.ValidateResponse(spec, validation.IncomingResponse{
+		StatusCode: response.StatusCode,
+		Headers:    response.Headers,
+		Body:       response.Body,
+	})
+}
+
+// blockedResponse reports a request that wasn't sent because it violates its spec
+func blockedResponse(issues []validation.Issue) IPCResponse {
+	first := issues[0]
+	return IPCResponse{
+		Success: false,
+		Error:   fmt.Sprintf("request blocked by spec validation: %s %s: %s (%d issue(s))", first.In, first.Pointer, first.Message, len(issues)),
+		Data: map[string]interface{}{
+			"blocked":       true,
+			"requestIssues": issues,
+		},
+	}
+}
@@ -0,0 +1,390 @@
+package ipc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/extract"
+	"github.com/triplewhale/postwhale/validation"
+)
+
+func TestHandleRequest_PreviewRequest(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	handler.HandleRequest(IPCRequest{
+		Action: "setVariable",
+		Data:   json.RawMessage(`{"scope": "global", "name": "orderId", "value": "123"}`),
+	})
+	handler.HandleRequest(IPCRequest{
+		Action: "setVariable",
+		Data:   json.RawMessage(`{"scope": "service:fusion", "name": "orderId", "value": "456"}`),
+	})
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "previewRequest",
+		Data: json.RawMessage(`{
+			"serviceId": "fusion",
+			"endpoint": "/orders/{orderId}",
+			"method": "GET",
+			"environment": "LOCAL",
+			"pathParams": {"orderId": "{{orderId}}"},
+			"headers": {"x-tw-shop-id": "{{shopId}}"}
+		}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["url"] != "http://localhost/fusion/orders/456" {
+		t.Errorf("Expected service scope to override global, got url %v", dataMap["url"])
+	}
+
+	unresolved, _ := json.Marshal(dataMap["unresolved"])
+	if !strings.Contains(string(unresolved), "shopId") {
+		t.Errorf("Expected shopId to be reported as unresolved, got %s", unresolved)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestUnresolvedVariable(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/orders/{{orderId}}", "method": "GET", "environment": "LOCAL"}`),
+	})
+
+	if response.Success {
+		t.Fatal("Expected failure for unresolved variable")
+	}
+	if !strings.Contains(response.Error, "orderId (in path)") {
+		t.Errorf("Expected error to name the variable and location, got %q", response.Error)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestExtractions(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"order": {"id": "ord_1"}}`))
+	}))
+	defer server.Close()
+
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "fusion", "Fusion", 8080, "{}")
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "POST", "/orders", "createOrder", "{}")
+
+	saveData, _ := json.Marshal(map[string]interface{}{
+		"endpointId":      1,
+		"name":            "Create order",
+		"extractionsJson": `[{"variable": "orderId", "source": "body", "path": "$.order.id"}, {"variable": "createdStatus", "source": "status", "scope": "service:fusion"}]`,
+	})
+	saveResponse := handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: saveData})
+	if !saveResponse.Success {
+		t.Fatalf("Failed to save request: %s", saveResponse.Error)
+	}
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/orders", "method": "POST", "environmentId": 1, "endpointId": 1, "savedRequestId": 1}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	results, ok := dataMap["extractions"].([]extract.Result)
+	if !ok || len(results) != 2 || !results[0].OK || !results[1].OK {
+		t.Fatalf("Expected two successful extractions, got %v", dataMap["extractions"])
+	}
+
+	globalVars := handler.HandleRequest(IPCRequest{Action: "getVariables", Data: json.RawMessage(`{"scope": "global"}`)})
+	if globalVars.Data.(map[string]string)["orderId"] != "ord_1" {
+		t.Errorf("Expected orderId to be stored globally, got %v", globalVars.Data)
+	}
+	serviceVars := handler.HandleRequest(IPCRequest{Action: "getVariables", Data: json.RawMessage(`{"scope": "service:fusion"}`)})
+	if serviceVars.Data.(map[string]string)["createdStatus"] != "201" {
+		t.Errorf("Expected createdStatus in service scope, got %v", serviceVars.Data)
+	}
+
+	// A follow-up request picks up the extracted value
+	preview := handler.HandleRequest(IPCRequest{
+		Action: "previewRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/orders/{{orderId}}", "method": "GET", "environmentId": 1}`),
+	})
+	if url := preview.Data.(map[string]interface{})["url"]; url != server.URL+"/orders/ord_1" {
+		t.Errorf("Expected chained orderId in URL, got %v", url)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestAssertions(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "ord_1", "total": "12"}`))
+	}))
+	defer server.Close()
+
+	spec := `{"method": "GET", "path": "/orders/1", "responses": {"200": {"content": {"application/json": {"schema": {"type": "object", "properties": {"total": {"type": "number"}}}}}}}}`
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "fusion", "Fusion", 8080, "{}")
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/orders/1", "getOrder", spec)
+
+	saveData, _ := json.Marshal(map[string]interface{}{
+		"endpointId":     1,
+		"name":           "Get order",
+		"assertionsJson": `[{"type": "statusEquals", "expected": "200"}, {"type": "jsonPathEquals", "path": "$.id", "expected": "ord_1"}, {"type": "matchesSchema"}]`,
+	})
+	if resp := handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: saveData}); !resp.Success {
+		t.Fatalf("Failed to save request: %s", resp.Error)
+	}
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/orders/1", "method": "GET", "environmentId": 1, "endpointId": 1, "savedRequestId": 1}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	results, ok := dataMap["assertions"].([]assertions.Result)
+	if !ok || len(results) != 3 {
+		t.Fatalf("Expected three assertion results, got %v", dataMap["assertions"])
+	}
+	if !results[0].Passed || !results[1].Passed {
+		t.Errorf("Expected status and jsonpath assertions to pass, got %+v", results[:2])
+	}
+	if results[2].Passed || len(results[2].Issues) != 1 || results[2].Issues[0].Pointer != "/total" {
+		t.Errorf("Expected schema violation at /total, got %+v", results[2])
+	}
+	if dataMap["assertionsPassed"] != false {
+		t.Errorf("Expected assertionsPassed to be false, got %v", dataMap["assertionsPassed"])
+	}
+
+	// Results are kept with the history entry
+	history, _ := db.GetRequestHistory(handler.database, 1, 10)
+	if len(history) != 1 || !strings.Contains(history[0].Response, `"assertionsPassed":false`) {
+		t.Errorf("Expected assertion results in history, got %v", history)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestAssertionsOnTransportError(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// A server that is gone by the time the request is sent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "fusion", "Fusion", 8080, "{}")
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/orders/1", "getOrder", "{}")
+
+	saveData, _ := json.Marshal(map[string]interface{}{
+		"endpointId":     1,
+		"name":           "Get order",
+		"assertionsJson": `[{"type": "responseTimeBelow", "ms": 5000}]`,
+	})
+	if resp := handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: saveData}); !resp.Success {
+		t.Fatalf("Failed to save request: %s", resp.Error)
+	}
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "executeRequest",
+		Data:   json.RawMessage(`{"serviceId": "fusion", "endpoint": "/orders/1", "method": "GET", "environmentId": 1, "endpointId": 1, "savedRequestId": 1}`),
+	})
+	dataMap := response.Data.(map[string]interface{})
+	if dataMap["error"] == nil {
+		t.Fatalf("Expected a connection error, got %v", dataMap)
+	}
+	results, _ := dataMap["assertions"].([]assertions.Result)
+	if len(results) != 1 || results[0].Passed || !strings.HasPrefix(results[0].Message, "request failed: ") {
+		t.Errorf("Expected the assertion to fail with the request error, got %+v", results)
+	}
+	if dataMap["assertionsPassed"] != false {
+		t.Errorf("Expected assertionsPassed to be false, got %v", dataMap["assertionsPassed"])
+	}
+}
+
+func TestHandleRequest_SaveSavedRequestInvalidAssertions(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "saveSavedRequest",
+		Data:   json.RawMessage(`{"endpointId": 1, "name": "Bad", "assertionsJson": "[{\"type\": \"nope\"}]"}`),
+	})
+	if response.Success {
+		t.Fatal("Expected invalid assertions to be rejected")
+	}
+}
+
+func TestHandleRequest_ExecuteRequestValidation(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+	seedSpecEndpoint(t, handler)
+
+	sent := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	request := func(validation string) IPCResponse {
+		data, _ := json.Marshal(map[string]interface{}{
+			"serviceId":     "orders",
+			"endpoint":      "/shops/{shopId}/orders",
+			"method":        "POST",
+			"environmentId": 1,
+			"endpointId":    1,
+			"pathParams":    map[string]string{"shopId": "shop_1"},
+			"headers":       map[string]string{"Content-Type": "application/json"},
+			"body":          `{"qty": 0}`,
+			"validation":    validation,
+		})
+		return handler.HandleRequest(IPCRequest{Action: "executeRequest", Data: data})
+	}
+
+	// Warn: sent, with issues attached
+	response := request("")
+	if !response.Success || sent != 1 {
+		t.Fatalf("Expected request to be sent in warn mode, got %+v", response)
+	}
+	issues := response.Data.(map[string]interface{})["requestIssues"].([]validation.Issue)
+	if len(issues) != 1 || issues[0] != (validation.Issue{In: "body", Pointer: "/qty", Message: "expected at least 1, got 0"}) {
+		t.Errorf("Expected qty issue, got %+v", issues)
+	}
+
+	// Block: not sent
+	response = request("block")
+	if response.Success || sent != 1 || !strings.Contains(response.Error, "body /qty: expected at least 1, got 0") {
+		t.Errorf("Expected blocked request, got %+v (sent %d)", response, sent)
+	}
+
+	// Off: sent without checks
+	response = request("off")
+	if _, ok := response.Data.(map[string]interface{})["requestIssues"]; !response.Success || ok || sent != 2 {
+		t.Errorf("Expected unchecked request, got %+v", response)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestResponseValidation(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	spec := `{"method": "GET", "path": "/orders/1", "responses": {"200": {"content": {"application/json": {"schema": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}}}}}}`
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "orders", "Orders", 8080, "{}")
+	if _, err := handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/orders/1", "getOrder", spec); err != nil {
+		t.Fatal(err)
+	}
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id": 5}`))
+	}))
+	defer server.Close()
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"serviceId":     "orders",
+		"endpoint":      "/orders/1",
+		"method":        "GET",
+		"environmentId": 1,
+		"endpointId":    1,
+	})
+	response := handler.HandleRequest(IPCRequest{Action: "executeRequest", Data: data})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	issues := response.Data.(map[string]interface{})["responseIssues"].([]validation.Issue)
+	if len(issues) != 1 || issues[0] != (validation.Issue{In: "body", Pointer: "/id", Message: "expected string, got integer"}) {
+		t.Errorf("Expected id type issue, got %+v", issues)
+	}
+
+	// Issues are kept with the response in history
+	history, err := db.GetRequestHistory(handler.database, 1, 10)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d (%v)", len(history), err)
+	}
+	if !strings.Contains(history[0].Response, `"responseIssues":[{"in":"body","pointer":"/id"`) {
+		t.Errorf("Expected response issues in history, got %s", history[0].Response)
+	}
+
+	status = http.StatusTeapot
+	response = handler.HandleRequest(IPCRequest{Action: "executeRequest", Data: data})
+	issues = response.Data.(map[string]interface{})["responseIssues"].([]validation.Issue)
+	if len(issues) != 1 || issues[0].In != "status" {
+		t.Errorf("Expected undocumented status issue, got %+v", issues)
+	}
+}
+
+func TestHandleRequest_ExecuteRequestValidationQueryInPath(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	spec := `{"method": "GET", "path": "/orders", "parameters": [{"name": "shopId", "in": "query", "required": true, "schema": {"type": "string"}}]}`
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "orders", "Orders", 8080, "{}")
+	if _, err := handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/orders", "listOrders", spec); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer server.Close()
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": server.URL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+
+	request := func(endpoint string) IPCResponse {
+		data, _ := json.Marshal(map[string]interface{}{
+			"serviceId":     "orders",
+			"endpoint":      endpoint,
+			"method":        "GET",
+			"environmentId": 1,
+			"endpointId":    1,
+			"validation":    "block",
+		})
+		return handler.HandleRequest(IPCRequest{Action: "executeRequest", Data: data})
+	}
+
+	// The UI appends query parameters to the endpoint path
+	if response := request("/orders?shopId=abc"); !response.Success || sent != 1 {
+		t.Errorf("Expected the query in the path to satisfy the spec, got %+v", response)
+	}
+	if response := request("/orders"); response.Success || !strings.Contains(response.Error, "shopId") || sent != 1 {
+		t.Errorf("Expected the missing query parameter to block, got %+v", response)
+	}
+}
@@ -0,0 +1,422 @@
+package ipc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/environment"
+	"github.com/triplewhale/postwhale/extract"
+	"github.com/triplewhale/postwhale/report"
+	"github.com/triplewhale/postwhale/runner"
+	"github.com/triplewhale/postwhale/templating"
+)
+
+// runCollectionInput selects the saved requests to run and how to run them.
+// Exactly one of RepoID, ServiceID or SavedRequestIDs selects the collection.
+type runCollectionInput struct {
+	RepoID          int64   `json:"repoId,omitempty"`
+	ServiceID       int64   `json:"serviceId,omitempty"`
+	SavedRequestIDs []int64 `json:"savedRequestIds,omitempty"`
+	// Target environment, as for executeRequest
+	Environment        string `json:"environment"`
+	EnvironmentID      int64  `json:"environmentId,omitempty"`
+	Deployment         string `json:"deployment,omitempty"`
+	DeploymentEndpoint string `json:"deploymentEndpoint,omitempty"`
+	Cluster            string `json:"cluster,omitempty"`
+	AuthEnabled        bool   `json:"authEnabled"`
+	// Execution options
+	StopOnFailure bool `json:"stopOnFailure"`
+	DelayMs       int  `json:"delayMs"`
+	Concurrency   int  `json:"concurrency"`
+	// Optional report: junit, json or tap. Written to ReportPath if set,
+	// otherwise returned inline as report.
+	Report     string `json:"report,omitempty"`
+	ReportPath string `json:"reportPath,omitempty"`
+}
+
+// handleRunCollection runs a collection of saved requests in order, streaming a
+// progress message per request, and stores the summary in the runs table
+func (h *Handler) handleRunCollection(requestID interface{}, data json.RawMessage) IPCResponse {
+	var input runCollectionInput
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if input.Report != "" && report.Extension(input.Report) == "" {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unknown report format: %q", input.Report),
+		}
+	}
+
+	target, items, err := h.collectionItems(input)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	// User-defined environments are recorded by name
+	environmentName := input.Environment
+	properties := map[string]string{}
+	if input.EnvironmentID > 0 {
+		env, err := environment.Load(h.database, input.EnvironmentID)
+		if err != nil {
+			return IPCResponse{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		environmentName = env.Name
+		properties["baseUrlTemplate"] = env.BaseURLTemplate
+	}
+	for key, value := range map[string]string{"deployment": input.Deployment, "deploymentEndpoint": input.DeploymentEndpoint, "cluster": input.Cluster} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+
+	r := &runner.Runner{
+		Prepare: func(item runner.Item) (client.RequestConfig, error) {
+			prepared, err := h.prepareRequest(executeRequestInput{
+				ServiceID:          item.ServiceID,
+				Port:               item.Port,
+				Endpoint:           item.Path,
+				Method:             item.Method,
+				Environment:        input.Environment,
+				Headers:            item.Headers,
+				Body:               item.Body,
+				EndpointID:         item.EndpointID,
+				AuthEnabled:        input.AuthEnabled,
+				Deployment:         input.Deployment,
+				DeploymentEndpoint: input.DeploymentEndpoint,
+				Cluster:            input.Cluster,
+				EnvironmentID:      input.EnvironmentID,
+				PathParams:         item.PathParams,
+				QueryParams:        item.QueryParams,
+				SavedRequestID:     item.SavedRequestID,
+			})
+			return prepared.config, err
+		},
+		Extract: func(item runner.Item, response client.Response) []extract.Result {
+			return h.applyExtractions(item.SavedRequestID, response)
+		},
+		Progress: func(event runner.Event) {
+			h.sendProgress(requestID, event)
+		},
+	}
+
+	ctx, done := h.beginRequest(requestID)
+	defer done()
+
+	summary := r.Run(ctx, items, runner.Options{
+		StopOnFailure: input.StopOnFailure,
+		Delay:         time.Duration(input.DelayMs) * time.Millisecond,
+		Concurrency:   input.Concurrency,
+	})
+
+	status := db.RunPassed
+	switch {
+	case summary.Cancelled:
+		status = db.RunCancelled
+	case !summary.Success():
+		status = db.RunFailed
+	}
+
+	summaryJSON, _ := json.Marshal(summary)
+	runID, err := db.AddRun(h.database, db.Run{
+		Target:      target,
+		Environment: environmentName,
+		Status:      status,
+		Total:       summary.Total,
+		Passed:      summary.Passed,
+		Failed:      summary.Failed,
+		Skipped:     summary.Skipped,
+		DurationMs:  summary.DurationMs,
+		SummaryJSON: string(summaryJSON),
+		StartedAt:   summary.StartedAt.UTC().Format(time.RFC3339),
+		FinishedAt:  summary.FinishedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to save run: %v", err),
+		}
+	}
+
+	result := map[string]interface{}{
+		"runId":       runID,
+		"target":      target,
+		"environment": environmentName,
+		"status":      status,
+		"summary":     summary,
+	}
+
+	if input.Report != "" {
+		var buf bytes.Buffer
+		meta := report.Metadata{Name: target, Target: target, Environment: environmentName, Properties: properties}
+		if err := report.Write(&buf, input.Report, summary, meta); err != nil {
+			return IPCResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to write report: %v", err),
+			}
+		}
+		if input.ReportPath != "" {
+			if err := os.WriteFile(input.ReportPath, buf.Bytes(), 0644); err != nil {
+				return IPCResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to write report: %v", err),
+				}
+			}
+			result["reportPath"] = input.ReportPath
+		} else {
+			result["report"] = buf.String()
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// collectionItems loads the saved requests selected by the input, in run order:
+// services by name, endpoints by path and method, saved requests oldest first.
+// Explicit saved request IDs run in the order given.
+func (h *Handler) collectionItems(input runCollectionInput) (string, []runner.Item, error) {
+	var target string
+	var saved []db.SavedRequest
+
+	switch {
+	case len(input.SavedRequestIDs) > 0:
+		target = "savedRequests"
+		for _, id := range input.SavedRequestIDs {
+			req, err := db.GetSavedRequest(h.database, id)
+			if err != nil {
+				return "", nil, fmt.Errorf("saved request %d not found: %v", id, err)
+			}
+			saved = append(saved, req)
+		}
+	case input.ServiceID > 0:
+		target = fmt.Sprintf("service:%d", input.ServiceID)
+		reqs, err := h.serviceSavedRequests(input.ServiceID)
+		if err != nil {
+			return "", nil, err
+		}
+		saved = reqs
+	case input.RepoID > 0:
+		target = fmt.Sprintf("repo:%d", input.RepoID)
+		services, err := db.GetServicesByRepo(h.database, input.RepoID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get services: %v", err)
+		}
+		for _, svc := range services {
+			reqs, err := h.serviceSavedRequests(svc.ID)
+			if err != nil {
+				return "", nil, err
+			}
+			saved = append(saved, reqs...)
+		}
+	default:
+		return "", nil, fmt.Errorf("repoId, serviceId or savedRequestIds is required")
+	}
+
+	items := make([]runner.Item, 0, len(saved))
+	for _, req := range saved {
+		item, err := h.savedRequestItem(req)
+		if err != nil {
+			return "", nil, err
+		}
+		items = append(items, item)
+	}
+
+	return target, items, nil
+}
+
+// serviceSavedRequests lists a service's saved requests by endpoint, oldest first per endpoint
+func (h *Handler) serviceSavedRequests(serviceID int64) ([]db.SavedRequest, error) {
+	endpoints, err := db.GetEndpointsByService(h.database, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get endpoints: %v", err)
+	}
+
+	saved := []db.SavedRequest{}
+	for _, ep := range endpoints {
+		reqs, err := db.GetSavedRequestsByEndpoint(h.database, ep.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get saved requests: %v", err)
+		}
+		sort.Slice(reqs, func(i, j int) bool { return reqs[i].ID < reqs[j].ID })
+		saved = append(saved, reqs...)
+	}
+	return saved, nil
+}
+
+// savedRequestItem converts a stored saved request into a runnable item
+func (h *Handler) savedRequestItem(req db.SavedRequest) (runner.Item, error) {
+	ep, err := db.GetEndpoint(h.database, req.EndpointID)
+	if err != nil {
+		return runner.Item{}, fmt.Errorf("endpoint for saved request %q not found: %v", req.Name, err)
+	}
+	svc, err := db.GetServiceByEndpoint(h.database, req.EndpointID)
+	if err != nil {
+		return runner.Item{}, fmt.Errorf("service for saved request %q not found: %v", req.Name, err)
+	}
+
+	rules, err := extract.ParseRules(req.ExtractionsJSON)
+	if err != nil {
+		return runner.Item{}, fmt.Errorf("saved request %q: %v", req.Name, err)
+	}
+	checks, err := assertions.ParseAssertions(req.AssertionsJSON)
+	if err != nil {
+		return runner.Item{}, fmt.Errorf("saved request %q: %v", req.Name, err)
+	}
+
+	return runner.Item{
+		SavedRequestID: req.ID,
+		EndpointID:     req.EndpointID,
+		Name:           req.Name,
+		ServiceID:      svc.ServiceID,
+		Port:           svc.Port,
+		Method:         ep.Method,
+		Path:           ep.Path,
+		PathParams:     savedPathParams(req.PathParamsJSON),
+		QueryParams:    savedQueryParams(req.QueryParamsJSON),
+		Headers:        savedHeaders(req.HeadersJSON),
+		Body:           req.Body,
+		Extractions:    rules,
+		Assertions:     checks,
+		Spec:           h.endpointSpec(req.EndpointID),
+	}, nil
+}
+
+// savedPathParams decodes a saved request's path_params_json
+func savedPathParams(data string) map[string]string {
+	params := map[string]string{}
+	_ = json.Unmarshal([]byte(data), &params)
+	return params
+}
+
+// savedQueryParams decodes a saved request's query_params_json
+func savedQueryParams(data string) []templating.QueryParam {
+	params := []templating.QueryParam{}
+	_ = json.Unmarshal([]byte(data), &params)
+	return params
+}
+
+// savedHeaders decodes a saved request's headers_json, keeping enabled headers.
+// Headers are stored as a [{key, value, enabled}] list; a plain object is accepted too.
+func savedHeaders(data string) map[string]string {
+	headers := map[string]string{}
+
+	var list []struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Enabled bool   `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(data), &list); err == nil {
+		for _, h := range list {
+			if h.Enabled && h.Key != "" {
+				headers[h.Key] = h.Value
+			}
+		}
+		return headers
+	}
+
+	_ = json.Unmarshal([]byte(data), &headers)
+	return headers
+}
+
+// handleGetRuns lists recent collection runs without their per-request results
+func (h *Handler) handleGetRuns(data json.RawMessage) IPCResponse {
+	var input struct {
+		Limit int `json:"limit"`
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &input); err != nil {
+			return IPCResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid request data: %v", err),
+			}
+		}
+	}
+
+	runs, err := db.GetRuns(h.database, input.Limit)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get runs: %v", err),
+		}
+	}
+
+	result := make([]interface{}, len(runs))
+	for i, run := range runs {
+		result[i] = runResponse(run)
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// handleGetRun retrieves a single run with its full summary
+func (h *Handler) handleGetRun(data json.RawMessage) IPCResponse {
+	var input struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	run, err := db.GetRun(h.database, input.ID)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get run: %v", err),
+		}
+	}
+
+	result := runResponse(run)
+	result["summaryJson"] = run.SummaryJSON
+
+	return IPCResponse{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// runResponse converts a run row to its IPC representation
+func runResponse(run db.Run) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          run.ID,
+		"target":      run.Target,
+		"environment": run.Environment,
+		"status":      run.Status,
+		"total":       run.Total,
+		"passed":      run.Passed,
+		"failed":      run.Failed,
+		"skipped":     run.Skipped,
+		"durationMs":  run.DurationMs,
+		"startedAt":   run.StartedAt,
+		"finishedAt":  run.FinishedAt,
+		"createdAt":   run.CreatedAt,
+	}
+}
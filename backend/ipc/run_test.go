@@ -0,0 +1,192 @@
+package ipc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/triplewhale/postwhale/runner"
+)
+
+// seedCollection stores a service with two chained saved requests and a test environment
+func seedCollection(t *testing.T, handler *Handler, baseURL string) {
+	t.Helper()
+
+	_, _ = handler.database.Exec("INSERT INTO repositories (name, path) VALUES (?, ?)", "test-repo", "/fake/path")
+	_, _ = handler.database.Exec("INSERT INTO services (repo_id, service_id, name, port, config_json) VALUES (?, ?, ?, ?, ?)", 1, "fusion", "Fusion", 8080, "{}")
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "POST", "/orders", "createOrder", "{}")
+	_, _ = handler.database.Exec("INSERT INTO endpoints (service_id, method, path, operation_id, spec_json) VALUES (?, ?, ?, ?, ?)", 1, "GET", "/orders/{id}", "getOrder", "{}")
+
+	requests := []map[string]interface{}{
+		{
+			"endpointId":      1,
+			"name":            "Create order",
+			"headersJson":     `[{"key": "X-Trace", "value": "run", "enabled": true}]`,
+			"extractionsJson": `[{"variable": "orderId", "source": "body", "path": "$.id"}]`,
+			"assertionsJson":  `[{"type": "statusEquals", "expected": "201"}]`,
+		},
+		{
+			"endpointId":     2,
+			"name":           "Get order",
+			"pathParamsJson": `{"id": "{{orderId}}"}`,
+			"assertionsJson": `[{"type": "jsonPathEquals", "path": "$.status", "expected": "open"}]`,
+		},
+	}
+	for _, req := range requests {
+		data, _ := json.Marshal(req)
+		if resp := handler.HandleRequest(IPCRequest{Action: "saveSavedRequest", Data: data}); !resp.Success {
+			t.Fatalf("Failed to save request: %s", resp.Error)
+		}
+	}
+
+	envData, _ := json.Marshal(map[string]interface{}{"name": "test", "baseUrlTemplate": baseURL})
+	handler.HandleRequest(IPCRequest{Action: "saveEnvironment", Data: envData})
+}
+
+func TestHandleRequest_RunCollection(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	var progress bytes.Buffer
+	handler.SetWriter(NewWriter(&progress))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.Header.Get("X-Trace") == "run":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "ord_9"}`))
+		case r.URL.Path == "/orders/ord_9":
+			w.Write([]byte(`{"status": "open"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	seedCollection(t, handler, server.URL)
+
+	response := handler.HandleRequest(IPCRequest{
+		Action:    "runCollection",
+		Data:      json.RawMessage(`{"repoId": 1, "environmentId": 1}`),
+		RequestID: "run-1",
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	summary := dataMap["summary"].(runner.Summary)
+	if summary.Passed != 2 || dataMap["status"] != "passed" || dataMap["environment"] != "test" {
+		t.Fatalf("Expected passing run, got %v", dataMap)
+	}
+	if summary.Results[0].Name != "Create order" {
+		t.Errorf("Expected endpoints in path order, got %s first", summary.Results[0].Name)
+	}
+
+	// One started and one finished message per request, tagged with the requestId
+	lines := strings.Split(strings.TrimSpace(progress.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 progress messages, got %d: %s", len(lines), progress.String())
+	}
+	var msg struct {
+		RequestID string       `json:"requestId"`
+		Progress  runner.Event `json:"progress"`
+	}
+	json.Unmarshal([]byte(lines[3]), &msg)
+	if msg.RequestID != "run-1" || msg.Progress.Type != runner.EventFinished || msg.Progress.Result == nil {
+		t.Errorf("Unexpected progress message: %s", lines[3])
+	}
+
+	// The run is persisted
+	runs := handler.HandleRequest(IPCRequest{Action: "getRuns", Data: json.RawMessage(`{}`)})
+	list := runs.Data.([]interface{})
+	if len(list) != 1 || list[0].(map[string]interface{})["target"] != "repo:1" {
+		t.Fatalf("Expected one stored run, got %v", runs.Data)
+	}
+	run := handler.HandleRequest(IPCRequest{Action: "getRun", Data: json.RawMessage(`{"id": 1}`)})
+	if !strings.Contains(run.Data.(map[string]interface{})["summaryJson"].(string), "Get order") {
+		t.Errorf("Expected stored summary to include results, got %v", run.Data)
+	}
+}
+
+func TestHandleRequest_RunCollectionStopOnFailure(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	seedCollection(t, handler, server.URL)
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "runCollection",
+		Data:   json.RawMessage(`{"savedRequestIds": [1, 2], "environmentId": 1, "stopOnFailure": true}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+
+	dataMap := response.Data.(map[string]interface{})
+	summary := dataMap["summary"].(runner.Summary)
+	if dataMap["status"] != "failed" || summary.Failed != 1 || summary.Skipped != 1 {
+		t.Errorf("Expected one failure then a skip, got %+v", summary)
+	}
+}
+
+func TestHandleRequest_RunCollectionRequiresTarget(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	response := handler.HandleRequest(IPCRequest{Action: "runCollection", Data: json.RawMessage(`{}`)})
+	if response.Success {
+		t.Error("Expected error when no collection is selected")
+	}
+}
+
+func TestHandleRequest_RunCollectionReport(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "ord_9"}`))
+	}))
+	defer server.Close()
+	seedCollection(t, handler, server.URL)
+
+	response := handler.HandleRequest(IPCRequest{
+		Action: "runCollection",
+		Data:   json.RawMessage(`{"savedRequestIds": [1], "environmentId": 1, "report": "tap"}`),
+	})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	tap, _ := response.Data.(map[string]interface{})["report"].(string)
+	if !strings.Contains(tap, "ok 1 - fusion: Create order") || !strings.Contains(tap, "# environment: test") {
+		t.Errorf("Expected inline TAP report, got %q", tap)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	data, _ := json.Marshal(map[string]interface{}{"savedRequestIds": []int{1}, "environmentId": 1, "report": "junit", "reportPath": path})
+	response = handler.HandleRequest(IPCRequest{Action: "runCollection", Data: data})
+	if !response.Success {
+		t.Fatalf("Expected success, got error: %s", response.Error)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(written), `<testcase name="Create order" classname="fusion"`) {
+		t.Errorf("Expected JUnit report at %s, got %q (%v)", path, written, err)
+	}
+
+	response = handler.HandleRequest(IPCRequest{
+		Action: "runCollection",
+		Data:   json.RawMessage(`{"savedRequestIds": [1], "report": "html"}`),
+	})
+	if response.Success {
+		t.Error("Expected unknown report format to be rejected")
+	}
+}
@@ -0,0 +1,281 @@
+package ipc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/scanner"
+)
+
+// RepositoryDiff describes what a scan changed in the stored services and endpoints
+type RepositoryDiff struct {
+	ServicesAdded     []string      `json:"servicesAdded"`
+	ServicesRemoved   []string      `json:"servicesRemoved"`
+	ServicesChanged   []string      `json:"servicesChanged"`
+	ServicesUnchanged int           `json:"servicesUnchanged"`
+	EndpointsAdded    []EndpointRef `json:"endpointsAdded"`
+	EndpointsRemoved  []EndpointRef `json:"endpointsRemoved"`
+	EndpointsChanged  []EndpointRef `json:"endpointsChanged"`
+}
+
+// EndpointRef identifies an endpoint in a diff
+type EndpointRef struct {
+	ServiceID string `json:"serviceId"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+}
+
+// newRepositoryDiff returns a diff with empty, not nil, lists
+func newRepositoryDiff() RepositoryDiff {
+	return RepositoryDiff{
+		ServicesAdded:    []string{},
+		ServicesRemoved:  []string{},
+		ServicesChanged:  []string{},
+		EndpointsAdded:   []EndpointRef{},
+		EndpointsRemoved: []EndpointRef{},
+		EndpointsChanged: []EndpointRef{},
+	}
+}
+
+// Empty reports whether the scan changed nothing
+func (d RepositoryDiff) Empty() bool {
+	return len(d.ServicesAdded) == 0 && len(d.ServicesRemoved) == 0 && len(d.ServicesChanged) == 0
+}
+
+// previousFingerprints returns the stored fingerprints of a repository's
+// services, keyed by service directory, for an incremental scan
+func previousFingerprints(services []db.Service) map[string]scanner.Fingerprint {
+	previous := map[string]scanner.Fingerprint{}
+	for _, svc := range services {
+		if svc.Dir == "" {
+			continue
+		}
+		var fp scanner.Fingerprint
+		if err := json.Unmarshal([]byte(svc.FilesJSON), &fp); err == nil && len(fp) > 0 {
+			previous[svc.Dir] = fp
+		}
+	}
+	return previous
+}
+
+// serviceDir returns the directory a stored service was scanned from.
+// Services scanned before directories were recorded are in services/<id>.
+func serviceDir(svc db.Service) string {
+	if svc.Dir == "" {
+		return "services/" + svc.ServiceID
+	}
+	return svc.Dir
+}
+
+// refreshRepository rescans a repository and applies the result, returning
+// the diff and the scan's errors and warnings. An incremental rescan only
+// parses services whose files changed.
+func (h *Handler) refreshRepository(repo db.Repository, incremental bool) (RepositoryDiff, []string, error) {
+	h.syncMu.Lock()
+	defer h.syncMu.Unlock()
+
+	existing, err := db.GetServicesByRepo(h.database, repo.ID)
+	if err != nil {
+		return RepositoryDiff{}, nil, fmt.Errorf("failed to get existing services: %v", err)
+	}
+
+	opts := scanner.ScanOptions{Layout: repositoryLayout(repo)}
+	if incremental {
+		opts.Previous = previousFingerprints(existing)
+	}
+	scanResult := scanner.ScanRepositoryWith(repo.Path, opts)
+	if len(scanResult.Errors) > 0 && len(scanResult.Services) == 0 {
+		return RepositoryDiff{}, nil, fmt.Errorf("scan failed: %s", scanResult.Errors[0])
+	}
+
+	// Apply the scan atomically; a failure part way leaves the stored services as they were
+	var diff RepositoryDiff
+	var skipped []string
+	err = db.WithTx(h.database, func(tx *sql.Tx) error {
+		var err error
+		diff, skipped, err = syncRepository(tx, repo.ID, scanResult.Services)
+		return err
+	})
+	if err != nil {
+		return RepositoryDiff{}, nil, err
+	}
+	warnings := append(scanResult.Errors, scanResult.Warnings...)
+	return diff, append(warnings, skipped...), nil
+}
+
+// syncRepository applies a full scan to a repository's stored services: new
+// services and endpoints are inserted, changed ones updated in place (keeping
+// their IDs), and ones missing from the scan removed. Rows that didn't change
+// aren't written. A scanned service whose serviceId is already held by an
+// unchanged service in another directory is skipped, and reported in the
+// returned warnings. Run it in a transaction so it applies all or nothing.
+func syncRepository(q db.Querier, repoID int64, services []scanner.DiscoveredService) (RepositoryDiff, []string, error) {
+	diff := newRepositoryDiff()
+	warnings := []string{}
+
+	existing, err := db.GetServicesByRepo(q, repoID)
+	if err != nil {
+		return diff, warnings, fmt.Errorf("failed to get existing services: %v", err)
+	}
+	byDir := map[string]db.Service{}
+	byServiceID := map[string]db.Service{}
+	for _, svc := range existing {
+		byDir[serviceDir(svc)] = svc
+		byServiceID[svc.ServiceID] = svc
+	}
+
+	// The scan can't see the serviceIds of services it didn't parse; they
+	// keep theirs
+	owners := map[string]string{}
+	for _, svc := range services {
+		if row, ok := byDir[svc.Dir]; ok && svc.Unchanged {
+			owners[row.ServiceID] = svc.Dir
+		}
+	}
+
+	seen := map[int64]bool{}
+	for _, svc := range services {
+		// Unchanged services, and ones whose config couldn't be read, keep
+		// their stored rows
+		if svc.Unchanged {
+			if row, ok := byDir[svc.Dir]; ok {
+				seen[row.ID] = true
+				diff.ServicesUnchanged++
+			}
+			continue
+		}
+		if owner, ok := owners[svc.ServiceID]; ok && owner != svc.Dir {
+			warnings = append(warnings, scanner.DuplicateServiceWarning(svc, owner))
+			continue
+		}
+		owners[svc.ServiceID] = svc.Dir
+
+		row := db.Service{
+			RepoID:     repoID,
+			ServiceID:  svc.ServiceID,
+			Name:       svc.Name,
+			Port:       svc.Port,
+			ConfigJSON: configJSON(svc.Config),
+			SpecJSON:   specJSON(svc.Spec),
+			Dir:        svc.Dir,
+			FilesJSON:  specJSON(svc.Fingerprint),
+		}
+
+		old, exists := byServiceID[svc.ServiceID]
+		if exists {
+			row.ID = old.ID
+			if err := db.UpdateService(q, row); err != nil {
+				return diff, warnings, fmt.Errorf("failed to update service %s: %v", svc.ServiceID, err)
+			}
+		} else {
+			row.ID, err = db.AddService(q, row)
+			if err != nil {
+				return diff, warnings, fmt.Errorf("failed to add service %s: %v", svc.ServiceID, err)
+			}
+		}
+		seen[row.ID] = true
+
+		endpointsChanged, err := syncEndpoints(q, row.ID, svc, &diff)
+		if err != nil {
+			return diff, warnings, err
+		}
+
+		switch {
+		case !exists:
+			diff.ServicesAdded = append(diff.ServicesAdded, svc.ServiceID)
+		case endpointsChanged || old.Name != row.Name || old.Port != row.Port || old.ConfigJSON != row.ConfigJSON || old.SpecJSON != row.SpecJSON:
+			diff.ServicesChanged = append(diff.ServicesChanged, svc.ServiceID)
+		default:
+			// Files changed but nothing we store did (e.g. comments)
+			diff.ServicesUnchanged++
+		}
+	}
+
+	// Remove services that no longer exist in the repository
+	for _, svc := range existing {
+		if seen[svc.ID] {
+			continue
+		}
+		endpoints, err := db.GetEndpointsByService(q, svc.ID)
+		if err != nil {
+			return diff, warnings, fmt.Errorf("failed to get endpoints for %s: %v", svc.ServiceID, err)
+		}
+		if err := db.DeleteService(q, svc.ID); err != nil {
+			return diff, warnings, fmt.Errorf("failed to remove service %s: %v", svc.ServiceID, err)
+		}
+		diff.ServicesRemoved = append(diff.ServicesRemoved, svc.ServiceID)
+		for _, ep := range endpoints {
+			diff.EndpointsRemoved = append(diff.EndpointsRemoved, EndpointRef{ServiceID: svc.ServiceID, Method: ep.Method, Path: ep.Path})
+		}
+	}
+
+	return diff, warnings, nil
+}
+
+// syncEndpoints applies a scanned service's endpoints to its stored rows,
+// recording each change in diff. It reports whether anything changed.
+func syncEndpoints(q db.Querier, serviceID int64, svc scanner.DiscoveredService, diff *RepositoryDiff) (bool, error) {
+	existing, err := db.GetEndpointsByService(q, serviceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get endpoints for %s: %v", svc.ServiceID, err)
+	}
+	byKey := map[string]db.Endpoint{}
+	for _, ep := range existing {
+		byKey[ep.Method+" "+ep.Path] = ep
+	}
+
+	changed := false
+	scanned := map[string]bool{}
+	for _, endpoint := range svc.Endpoints {
+		key := endpoint.Method + " " + endpoint.Path
+		scanned[key] = true
+		ref := EndpointRef{ServiceID: svc.ServiceID, Method: endpoint.Method, Path: endpoint.Path}
+
+		file, visibility := specSource(endpoint)
+		row := db.Endpoint{
+			ServiceID:   serviceID,
+			Method:      endpoint.Method,
+			Path:        endpoint.Path,
+			OperationID: endpoint.OperationID,
+			SpecJSON:    specJSON(endpoint),
+			SpecFile:    file,
+			Visibility:  visibility,
+		}
+
+		old, exists := byKey[key]
+		if !exists {
+			if _, err := db.AddEndpoint(q, row); err != nil {
+				return changed, fmt.Errorf("failed to add endpoint %s: %v", endpoint.Path, err)
+			}
+			diff.EndpointsAdded = append(diff.EndpointsAdded, ref)
+			changed = true
+			continue
+		}
+
+		if old.OperationID == row.OperationID && old.SpecJSON == row.SpecJSON && old.SpecFile == row.SpecFile && old.Visibility == row.Visibility {
+			continue
+		}
+		row.ID = old.ID
+		if err := db.UpdateEndpoint(q, row); err != nil {
+			return changed, fmt.Errorf("failed to update endpoint %s: %v", endpoint.Path, err)
+		}
+		diff.EndpointsChanged = append(diff.EndpointsChanged, ref)
+		changed = true
+	}
+
+	// Remove endpoints that no longer exist for this service
+	for _, ep := range existing {
+		if scanned[ep.Method+" "+ep.Path] {
+			continue
+		}
+		if err := db.DeleteEndpoint(q, ep.ID); err != nil {
+			return changed, fmt.Errorf("failed to remove endpoint %s: %v", ep.Path, err)
+		}
+		diff.EndpointsRemoved = append(diff.EndpointsRemoved, EndpointRef{ServiceID: svc.ServiceID, Method: ep.Method, Path: ep.Path})
+		changed = true
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,285 @@
+package ipc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/triplewhale/postwhale/db"
+)
+
+const syncSpec = `openapi: 3.0.0
+info:
+  title: Orders
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200":
+          description: ok
+  /orders/{id}:
+    get:
+      operationId: getOrder
+      responses:
+        "200":
+          description: ok
+`
+
+func writeSyncService(t *testing.T, repo, name, spec string) {
+	t.Helper()
+	svcDir := filepath.Join(repo, "services", name)
+	if err := os.MkdirAll(svcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"tw-config.json": `{"serviceId": "` + name + `", "env": {"PORT": 8080}}`,
+		"openapi.yaml":   spec,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(svcDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func refreshDiff(t *testing.T, handler *Handler) RepositoryDiff {
+	t.Helper()
+	response := handler.HandleRequest(IPCRequest{Action: "refreshRepository", Data: json.RawMessage(`{"id": 1}`)})
+	if !response.Success {
+		t.Fatalf("Refresh failed: %s", response.Error)
+	}
+	return response.Data.(map[string]interface{})["diff"].(RepositoryDiff)
+}
+
+func TestHandleRequest_RefreshRepositoryDiff(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	writeSyncService(t, repo, "billing", syncSpec)
+
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+	before, _ := db.GetAllEndpoints(handler.database)
+
+	// Nothing on disk changed
+	diff := refreshDiff(t, handler)
+	if !diff.Empty() || diff.ServicesUnchanged != 2 {
+		t.Errorf("Expected an empty diff, got %+v", diff)
+	}
+
+	// Change one operation, drop one and add one; remove the other service
+	edited := `openapi: 3.0.0
+info:
+  title: Orders
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      summary: List orders
+      responses:
+        "200":
+          description: ok
+  /refunds:
+    post:
+      operationId: createRefund
+      responses:
+        "201":
+          description: created
+`
+	if err := os.WriteFile(filepath.Join(repo, "services", "orders", "openapi.yaml"), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(repo, "services", "billing")); err != nil {
+		t.Fatal(err)
+	}
+
+	diff = refreshDiff(t, handler)
+	if len(diff.ServicesChanged) != 1 || diff.ServicesChanged[0] != "orders" || len(diff.ServicesRemoved) != 1 || diff.ServicesRemoved[0] != "billing" {
+		t.Errorf("Expected orders changed and billing removed, got %+v", diff)
+	}
+	if len(diff.EndpointsAdded) != 1 || diff.EndpointsAdded[0] != (EndpointRef{ServiceID: "orders", Method: "POST", Path: "/refunds"}) {
+		t.Errorf("Expected refunds endpoint added, got %+v", diff.EndpointsAdded)
+	}
+	if len(diff.EndpointsChanged) != 1 || diff.EndpointsChanged[0].Path != "/orders" {
+		t.Errorf("Expected /orders changed, got %+v", diff.EndpointsChanged)
+	}
+	// The dropped orders endpoint plus billing's two
+	if len(diff.EndpointsRemoved) != 3 || diff.EndpointsRemoved[0] != (EndpointRef{ServiceID: "orders", Method: "GET", Path: "/orders/{id}"}) {
+		t.Errorf("Expected 3 removed endpoints, got %+v", diff.EndpointsRemoved)
+	}
+
+	// Changed endpoints keep their IDs
+	after, _ := db.GetAllEndpoints(handler.database)
+	if len(after) != 2 {
+		t.Fatalf("Expected 2 endpoints left, got %d", len(after))
+	}
+	for _, ep := range after {
+		if ep.Path != "/orders" {
+			continue
+		}
+		for _, old := range before {
+			if old.Path == "/orders" && old.ServiceID == ep.ServiceID && old.ID != ep.ID {
+				t.Errorf("Expected /orders to keep ID %d, got %d", old.ID, ep.ID)
+			}
+		}
+	}
+}
+
+func TestHandleRequest_RefreshKeepsServiceWithBrokenConfig(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+	endpoints, _ := db.GetAllEndpoints(handler.database)
+	if _, err := db.AddSavedRequest(handler.database, db.SavedRequest{EndpointID: endpoints[0].ID, Name: "List"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A config caught half-written is reported, not treated as a removal
+	configPath := filepath.Join(repo, "services", "orders", "tw-config.json")
+	if err := os.WriteFile(configPath, []byte(`{"serviceId": "ord`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	response := handler.HandleRequest(IPCRequest{Action: "refreshRepository", Data: json.RawMessage(`{"id": 1}`)})
+	if !response.Success {
+		t.Fatalf("Refresh failed: %s", response.Error)
+	}
+	result := response.Data.(map[string]interface{})
+	if diff := result["diff"].(RepositoryDiff); !diff.Empty() {
+		t.Errorf("Expected nothing removed, got %+v", diff)
+	}
+	if warnings := result["warnings"].([]string); len(warnings) == 0 {
+		t.Error("Expected the broken config to be reported")
+	}
+
+	writeSyncService(t, repo, "orders", syncSpec)
+	refreshDiff(t, handler)
+
+	saved, _ := db.GetAllSavedRequests(handler.database)
+	if len(saved) != 1 {
+		t.Errorf("Expected the saved request to survive, got %d", len(saved))
+	}
+}
+
+func TestHandleRequest_RefreshKeepsEndpointsWithBrokenSpec(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+	endpoints, _ := db.GetAllEndpoints(handler.database)
+	if _, err := db.AddSavedRequest(handler.database, db.SavedRequest{EndpointID: endpoints[0].ID, Name: "List"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A spec caught half-written keeps the stored endpoints, and with them
+	// their saved requests
+	specPath := filepath.Join(repo, "services", "orders", "openapi.yaml")
+	if err := os.WriteFile(specPath, []byte("openapi: 3.0.0\npaths:\n  /orders:\n    get: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	response := handler.HandleRequest(IPCRequest{Action: "refreshRepository", Data: json.RawMessage(`{"id": 1}`)})
+	if !response.Success {
+		t.Fatalf("Refresh failed: %s", response.Error)
+	}
+	result := response.Data.(map[string]interface{})
+	if diff := result["diff"].(RepositoryDiff); !diff.Empty() || len(diff.EndpointsRemoved) != 0 {
+		t.Errorf("Expected nothing removed, got %+v", diff)
+	}
+	if warnings := result["warnings"].([]string); len(warnings) != 1 || !strings.Contains(warnings[0], "openapi.yaml") {
+		t.Errorf("Expected the broken spec to be reported, got %v", warnings)
+	}
+
+	after, _ := db.GetAllEndpoints(handler.database)
+	if len(after) != len(endpoints) {
+		t.Errorf("Expected %d endpoints to be kept, got %d", len(endpoints), len(after))
+	}
+	saved, _ := db.GetAllSavedRequests(handler.database)
+	if len(saved) != 1 {
+		t.Errorf("Expected the saved request to survive, got %d", len(saved))
+	}
+}
+
+func TestHandleRequest_DuplicateServiceIDs(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// Two directories claiming the same serviceId; the first is kept
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+	writeSyncService(t, repo, "orders-copy", syncSpec)
+	config := `{"serviceId": "orders", "env": {"PORT": 8080}}`
+	copyConfig := filepath.Join(repo, "services", "orders-copy", "tw-config.json")
+	if err := os.WriteFile(copyConfig, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	response := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data})
+	if !response.Success {
+		t.Fatalf("Expected the duplicate to be skipped, got error: %s", response.Error)
+	}
+	if warnings := response.Data.(map[string]interface{})["warnings"].([]string); len(warnings) != 1 || !strings.Contains(warnings[0], "services/orders-copy") {
+		t.Errorf("Expected a warning for the duplicate, got %v", warnings)
+	}
+	services, _ := db.GetAllServices(handler.database)
+	if len(services) != 1 || services[0].Dir != "services/orders" {
+		t.Fatalf("Expected only services/orders to be stored, got %+v", services)
+	}
+
+	// An incremental refresh only parses the copy, which was never stored,
+	// but the unchanged original still owns the serviceId
+	response = handler.HandleRequest(IPCRequest{Action: "refreshRepository", Data: json.RawMessage(`{"id": 1}`)})
+	if !response.Success {
+		t.Fatalf("Refresh failed: %s", response.Error)
+	}
+	if warnings := response.Data.(map[string]interface{})["warnings"].([]string); len(warnings) != 1 {
+		t.Errorf("Expected a warning for the duplicate, got %v", warnings)
+	}
+	services, _ = db.GetAllServices(handler.database)
+	if len(services) != 1 || services[0].Dir != "services/orders" {
+		t.Errorf("Expected services/orders to keep the serviceId, got %+v", services)
+	}
+}
+
+func TestHandleRequest_AddRepositoryIsAtomic(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	// Fail the sync after the repository and its service are inserted
+	if _, err := handler.database.Exec(`CREATE TRIGGER fail_endpoint BEFORE INSERT ON endpoints WHEN NEW.path = '/orders/{id}'
+		BEGIN SELECT RAISE(ABORT, 'endpoint rejected'); END`); err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); resp.Success {
+		t.Fatal("Expected the rejected endpoint to fail the add")
+	}
+
+	// Nothing from the failed add is left behind
+	repos, _ := db.GetRepositories(handler.database)
+	services, _ := db.GetAllServices(handler.database)
+	endpoints, _ := db.GetAllEndpoints(handler.database)
+	if len(repos) != 0 || len(services) != 0 || len(endpoints) != 0 {
+		t.Errorf("Expected the add to roll back, got %d repositories, %d services, %d endpoints", len(repos), len(services), len(endpoints))
+	}
+}
@@ -0,0 +1,101 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/triplewhale/postwhale/db"
+)
+
+// handleSetVariable creates or replaces a variable in a scope
+func (h *Handler) handleSetVariable(data json.RawMessage) IPCResponse {
+	var input struct {
+		Scope string `json:"scope"`
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if err := db.SetVariable(h.database, input.Scope, input.Name, input.Value); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to set variable: %v", err),
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"scope": input.Scope,
+			"name":  input.Name,
+			"value": input.Value,
+		},
+	}
+}
+
+// handleGetVariables retrieves all variables in a scope
+// Scopes are "global", "service:<serviceId>" and "request:<savedRequestId>"
+func (h *Handler) handleGetVariables(data json.RawMessage) IPCResponse {
+	var input struct {
+		Scope string `json:"scope"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if input.Scope == "" {
+		input.Scope = db.GlobalScope
+	}
+
+	vars, err := db.GetVariables(h.database, input.Scope)
+	if err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get variables: %v", err),
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data:    vars,
+	}
+}
+
+// handleDeleteVariable removes a variable from a scope
+func (h *Handler) handleDeleteVariable(data json.RawMessage) IPCResponse {
+	var input struct {
+		Scope string `json:"scope"`
+		Name  string `json:"name"`
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid request data: %v", err),
+		}
+	}
+
+	if err := db.DeleteVariable(h.database, input.Scope, input.Name); err != nil {
+		return IPCResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to delete variable: %v", err),
+		}
+	}
+
+	return IPCResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"deleted": true,
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package ipc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/events"
+	"github.com/triplewhale/postwhale/scanner"
+)
+
+// fileState is what the watcher compares between polls
+type fileState struct {
+	size    int64
+	modTime int64
+}
+
+// repoWatch tracks one repository between polls
+type repoWatch struct {
+	files   map[string]fileState
+	changed time.Time // last time files differed; zero when settled
+}
+
+// Watch polls every registered repository's service configs and spec files
+// until ctx is done. Polling keeps the backend free of platform-specific
+// file notification APIs. Once a repository's files have stopped changing for
+// debounce it is rescanned incrementally, so only the edited services are
+// re-parsed, and a repositoryChanged event carrying the diff is published when
+// anything stored changed.
+func (h *Handler) Watch(ctx context.Context, interval, debounce time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	watches := map[int64]*repoWatch{}
+	for {
+		h.pollRepositories(watches, time.Now(), debounce)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollRepositories runs one watcher pass over every repository
+func (h *Handler) pollRepositories(watches map[int64]*repoWatch, now time.Time, debounce time.Duration) {
+	repos, err := db.GetRepositories(h.database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Watcher: failed to get repositories: %v\n", err)
+		return
+	}
+
+	current := map[int64]bool{}
+	for _, repo := range repos {
+		current[repo.ID] = true
+
+		services, err := db.GetServicesByRepo(h.database, repo.ID)
+		if err != nil {
+			continue
+		}
+		files := statFiles(scanner.WatchFiles(repo.Path, repositoryLayout(repo), previousFingerprints(services)))
+
+		watch, ok := watches[repo.ID]
+		if !ok {
+			// First sight of the repository: nothing to compare against yet
+			watches[repo.ID] = &repoWatch{files: files}
+			continue
+		}
+		if !sameFiles(watch.files, files) {
+			watch.files = files
+			watch.changed = now
+			continue
+		}
+		if watch.changed.IsZero() || now.Sub(watch.changed) < debounce {
+			continue
+		}
+
+		watch.changed = time.Time{}
+		diff, warnings, err := h.refreshRepository(repo, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Watcher: failed to refresh %s: %v\n", repo.Path, err)
+			continue
+		}
+		if diff.Empty() {
+			continue
+		}
+		h.events.Publish(events.TopicRepositoryChanged, map[string]interface{}{
+			"id":       repo.ID,
+			"name":     repo.Name,
+			"path":     repo.Path,
+			"diff":     diff,
+			"warnings": warnings,
+		})
+	}
+
+	// Forget repositories that were removed
+	for id := range watches {
+		if !current[id] {
+			delete(watches, id)
+		}
+	}
+}
+
+// statFiles records the size and modification time of each file that exists
+func statFiles(paths []string) map[string]fileState {
+	files := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime().UnixNano()}
+	}
+	return files
+}
+
+// sameFiles reports whether two polls saw the same files in the same state
+func sameFiles(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		if other, ok := b[path]; !ok || other != state {
+			return false
+		}
+	}
+	return true
+}
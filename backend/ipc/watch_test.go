@@ -0,0 +1,79 @@
+package ipc
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollRepositories_DebouncesAndSendsEvent(t *testing.T) {
+	handler := NewHandler(":memory:")
+	defer handler.Close()
+
+	var buf bytes.Buffer
+	handler.SetWriter(NewWriter(&buf))
+	if resp := handler.HandleRequest(IPCRequest{Action: "subscribe", Data: json.RawMessage(`{"topics": ["repositoryChanged"]}`)}); !resp.Success {
+		t.Fatalf("Failed to subscribe: %s", resp.Error)
+	}
+
+	repo := t.TempDir()
+	writeSyncService(t, repo, "orders", syncSpec)
+
+	data, _ := json.Marshal(map[string]string{"path": repo})
+	if resp := handler.HandleRequest(IPCRequest{Action: "addRepository", Data: data}); !resp.Success {
+		t.Fatalf("Failed to add repository: %s", resp.Error)
+	}
+
+	watches := map[int64]*repoWatch{}
+	start := time.Now()
+	debounce := time.Second
+
+	// The first poll only records what's on disk
+	handler.pollRepositories(watches, start, debounce)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no event on the first poll, got %s", buf.String())
+	}
+
+	edited := strings.Replace(syncSpec, "operationId: listOrders", "operationId: listOrders\n      summary: List orders", 1)
+	specPath := filepath.Join(repo, "services", "orders", "openapi.yaml")
+	if err := os.WriteFile(specPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Seeing the change and polling again within the debounce does nothing yet
+	handler.pollRepositories(watches, start.Add(100*time.Millisecond), debounce)
+	handler.pollRepositories(watches, start.Add(500*time.Millisecond), debounce)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no event before the debounce, got %s", buf.String())
+	}
+
+	// Once the files have been quiet for the debounce the repository is refreshed
+	handler.pollRepositories(watches, start.Add(2*time.Second), debounce)
+	var event struct {
+		Event string `json:"event"`
+		Data  struct {
+			ID   int64          `json:"id"`
+			Diff RepositoryDiff `json:"diff"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("Expected one event line, got %q: %v", buf.String(), err)
+	}
+	if event.Event != "repositoryChanged" || event.Data.ID != 1 {
+		t.Errorf("Expected repositoryChanged for repository 1, got %+v", event)
+	}
+	if len(event.Data.Diff.EndpointsChanged) != 1 || event.Data.Diff.EndpointsChanged[0].Path != "/orders" {
+		t.Errorf("Expected /orders changed, got %+v", event.Data.Diff)
+	}
+
+	// Nothing further changed, so nothing more is sent
+	buf.Reset()
+	handler.pollRepositories(watches, start.Add(5*time.Second), debounce)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no further events, got %s", buf.String())
+	}
+}
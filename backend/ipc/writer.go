@@ -0,0 +1,50 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Writer serializes newline-delimited JSON messages onto a shared output stream.
+// Requests are handled concurrently, so every write to stdout must go through here.
+type Writer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewWriter creates a Writer that writes to out
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// WriteResponse writes a single response line
+func (w *Writer) WriteResponse(response IPCResponse) error {
+	return w.writeLine(response)
+}
+
+// WriteProgress writes an intermediate progress line for a request that is still running
+func (w *Writer) WriteProgress(progress IPCProgress) error {
+	return w.writeLine(progress)
+}
+
+// WriteEvent writes an unsolicited event line
+func (w *Writer) WriteEvent(event IPCEvent) error {
+	return w.writeLine(event)
+}
+
+// writeLine marshals v and writes it as one line, holding the lock for the whole write
+func (w *Writer) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.out.Write(line)
+	return err
+}
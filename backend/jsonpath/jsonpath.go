@@ -0,0 +1,179 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segment is one step of a parsed path: a key, an index, or a wildcard
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parse validates a JSONPath expression and splits it into segments.
+// Supported syntax: $, .key, ['key'], ["key"], [n], [-n], .* and [*]
+func parse(path string) ([]segment, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("jsonpath must start with $: %q", path)
+	}
+
+	var segments []segment
+	i := 1
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			key := path[start:i]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in jsonpath %q", path)
+			}
+			if key == "*" {
+				segments = append(segments, segment{wildcard: true})
+			} else {
+				segments = append(segments, segment{key: key})
+			}
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in jsonpath %q", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "*":
+				segments = append(segments, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, segment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in jsonpath %q", inner, path)
+				}
+				segments = append(segments, segment{index: n, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q in jsonpath %q", path[i], path)
+		}
+	}
+
+	return segments, nil
+}
+
+// Decode parses a JSON document for querying. Numbers are decoded as
+// json.Number so large integers such as IDs keep every digit.
+func Decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value")
+	}
+	return doc, nil
+}
+
+// Query evaluates path against a decoded JSON document and returns every match.
+// An empty result with a nil error means the path is valid but matched nothing.
+func Query(doc interface{}, path string) ([]interface{}, error) {
+	segments, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{doc}
+	for _, seg := range segments {
+		var next []interface{}
+		for _, node := range current {
+			next = append(next, step(node, seg)...)
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// First evaluates path and returns the first match
+func First(doc interface{}, path string) (interface{}, bool, error) {
+	matches, err := Query(doc, path)
+	if err != nil || len(matches) == 0 {
+		return nil, false, err
+	}
+	return matches[0], true, nil
+}
+
+// step applies a single segment to a node
+func step(node interface{}, seg segment) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if seg.wildcard {
+			// Keys in sorted order, so matches don't depend on map iteration
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			out := make([]interface{}, 0, len(v))
+			for _, key := range keys {
+				out = append(out, v[key])
+			}
+			return out
+		}
+		if seg.isIndex {
+			return nil
+		}
+		if child, ok := v[seg.key]; ok {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if seg.wildcard {
+			return v
+		}
+		if !seg.isIndex {
+			return nil
+		}
+		idx := seg.index
+		if idx < 0 {
+			idx += len(v)
+		}
+		if idx >= 0 && idx < len(v) {
+			return []interface{}{v[idx]}
+		}
+	}
+	return nil
+}
+
+// String renders a matched value as text: strings as-is, numbers from Decode
+// as their original digits, everything else as compact JSON
+func String(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
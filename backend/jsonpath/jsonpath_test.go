@@ -0,0 +1,91 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("invalid test JSON: %v", err)
+	}
+	return doc
+}
+
+func TestFirst(t *testing.T) {
+	doc := decode(t, `{"order": {"id": "ord_1", "items": [{"sku": "a"}, {"sku": "b"}]}, "odd key": 1}`)
+
+	cases := map[string]interface{}{
+		"$.order.id":            "ord_1",
+		"$.order.items[1].sku":  "b",
+		"$.order.items[-1].sku": "b",
+		"$['odd key']":          float64(1),
+		`$["order"]["id"]`:      "ord_1",
+	}
+	for path, expected := range cases {
+		value, ok, err := First(doc, path)
+		if err != nil || !ok {
+			t.Errorf("First(%s) failed: ok=%v err=%v", path, ok, err)
+			continue
+		}
+		if value != expected {
+			t.Errorf("First(%s) = %v, want %v", path, value, expected)
+		}
+	}
+}
+
+func TestQuery_Wildcard(t *testing.T) {
+	doc := decode(t, `{"items": [{"sku": "a"}, {"sku": "b"}]}`)
+
+	matches, err := Query(doc, "$.items[*].sku")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0] != "a" || matches[1] != "b" {
+		t.Errorf("Expected [a b], got %v", matches)
+	}
+}
+
+func TestQuery_NoMatchAndInvalid(t *testing.T) {
+	doc := decode(t, `{"a": 1}`)
+
+	if _, ok, err := First(doc, "$.b"); ok || err != nil {
+		t.Errorf("Expected no match without error, got ok=%v err=%v", ok, err)
+	}
+	for _, path := range []string{"a.b", "$.", "$[1", "$[x]"} {
+		if _, err := Query(doc, path); err == nil {
+			t.Errorf("Expected error for invalid path %q", path)
+		}
+	}
+}
+
+func TestDecode_KeepsNumberPrecision(t *testing.T) {
+	doc, err := Decode([]byte(`{"id": 9007199254740993, "total": 1.50}`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	for path, expected := range map[string]string{"$.id": "9007199254740993", "$.total": "1.50"} {
+		value, _, _ := First(doc, path)
+		if got := String(value); got != expected {
+			t.Errorf("String(%s) = %s, want %s", path, got, expected)
+		}
+	}
+
+	for _, input := range []string{"", `{"a": 1} x`, `{"a":`} {
+		if _, err := Decode([]byte(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestQuery_WildcardObjectOrder(t *testing.T) {
+	doc := decode(t, `{"c": 3, "a": 1, "b": 2}`)
+	for i := 0; i < 20; i++ {
+		matches, _ := Query(doc, "$.*")
+		if len(matches) != 3 || matches[0] != float64(1) || matches[1] != float64(2) || matches[2] != float64(3) {
+			t.Fatalf("Expected values in key order, got %v", matches)
+		}
+	}
+}
@@ -2,15 +2,24 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/triplewhale/postwhale/cli"
 	"github.com/triplewhale/postwhale/ipc"
 )
 
 func main() {
+	// Subcommands run headless (e.g. in CI) instead of speaking the IPC protocol
+	if len(os.Args) > 1 {
+		os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	// Get user data directory for database
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -27,12 +36,27 @@ func main() {
 
 	// Initialize database
 	dbPath := filepath.Join(dataDir, "postwhale.db")
-	handler := ipc.NewHandler(dbPath)
+	handler, err := ipc.OpenHandler(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	defer handler.Close()
 
 	fmt.Fprintf(os.Stderr, "PostWhale Backend Started (DB: %s)\n", dbPath)
 
 	// Read JSON requests from stdin, write responses to stdout
+	// Each request is handled in its own goroutine so a slow request doesn't block the rest;
+	// responses are correlated by requestId and may arrive out of order
+	out := ipc.NewWriter(os.Stdout)
+	handler.SetWriter(out)
+	var wg sync.WaitGroup
+
+	// Rescan repositories when their service configs or specs change on disk
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go handler.Watch(watchCtx, time.Second, 500*time.Millisecond)
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -44,28 +68,30 @@ func main() {
 				Success: false,
 				Error:   fmt.Sprintf("invalid JSON: %v", err),
 			}
-			writeResponse(response)
+			writeResponse(out, response)
 			continue
 		}
 
 		// Handle request
-		response := handler.HandleRequest(request)
-
-		// Write response
-		writeResponse(response)
+		wg.Add(1)
+		go func(request ipc.IPCRequest) {
+			defer wg.Done()
+			writeResponse(out, handler.HandleRequest(request))
+		}(request)
 	}
 
+	// Let in-flight requests finish before closing the database
+	stopWatching()
+	wg.Wait()
+
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func writeResponse(response ipc.IPCResponse) {
-	responseJSON, err := json.Marshal(response)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling response: %v\n", err)
-		return
+func writeResponse(out *ipc.Writer, response ipc.IPCResponse) {
+	if err := out.WriteResponse(response); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing response: %v\n", err)
 	}
-	fmt.Println(string(responseJSON))
 }
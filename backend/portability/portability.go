@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/triplewhale/postwhale/assertions"
+	store "github.com/triplewhale/postwhale/db"
+	"github.com/triplewhale/postwhale/extract"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,13 +24,15 @@ type SavedRequestWithEndpoint struct {
 	QueryParamsJSON string
 	HeadersJSON     string
 	Body            string
+	ExtractionsJSON string
+	AssertionsJSON  string
 	Method          string
 	Path            string
 }
 
 func GetSavedRequestsWithEndpoints(db *sql.DB, serviceID int64) ([]SavedRequestWithEndpoint, error) {
 	query := `
-		SELECT sr.id, sr.endpoint_id, sr.name, sr.path_params_json, sr.query_params_json, sr.headers_json, sr.body, e.method, e.path
+		SELECT sr.id, sr.endpoint_id, sr.name, sr.path_params_json, sr.query_params_json, sr.headers_json, sr.body, sr.extractions_json, sr.assertions_json, e.method, e.path
 		FROM saved_requests sr
 		JOIN endpoints e ON sr.endpoint_id = e.id
 		WHERE e.service_id = ?
@@ -42,7 +47,7 @@ func GetSavedRequestsWithEndpoints(db *sql.DB, serviceID int64) ([]SavedRequestW
 	var results []SavedRequestWithEndpoint
 	for rows.Next() {
 		var r SavedRequestWithEndpoint
-		if err := rows.Scan(&r.ID, &r.EndpointID, &r.Name, &r.PathParamsJSON, &r.QueryParamsJSON, &r.HeadersJSON, &r.Body, &r.Method, &r.Path); err != nil {
+		if err := rows.Scan(&r.ID, &r.EndpointID, &r.Name, &r.PathParamsJSON, &r.QueryParamsJSON, &r.HeadersJSON, &r.Body, &r.ExtractionsJSON, &r.AssertionsJSON, &r.Method, &r.Path); err != nil {
 			return nil, err
 		}
 		results = append(results, r)
@@ -50,19 +55,25 @@ func GetSavedRequestsWithEndpoints(db *sql.DB, serviceID int64) ([]SavedRequestW
 	return results, rows.Err()
 }
 
-func GetServicePath(db *sql.DB, serviceID int64) (string, string, error) {
+// GetServicePath returns a service's ID and directory. The directory recorded
+// by the last scan is used, so services outside services/ are found too.
+func GetServicePath(db store.Querier, serviceID int64) (string, string, error) {
 	query := `
-		SELECT s.service_id, r.path
+		SELECT s.service_id, s.dir, r.path
 		FROM services s
 		JOIN repositories r ON s.repo_id = r.id
 		WHERE s.id = ?
 	`
-	var svcID, repoPath string
-	err := db.QueryRow(query, serviceID).Scan(&svcID, &repoPath)
+	var svcID, dir, repoPath string
+	err := db.QueryRow(query, serviceID).Scan(&svcID, &dir, &repoPath)
 	if err != nil {
 		return "", "", err
 	}
-	return svcID, filepath.Join(repoPath, "services", svcID), nil
+	if dir == "" {
+		// Scanned before directories were recorded
+		return svcID, filepath.Join(repoPath, "services", svcID), nil
+	}
+	return svcID, filepath.Join(repoPath, filepath.FromSlash(dir)), nil
 }
 
 func GetRepoServices(db *sql.DB, repoID int64) ([]struct {
@@ -150,6 +161,14 @@ func ExportServiceSavedRequests(db *sql.DB, serviceID int64) (*ExportResult, err
 			}
 		}
 
+		if rules, err := extract.ParseRules(r.ExtractionsJSON); err == nil && len(rules) > 0 {
+			portable.Extractions = rules
+		}
+
+		if list, err := assertions.ParseAssertions(r.AssertionsJSON); err == nil && len(list) > 0 {
+			portable.Assertions = list
+		}
+
 		file.SavedRequests = append(file.SavedRequests, portable)
 	}
 
@@ -166,12 +185,8 @@ func ExportServiceSavedRequests(db *sql.DB, serviceID int64) (*ExportResult, err
 	return &ExportResult{FilePath: filePath, Count: len(file.SavedRequests)}, nil
 }
 
-func ImportServiceSavedRequests(db *sql.DB, serviceID int64) (*ImportResult, error) {
-	svcID, svcPath, err := GetServicePath(db, serviceID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get service path: %w", err)
-	}
-
+// ReadSavedRequestsFile reads and parses the saved requests file in a service directory
+func ReadSavedRequestsFile(svcPath string) (*SavedRequestsFile, error) {
 	filePath := filepath.Join(svcPath, FileName)
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -186,6 +201,37 @@ func ImportServiceSavedRequests(db *sql.DB, serviceID int64) (*ImportResult, err
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	return &file, nil
+}
+
+// ImportServiceSavedRequests reads a service's saved requests file and adds
+// or replaces its saved requests in one transaction. Requests for endpoints
+// the service doesn't have are skipped; any database error rolls the whole
+// import back.
+func ImportServiceSavedRequests(db *sql.DB, serviceID int64) (*ImportResult, error) {
+	var result *ImportResult
+	err := store.WithTx(db, func(tx *sql.Tx) error {
+		var err error
+		result, err = importServiceSavedRequests(tx, serviceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func importServiceSavedRequests(db store.Querier, serviceID int64) (*ImportResult, error) {
+	svcID, svcPath, err := GetServicePath(db, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service path: %w", err)
+	}
+
+	file, err := ReadSavedRequestsFile(svcPath)
+	if err != nil {
+		return nil, err
+	}
+
 	if file.ServiceID != svcID {
 		return nil, fmt.Errorf("service_id mismatch: file has '%s', expected '%s'", file.ServiceID, svcID)
 	}
@@ -232,27 +278,50 @@ func ImportServiceSavedRequests(db *sql.DB, serviceID int64) (*ImportResult, err
 			}
 		}
 
+		extractionsJSON := "[]"
+		if len(portable.Extractions) > 0 {
+			if data, err := json.Marshal(portable.Extractions); err == nil {
+				extractionsJSON = string(data)
+			}
+		}
+
+		assertionsJSON := "[]"
+		if len(portable.Assertions) > 0 {
+			if data, err := json.Marshal(portable.Assertions); err == nil {
+				assertionsJSON = string(data)
+			}
+		}
+
+		// Check the lists the way the editor does; a bad entry would otherwise
+		// fail every run that includes the request
+		if _, err := extract.ParseRules(extractionsJSON); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", portable.Name, err))
+			result.Skipped++
+			continue
+		}
+		if _, err := assertions.ParseAssertions(assertionsJSON); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", portable.Name, err))
+			result.Skipped++
+			continue
+		}
+
 		existingID, exists := existingRequests[endpointID][portable.Name]
 		if exists {
 			_, err := db.Exec(
-				"UPDATE saved_requests SET path_params_json = ?, query_params_json = ?, headers_json = ?, body = ? WHERE id = ?",
-				pathParamsJSON, queryParamsJSON, headersJSON, portable.Body, existingID,
+				"UPDATE saved_requests SET path_params_json = ?, query_params_json = ?, headers_json = ?, body = ?, extractions_json = ?, assertions_json = ? WHERE id = ?",
+				pathParamsJSON, queryParamsJSON, headersJSON, portable.Body, extractionsJSON, assertionsJSON, existingID,
 			)
 			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("failed to update '%s': %v", portable.Name, err))
-				result.Skipped++
-				continue
+				return nil, fmt.Errorf("failed to update '%s': %w", portable.Name, err)
 			}
 			result.Replaced++
 		} else {
 			_, err := db.Exec(
-				"INSERT INTO saved_requests (endpoint_id, name, path_params_json, query_params_json, headers_json, body) VALUES (?, ?, ?, ?, ?, ?)",
-				endpointID, portable.Name, pathParamsJSON, queryParamsJSON, headersJSON, portable.Body,
+				"INSERT INTO saved_requests (endpoint_id, name, path_params_json, query_params_json, headers_json, body, extractions_json, assertions_json) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				endpointID, portable.Name, pathParamsJSON, queryParamsJSON, headersJSON, portable.Body, extractionsJSON, assertionsJSON,
 			)
 			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("failed to add '%s': %v", portable.Name, err))
-				result.Skipped++
-				continue
+				return nil, fmt.Errorf("failed to add '%s': %w", portable.Name, err)
 			}
 			result.Added++
 		}
@@ -261,7 +330,7 @@ func ImportServiceSavedRequests(db *sql.DB, serviceID int64) (*ImportResult, err
 	return result, nil
 }
 
-func buildEndpointMap(db *sql.DB, serviceID int64) (map[string]int64, error) {
+func buildEndpointMap(db store.Querier, serviceID int64) (map[string]int64, error) {
 	rows, err := db.Query("SELECT id, method, path FROM endpoints WHERE service_id = ?", serviceID)
 	if err != nil {
 		return nil, err
@@ -280,7 +349,7 @@ func buildEndpointMap(db *sql.DB, serviceID int64) (map[string]int64, error) {
 	return m, rows.Err()
 }
 
-func getExistingRequestsByEndpoint(db *sql.DB, serviceID int64) (map[int64]map[string]int64, error) {
+func getExistingRequestsByEndpoint(db store.Querier, serviceID int64) (map[int64]map[string]int64, error) {
 	query := `
 		SELECT sr.id, sr.endpoint_id, sr.name
 		FROM saved_requests sr
@@ -334,14 +403,13 @@ func ImportRepoSavedRequests(db *sql.DB, repoID int64) (map[string]*ImportResult
 		return nil, fmt.Errorf("failed to get services: %w", err)
 	}
 
-	repoPath, err := GetRepoPath(db, repoID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get repo path: %w", err)
-	}
-
 	results := make(map[string]*ImportResult)
 	for _, svc := range services {
-		filePath := filepath.Join(repoPath, "services", svc.ServiceID, FileName)
+		_, svcPath, err := GetServicePath(db, svc.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service path: %w", err)
+		}
+		filePath := filepath.Join(svcPath, FileName)
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			continue
 		}
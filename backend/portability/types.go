@@ -1,5 +1,10 @@
 package portability
 
+import (
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/extract"
+)
+
 type QueryParam struct {
 	Key     string `yaml:"key"`
 	Value   string `yaml:"value"`
@@ -18,12 +23,14 @@ type EndpointRef struct {
 }
 
 type PortableSavedRequest struct {
-	Name        string            `yaml:"name"`
-	Endpoint    EndpointRef       `yaml:"endpoint"`
-	PathParams  map[string]string `yaml:"path_params,omitempty"`
-	QueryParams []QueryParam      `yaml:"query_params,omitempty"`
-	Headers     []Header          `yaml:"headers,omitempty"`
-	Body        string            `yaml:"body,omitempty"`
+	Name        string                 `yaml:"name"`
+	Endpoint    EndpointRef            `yaml:"endpoint"`
+	PathParams  map[string]string      `yaml:"path_params,omitempty"`
+	QueryParams []QueryParam           `yaml:"query_params,omitempty"`
+	Headers     []Header               `yaml:"headers,omitempty"`
+	Body        string                 `yaml:"body,omitempty"`
+	Extractions []extract.Rule         `yaml:"extractions,omitempty"`
+	Assertions  []assertions.Assertion `yaml:"assertions,omitempty"`
 }
 
 type SavedRequestsFile struct {
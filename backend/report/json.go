@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/runner"
+)
+
+// jsonVersion is bumped on incompatible changes to the JSON report layout
+const jsonVersion = 1
+
+type jsonReport struct {
+	Version     int               `json:"version"`
+	Name        string            `json:"name"`
+	Target      string            `json:"target"`
+	Environment string            `json:"environment"`
+	Properties  map[string]string `json:"properties"`
+	StartedAt   string            `json:"startedAt"`
+	FinishedAt  string            `json:"finishedAt"`
+	DurationMs  int64             `json:"durationMs"`
+	Totals      jsonTotals        `json:"totals"`
+	Results     []jsonResult      `json:"results"`
+}
+
+type jsonTotals struct {
+	Total     int  `json:"total"`
+	Passed    int  `json:"passed"`
+	Failed    int  `json:"failed"`
+	Skipped   int  `json:"skipped"`
+	Cancelled bool `json:"cancelled"`
+}
+
+type jsonResult struct {
+	Name       string              `json:"name"`
+	ServiceID  string              `json:"serviceId"`
+	Status     string              `json:"status"` // passed, failed, error or skipped
+	DurationMs int64               `json:"durationMs"`
+	Error      string              `json:"error,omitempty"`
+	Request    jsonRequest         `json:"request"`
+	Response   *jsonResponse       `json:"response,omitempty"`
+	Assertions []assertions.Result `json:"assertions"`
+}
+
+type jsonRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+type jsonResponse struct {
+	StatusCode int                 `json:"statusCode"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+}
+
+// writeJSON renders the run in a stable, versioned JSON layout
+func writeJSON(w io.Writer, summary runner.Summary, meta Metadata) error {
+	properties := meta.Properties
+	if properties == nil {
+		properties = map[string]string{}
+	}
+
+	out := jsonReport{
+		Version:     jsonVersion,
+		Name:        meta.Name,
+		Target:      meta.Target,
+		Environment: meta.Environment,
+		Properties:  properties,
+		StartedAt:   summary.StartedAt.UTC().Format(time.RFC3339),
+		FinishedAt:  summary.FinishedAt.UTC().Format(time.RFC3339),
+		DurationMs:  summary.DurationMs,
+		Totals: jsonTotals{
+			Total:     summary.Total,
+			Passed:    summary.Passed,
+			Failed:    summary.Failed,
+			Skipped:   summary.Skipped,
+			Cancelled: summary.Cancelled,
+		},
+		Results: make([]jsonResult, 0, len(summary.Results)),
+	}
+
+	for _, result := range summary.Results {
+		r := jsonResult{
+			Name:       result.Name,
+			ServiceID:  result.ServiceID,
+			Status:     status(result),
+			DurationMs: result.ResponseTime,
+			Error:      result.Error,
+			Request: jsonRequest{
+				Method:  result.Method,
+				URL:     result.URL,
+				Headers: redactHeaders(result.RequestHeaders),
+				Body:    snippet(result.RequestBody),
+			},
+			Assertions: result.Assertions,
+		}
+		if r.Request.Headers == nil {
+			r.Request.Headers = map[string]string{}
+		}
+		if r.Assertions == nil {
+			r.Assertions = []assertions.Result{}
+		}
+		if result.StatusCode != 0 {
+			r.Response = &jsonResponse{
+				StatusCode: result.StatusCode,
+				Headers:    redactHeaderValues(result.ResponseHeaders),
+				Body:       snippet(result.ResponseBody),
+			}
+		}
+		out.Results = append(out.Results, r)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/triplewhale/postwhale/runner"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit renders one testsuite per service and one testcase per saved request
+func writeJUnit(w io.Writer, summary runner.Summary, meta Metadata) error {
+	properties := []junitProperty{
+		{Name: "environment", Value: meta.Environment},
+		{Name: "target", Value: meta.Target},
+	}
+	for _, k := range sortedKeys(meta.Properties) {
+		properties = append(properties, junitProperty{Name: k, Value: meta.Properties[k]})
+	}
+
+	suites := junitTestSuites{
+		Name: meta.Name,
+		Time: seconds(summary.DurationMs),
+	}
+	index := map[string]int{}
+	suiteMs := map[int]int64{}
+
+	for _, result := range summary.Results {
+		i, ok := index[result.ServiceID]
+		if !ok {
+			i = len(suites.Suites)
+			index[result.ServiceID] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{
+				Name:       result.ServiceID,
+				Timestamp:  summary.StartedAt.UTC().Format(time.RFC3339),
+				Properties: properties,
+			})
+		}
+		suite := &suites.Suites[i]
+
+		tc := junitTestCase{
+			Name:      result.Name,
+			ClassName: result.ServiceID,
+			Time:      seconds(result.ResponseTime),
+		}
+		if !result.Skipped {
+			tc.SystemOut = exchange(result)
+		}
+
+		switch status(result) {
+		case "skipped":
+			tc.Skipped = &junitMessage{Message: "not run"}
+			suite.Skipped++
+		case "error":
+			tc.Error = &junitMessage{Message: result.Error, Type: "error"}
+			suite.Errors++
+		case "failed":
+			messages := failureMessages(result)
+			tc.Failure = &junitMessage{Message: firstLine(messages), Type: "assertion", Body: strings.Join(messages, "\n")}
+			suite.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+		suiteMs[i] += result.ResponseTime
+	}
+
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.Time = seconds(suiteMs[i])
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats milliseconds as fractional seconds, as JUnit expects
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// firstLine returns the first message, or a generic one
+func firstLine(messages []string) string {
+	if len(messages) == 0 {
+		return "assertion failed"
+	}
+	return messages[0]
+}
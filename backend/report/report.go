@@ -0,0 +1,190 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/triplewhale/postwhale/runner"
+)
+
+// Report formats
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+	FormatTAP   = "tap"
+)
+
+// Formats lists the supported report formats
+var Formats = []string{FormatJUnit, FormatJSON, FormatTAP}
+
+// snippetLimit caps request and response bodies included in reports
+const snippetLimit = 2048
+
+// sensitiveHeaders are headers whose values are redacted from reports, which
+// are often uploaded as CI artifacts. Keys are lowercase.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+// redacted replaces the value of a sensitive header
+const redacted = "[REDACTED]"
+
+// Metadata describes where and against what a run executed
+type Metadata struct {
+	Name        string            // report title, e.g. the repository or collection name
+	Target      string            // what was run, e.g. service:fusion
+	Environment string            // environment name
+	Properties  map[string]string // extra key/value pairs, e.g. base URL or git commit
+}
+
+// Write renders a run summary in the given format
+func Write(w io.Writer, format string, summary runner.Summary, meta Metadata) error {
+	switch format {
+	case FormatJUnit:
+		return writeJUnit(w, summary, meta)
+	case FormatJSON:
+		return writeJSON(w, summary, meta)
+	case FormatTAP:
+		return writeTAP(w, summary, meta)
+	default:
+		return fmt.Errorf("unknown report format: %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Extension returns the conventional file extension for a format
+func Extension(format string) string {
+	switch format {
+	case FormatJUnit:
+		return ".xml"
+	case FormatJSON:
+		return ".json"
+	case FormatTAP:
+		return ".tap"
+	default:
+		return ""
+	}
+}
+
+// status classifies a result as passed, failed, error or skipped.
+// Errors are requests that never produced a checkable response.
+func status(result runner.Result) string {
+	switch {
+	case result.Skipped:
+		return "skipped"
+	case result.Passed:
+		return "passed"
+	case result.Error != "":
+		return "error"
+	default:
+		return "failed"
+	}
+}
+
+// failureMessages lists the messages of every failed assertion
+func failureMessages(result runner.Result) []string {
+	messages := []string{}
+	for _, a := range result.Assertions {
+		if a.Passed {
+			continue
+		}
+		msg := a.Message
+		if a.Path != "" {
+			msg = fmt.Sprintf("%s %s: %s", a.Type, a.Path, a.Message)
+		} else if msg == "" {
+			msg = a.Type
+		}
+		messages = append(messages, msg)
+		for _, issue := range a.Issues {
+			messages = append(messages, fmt.Sprintf("  %s: %s", issue.Pointer, issue.Message))
+		}
+	}
+	return messages
+}
+
+// snippet truncates a body for inclusion in a report
+func snippet(body string) string {
+	if len(body) <= snippetLimit {
+		return body
+	}
+	cut := snippetLimit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut] + fmt.Sprintf("... (%d bytes truncated)", len(body)-cut)
+}
+
+// sortedKeys returns map keys in sorted order so output is stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// redactHeaders returns a copy of request headers with sensitive values redacted
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			v = redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactHeaderValues returns a copy of response headers with sensitive values redacted
+func redactHeaderValues(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string][]string, len(headers))
+	for k, v := range headers {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			v = []string{redacted}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// exchange renders the request and response of a result as plain text
+func exchange(result runner.Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", result.Method, result.URL)
+	requestHeaders := redactHeaders(result.RequestHeaders)
+	for _, k := range sortedKeys(requestHeaders) {
+		fmt.Fprintf(&b, "%s: %s\n", k, requestHeaders[k])
+	}
+	if result.RequestBody != "" {
+		fmt.Fprintf(&b, "\n%s\n", snippet(result.RequestBody))
+	}
+	if result.StatusCode != 0 {
+		fmt.Fprintf(&b, "\n--> %d (%dms)\n", result.StatusCode, result.ResponseTime)
+		responseHeaders := redactHeaderValues(result.ResponseHeaders)
+		headerNames := make([]string, 0, len(responseHeaders))
+		for k := range responseHeaders {
+			headerNames = append(headerNames, k)
+		}
+		sort.Strings(headerNames)
+		for _, k := range headerNames {
+			fmt.Fprintf(&b, "%s: %s\n", k, strings.Join(responseHeaders[k], ", "))
+		}
+		if result.ResponseBody != "" {
+			fmt.Fprintf(&b, "\n%s\n", snippet(result.ResponseBody))
+		}
+	}
+	return b.String()
+}
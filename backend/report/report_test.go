@@ -0,0 +1,180 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/runner"
+	"github.com/triplewhale/postwhale/validation"
+)
+
+func testSummary() runner.Summary {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return runner.Summary{
+		Total:      3,
+		Passed:     1,
+		Failed:     1,
+		Skipped:    1,
+		StartedAt:  start,
+		FinishedAt: start.Add(300 * time.Millisecond),
+		DurationMs: 300,
+		Results: []runner.Result{
+			{
+				Index: 0, Name: "Create order", ServiceID: "fusion", Method: "POST", URL: "http://fusion/orders",
+				RequestHeaders: map[string]string{"X-Trace": "1"}, RequestBody: `{"qty": 1}`,
+				StatusCode: 201, ResponseBody: `{"id": "ord_1"}`, ResponseTime: 120, Passed: true,
+				Assertions: []assertions.Result{{Type: assertions.TypeStatusEquals, Passed: true, Actual: "201"}},
+			},
+			{
+				Index: 1, Name: "Get order", ServiceID: "fusion", Method: "GET", URL: "http://fusion/orders/ord_1",
+				StatusCode: 200, ResponseBody: `{"total": "12"}`, ResponseTime: 80,
+				Assertions: []assertions.Result{{
+					Type: assertions.TypeMatchesSchema, Message: "response body has 1 schema violation(s)",
+					Issues: []validation.Issue{{Pointer: "/total", Message: "expected number, got string"}},
+				}},
+			},
+			{Index: 2, Name: "List shops", ServiceID: "shops", Method: "GET", Skipped: true},
+		},
+	}
+}
+
+func testMetadata() Metadata {
+	return Metadata{Name: "backend", Target: "repo:1", Environment: "STAGING", Properties: map[string]string{"baseUrl": "http://fusion"}}
+}
+
+func TestWrite_JUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJUnit, testSummary(), testMetadata()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if parsed.Tests != 3 || parsed.Failures != 1 || parsed.Skipped != 1 || len(parsed.Suites) != 2 {
+		t.Fatalf("Unexpected totals: %+v", parsed)
+	}
+
+	fusion := parsed.Suites[0]
+	if fusion.Name != "fusion" || len(fusion.Cases) != 2 || fusion.Time != "0.200" {
+		t.Errorf("Unexpected fusion suite: %+v", fusion)
+	}
+	failure := fusion.Cases[1].Failure
+	if failure == nil || !strings.Contains(failure.Body, "/total: expected number, got string") {
+		t.Errorf("Expected schema issue in failure, got %+v", failure)
+	}
+	if !strings.Contains(fusion.Cases[0].SystemOut, "X-Trace: 1") {
+		t.Errorf("Expected request snippet in system-out, got %q", fusion.Cases[0].SystemOut)
+	}
+	if len(fusion.Properties) != 3 || fusion.Properties[0].Value != "STAGING" {
+		t.Errorf("Expected environment properties, got %+v", fusion.Properties)
+	}
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, testSummary(), testMetadata()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var parsed jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if parsed.Version != 1 || parsed.Environment != "STAGING" || parsed.Totals.Failed != 1 {
+		t.Errorf("Unexpected report header: %+v", parsed)
+	}
+	statuses := []string{parsed.Results[0].Status, parsed.Results[1].Status, parsed.Results[2].Status}
+	if strings.Join(statuses, ",") != "passed,failed,skipped" {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+	if parsed.Results[2].Response != nil {
+		t.Error("Expected no response for a skipped request")
+	}
+}
+
+func TestWrite_TAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatTAP, testSummary(), testMetadata()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"TAP version 13\n1..3\n",
+		"# environment: STAGING\n",
+		"ok 1 - fusion: Create order # time=120ms\n",
+		"not ok 2 - fusion: Get order # time=80ms\n",
+		`    - "/total: expected number, got string"`,
+		"ok 3 - shops: List shops # SKIP not run\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected TAP output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWrite_TAPEscapes(t *testing.T) {
+	summary := runner.Summary{Total: 1, Failed: 1, Results: []runner.Result{{
+		Name: "Order #1 \\ retry", ServiceID: "fusion", Method: "GET", URL: "http://fusion/orders",
+		Error: `bad path C:\orders #1`,
+	}}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatTAP, summary, testMetadata()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"not ok 1 - fusion: Order \\#1 \\\\ retry # time=0ms\n",
+		`  message: "bad path C:\\orders #1"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected TAP output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWrite_RedactsSensitiveHeaders(t *testing.T) {
+	summary := testSummary()
+	summary.Results[1].RequestHeaders = map[string]string{"authorization": "Bearer s3cret", "X-Api-Key": "s3cret", "X-Trace": "2"}
+	summary.Results[1].ResponseHeaders = map[string][]string{"Set-Cookie": {"session=s3cret"}}
+
+	for _, format := range []string{FormatJUnit, FormatJSON} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, summary, testMetadata()); err != nil {
+			t.Fatalf("Write %s failed: %v", format, err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "s3cret") {
+			t.Errorf("Expected %s report to redact secrets, got:\n%s", format, out)
+		}
+		if !strings.Contains(out, redacted) || !strings.Contains(out, "X-Trace") {
+			t.Errorf("Expected %s report to keep header names, got:\n%s", format, out)
+		}
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "html", testSummary(), testMetadata()); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("é", snippetLimit)
+	got := snippet(long)
+	if !strings.Contains(got, "bytes truncated") || !strings.HasPrefix(got, "é") {
+		t.Errorf("Expected truncated snippet, got %q", got[:20])
+	}
+	if snippet("short") != "short" {
+		t.Error("Expected short bodies to be untouched")
+	}
+}
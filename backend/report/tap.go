@@ -0,0 +1,81 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/triplewhale/postwhale/runner"
+)
+
+// writeTAP renders the run as TAP version 13, with failure details in YAML blocks
+func writeTAP(w io.Writer, summary runner.Summary, meta Metadata) error {
+	var b strings.Builder
+
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(summary.Results))
+	if meta.Name != "" {
+		fmt.Fprintf(&b, "# %s\n", meta.Name)
+	}
+	fmt.Fprintf(&b, "# target: %s\n", meta.Target)
+	fmt.Fprintf(&b, "# environment: %s\n", meta.Environment)
+	for _, k := range sortedKeys(meta.Properties) {
+		fmt.Fprintf(&b, "# %s: %s\n", k, meta.Properties[k])
+	}
+
+	for i, result := range summary.Results {
+		description := tapEscape(fmt.Sprintf("%s: %s", result.ServiceID, result.Name))
+
+		switch status(result) {
+		case "skipped":
+			fmt.Fprintf(&b, "ok %d - %s # SKIP not run\n", i+1, description)
+			continue
+		case "passed":
+			fmt.Fprintf(&b, "ok %d - %s # time=%dms\n", i+1, description, result.ResponseTime)
+			continue
+		}
+
+		fmt.Fprintf(&b, "not ok %d - %s # time=%dms\n", i+1, description, result.ResponseTime)
+		b.WriteString("  ---\n")
+		if result.Error != "" {
+			fmt.Fprintf(&b, "  message: %s\n", yamlString(result.Error))
+		} else {
+			b.WriteString("  message: assertion failed\n")
+		}
+		fmt.Fprintf(&b, "  method: %s\n", result.Method)
+		fmt.Fprintf(&b, "  url: %s\n", yamlString(result.URL))
+		if result.StatusCode != 0 {
+			fmt.Fprintf(&b, "  status: %d\n", result.StatusCode)
+		}
+		if failures := failureMessages(result); len(failures) > 0 {
+			b.WriteString("  failures:\n")
+			for _, msg := range failures {
+				fmt.Fprintf(&b, "    - %s\n", yamlString(strings.TrimSpace(msg)))
+			}
+		}
+		if result.ResponseBody != "" {
+			b.WriteString("  response: |\n")
+			for _, line := range strings.Split(strings.TrimRight(snippet(result.ResponseBody), "\n"), "\n") {
+				fmt.Fprintf(&b, "    %s\n", line)
+			}
+		}
+		b.WriteString("  ...\n")
+	}
+
+	fmt.Fprintf(&b, "# passed %d, failed %d, skipped %d\n", summary.Passed, summary.Failed, summary.Skipped)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tapEscape escapes a test description so a '#' in it doesn't start a
+// directive and it stays on one line
+func tapEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "#", `\#`, "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// yamlString quotes a scalar for the TAP YAML block. Inside the quotes '#'
+// isn't a comment and '\' is escaped, so messages need nothing more.
+func yamlString(s string) string {
+	return fmt.Sprintf("%q", s)
+}
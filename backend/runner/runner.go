@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/discovery"
+	"github.com/triplewhale/postwhale/extract"
+	"github.com/triplewhale/postwhale/templating"
+)
+
+// Item is one saved request queued for a run
+type Item struct {
+	SavedRequestID int64 // 0 when the request doesn't come from the database
+	EndpointID     int64
+	Name           string
+	ServiceID      string
+	Port           int
+	Method         string
+	Path           string
+	PathParams     map[string]string
+	QueryParams    []templating.QueryParam
+	Headers        map[string]string
+	Body           string
+	Extractions    []extract.Rule
+	Assertions     []assertions.Assertion
+	Spec           *discovery.APIEndpoint // documented operation, used by matchesSchema; may be nil
+}
+
+// Options control how a run executes
+type Options struct {
+	StopOnFailure bool
+	Delay         time.Duration // pause before each request after the first, per worker
+	Concurrency   int           // number of requests in flight; values below 1 mean 1
+}
+
+// Result is the outcome of one item
+type Result struct {
+	Index           int                 `json:"index"`
+	SavedRequestID  int64               `json:"savedRequestId,omitempty"`
+	Name            string              `json:"name"`
+	ServiceID       string              `json:"serviceId"`
+	Method          string              `json:"method"`
+	URL             string              `json:"url,omitempty"`
+	RequestHeaders  map[string]string   `json:"requestHeaders,omitempty"`
+	RequestBody     string              `json:"requestBody,omitempty"`
+	StatusCode      int                 `json:"statusCode,omitempty"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string              `json:"responseBody,omitempty"`
+	ResponseTime    int64               `json:"responseTime"` // milliseconds
+	Passed          bool                `json:"passed"`
+	Skipped         bool                `json:"skipped,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Assertions      []assertions.Result `json:"assertions,omitempty"`
+	Extractions     []extract.Result    `json:"extractions,omitempty"`
+	StartedAt       time.Time           `json:"startedAt"`
+}
+
+// Summary is the outcome of a whole run
+type Summary struct {
+	Total      int       `json:"total"`
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	Cancelled  bool      `json:"cancelled,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Results    []Result  `json:"results"`
+}
+
+// Success reports whether every item ran and passed
+func (s Summary) Success() bool {
+	return s.Failed == 0 && s.Skipped == 0 && !s.Cancelled
+}
+
+// Event types reported through Runner.Progress
+const (
+	EventStarted  = "started"
+	EventFinished = "finished"
+)
+
+// Event reports progress on a single item
+type Event struct {
+	Type   string  `json:"type"`
+	Index  int     `json:"index"`
+	Total  int     `json:"total"`
+	Name   string  `json:"name"`
+	Result *Result `json:"result,omitempty"` // set on finished
+}
+
+// Runner executes items, chaining variables through its callbacks
+type Runner struct {
+	// Prepare resolves an item's target and {{variables}} into a request.
+	// It is called right before the item is sent, so it sees values extracted by earlier items.
+	Prepare func(item Item) (client.RequestConfig, error)
+	// Extract applies the item's extraction rules and stores the values; may be nil
+	Extract func(item Item, response client.Response) []extract.Result
+	// Progress receives an event as each item starts and finishes; may be nil
+	Progress func(event Event)
+}
+
+// Run executes items in order and returns the summary.
+// With concurrency above 1, items start in order but may finish out of order.
+func (r *Runner) Run(ctx context.Context, items []Item, opts Options) Summary {
+	summary := Summary{
+		Total:     len(items),
+		StartedAt: time.Now(),
+		Results:   make([]Result, len(items)),
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	failed := false
+	next := 0
+
+	// claim hands out the next item index, or -1 once the run should stop
+	claim := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= len(items) || ctx.Err() != nil || (opts.StopOnFailure && failed) {
+			return -1
+		}
+		i := next
+		next++
+		return i
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first := true
+			for {
+				i := claim()
+				if i < 0 {
+					return
+				}
+				if !first && opts.Delay > 0 {
+					select {
+					case <-time.After(opts.Delay):
+					case <-ctx.Done():
+					}
+				}
+				first = false
+
+				result := r.runItem(ctx, i, items[i], len(items))
+
+				mu.Lock()
+				summary.Results[i] = result
+				if !result.Passed && !result.Skipped {
+					failed = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	summary.Cancelled = ctx.Err() != nil
+
+	// Anything never started was skipped
+	for i := next; i < len(items); i++ {
+		summary.Results[i] = skippedResult(i, items[i])
+	}
+
+	for _, result := range summary.Results {
+		switch {
+		case result.Skipped:
+			summary.Skipped++
+		case result.Passed:
+			summary.Passed++
+		default:
+			summary.Failed++
+		}
+	}
+
+	summary.FinishedAt = time.Now()
+	summary.DurationMs = summary.FinishedAt.Sub(summary.StartedAt).Milliseconds()
+	return summary
+}
+
+// runItem prepares, sends and checks a single item
+func (r *Runner) runItem(ctx context.Context, index int, item Item, total int) Result {
+	r.emit(Event{Type: EventStarted, Index: index, Total: total, Name: item.Name})
+
+	result := Result{
+		Index:          index,
+		SavedRequestID: item.SavedRequestID,
+		Name:           item.Name,
+		ServiceID:      item.ServiceID,
+		Method:         item.Method,
+		StartedAt:      time.Now(),
+	}
+
+	config, err := r.Prepare(item)
+	result.URL = client.BuildURL(config)
+	result.RequestHeaders = config.Headers
+	result.RequestBody = config.Body
+	if err != nil {
+		result.Error = err.Error()
+		r.emit(Event{Type: EventFinished, Index: index, Total: total, Name: item.Name, Result: &result})
+		return result
+	}
+
+	response := client.ExecuteRequest(ctx, config)
+	result.StatusCode = response.StatusCode
+	result.ResponseHeaders = response.Headers
+	result.ResponseBody = response.Body
+	result.ResponseTime = response.ResponseTime.Milliseconds()
+
+	switch {
+	case response.Cancelled:
+		result.Skipped = true
+		result.Error = response.Error
+	case response.Error != "":
+		result.Error = response.Error
+		result.Assertions = assertions.Evaluate(item.Assertions, response, item.Spec)
+	default:
+		if r.Extract != nil && len(item.Extractions) > 0 {
+			result.Extractions = r.Extract(item, response)
+		}
+		result.Assertions = assertions.Evaluate(item.Assertions, response, item.Spec)
+		result.Passed = assertions.Passed(result.Assertions)
+	}
+
+	r.emit(Event{Type: EventFinished, Index: index, Total: total, Name: item.Name, Result: &result})
+	return result
+}
+
+// emit forwards an event to the progress callback, if any
+func (r *Runner) emit(event Event) {
+	if r.Progress != nil {
+		r.Progress(event)
+	}
+}
+
+// skippedResult records an item that never ran
+func skippedResult(index int, item Item) Result {
+	return Result{
+		Index:          index,
+		SavedRequestID: item.SavedRequestID,
+		Name:           item.Name,
+		ServiceID:      item.ServiceID,
+		Method:         item.Method,
+		Skipped:        true,
+	}
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/triplewhale/postwhale/assertions"
+	"github.com/triplewhale/postwhale/client"
+	"github.com/triplewhale/postwhale/extract"
+	"github.com/triplewhale/postwhale/templating"
+)
+
+// memoryRunner chains variables through an in-memory map
+func memoryRunner(baseURL string) (*Runner, map[string]string) {
+	vars := map[string]string{}
+	var mu sync.Mutex
+	r := &Runner{
+		Prepare: func(item Item) (client.RequestConfig, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			resolved, err := templating.NewResolver(templating.Scope{Name: "global", Vars: vars}).ResolveRequest(templating.Request{
+				Path:    item.Path,
+				Headers: item.Headers,
+				Body:    item.Body,
+			})
+			return client.RequestConfig{
+				BaseURL:  baseURL,
+				Endpoint: resolved.URL(),
+				Method:   item.Method,
+				Headers:  resolved.Headers,
+				Body:     resolved.Body,
+				Timeout:  5 * time.Second,
+			}, err
+		},
+		Extract: func(item Item, response client.Response) []extract.Result {
+			mu.Lock()
+			defer mu.Unlock()
+			results := extract.Apply(item.Extractions, response, "global")
+			for _, r := range results {
+				if r.OK {
+					vars[r.Variable] = r.Value
+				}
+			}
+			return results
+		},
+	}
+	return r, vars
+}
+
+func TestRun_ChainsVariables(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/orders":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "ord_1"}`))
+		case r.URL.Path == "/orders/ord_1":
+			w.Write([]byte(`{"id": "ord_1", "status": "open"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r, _ := memoryRunner(server.URL)
+	var events []Event
+	r.Progress = func(e Event) { events = append(events, e) }
+
+	items := []Item{
+		{
+			Name:        "Create",
+			Method:      "POST",
+			Path:        "/orders",
+			Extractions: []extract.Rule{{Variable: "orderId", Source: extract.SourceBody, Path: "$.id"}},
+			Assertions:  []assertions.Assertion{{Type: assertions.TypeStatusEquals, Expected: "201"}},
+		},
+		{
+			Name:       "Get",
+			Method:     "GET",
+			Path:       "/orders/{{orderId}}",
+			Assertions: []assertions.Assertion{{Type: assertions.TypeJSONPathEquals, Path: "$.status", Expected: "open"}},
+		},
+	}
+
+	summary := r.Run(context.Background(), items, Options{})
+
+	if !summary.Success() || summary.Passed != 2 {
+		t.Fatalf("Expected both items to pass, got %+v", summary)
+	}
+	if !strings.HasSuffix(summary.Results[1].URL, "/orders/ord_1") {
+		t.Errorf("Expected chained URL, got %s", summary.Results[1].URL)
+	}
+	if len(events) != 4 || events[0].Type != EventStarted || events[3].Type != EventFinished || events[3].Result == nil {
+		t.Errorf("Unexpected progress events: %+v", events)
+	}
+}
+
+func TestRun_StopOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	r, _ := memoryRunner(server.URL)
+	failing := Item{
+		Name:       "Fails",
+		Method:     "GET",
+		Path:       "/",
+		Assertions: []assertions.Assertion{{Type: assertions.TypeStatusInRange, Min: 200, Max: 299}},
+	}
+
+	summary := r.Run(context.Background(), []Item{failing, failing, failing}, Options{StopOnFailure: true})
+	if summary.Failed != 1 || summary.Skipped != 2 {
+		t.Errorf("Expected 1 failure and 2 skipped, got %+v", summary)
+	}
+
+	summary = r.Run(context.Background(), []Item{failing, failing, failing}, Options{})
+	if summary.Failed != 3 || summary.Skipped != 0 {
+		t.Errorf("Expected 3 failures without stop-on-failure, got %+v", summary)
+	}
+}
+
+func TestRun_UnresolvedVariableFails(t *testing.T) {
+	r, _ := memoryRunner("http://127.0.0.1:1")
+
+	summary := r.Run(context.Background(), []Item{{Name: "Missing", Method: "GET", Path: "/{{nope}}"}}, Options{})
+	if summary.Failed != 1 || !strings.Contains(summary.Results[0].Error, "nope") {
+		t.Errorf("Expected unresolved variable failure, got %+v", summary.Results[0])
+	}
+}
+
+func TestRun_Concurrency(t *testing.T) {
+	var inflight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&inflight, -1)
+	}))
+	defer server.Close()
+
+	r, _ := memoryRunner(server.URL)
+	items := make([]Item, 6)
+	for i := range items {
+		items[i] = Item{Name: "Ping", Method: "GET", Path: "/"}
+	}
+
+	summary := r.Run(context.Background(), items, Options{Concurrency: 3})
+	if summary.Passed != 6 {
+		t.Fatalf("Expected 6 passes, got %+v", summary)
+	}
+	if peak := atomic.LoadInt32(&peak); peak < 2 || peak > 3 {
+		t.Errorf("Expected between 2 and 3 concurrent requests, got %d", peak)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	r, _ := memoryRunner(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Progress = func(e Event) {
+		if e.Type == EventFinished {
+			cancel()
+		}
+	}
+
+	items := []Item{{Name: "A", Method: "GET", Path: "/"}, {Name: "B", Method: "GET", Path: "/"}}
+	summary := r.Run(ctx, items, Options{})
+
+	if !summary.Cancelled || summary.Passed != 1 || summary.Skipped != 1 {
+		t.Errorf("Expected cancelled run with 1 pass and 1 skip, got %+v", summary)
+	}
+}
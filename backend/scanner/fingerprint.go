@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileStamp identifies the content of a file a service was scanned from
+type FileStamp struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"` // unix nanoseconds
+	Hash    string `json:"hash"`    // sha256 of the content
+}
+
+// Fingerprint maps the files a service was scanned from (tw-config.json,
+// spec files, the files their $refs point to and the repository's
+// postwhale.config.yml), relative to the service directory, to their stamps
+type Fingerprint map[string]FileStamp
+
+// Equal reports whether two fingerprints cover the same files with the same
+// content. Modification times are ignored so touching a file doesn't count.
+func (f Fingerprint) Equal(other Fingerprint) bool {
+	if len(f) != len(other) {
+		return false
+	}
+	for file, stamp := range f {
+		o, ok := other[file]
+		if !ok || o.Hash != stamp.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Files returns the fingerprinted files in sorted order
+func (f Fingerprint) Files() []string {
+	files := make([]string, 0, len(f))
+	for file := range f {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// stampFiles stamps files relative to dir. Hashes are reused from previous
+// when a file's size and modification time are unchanged, so only edited
+// files are read. Files that can't be read are left out.
+func stampFiles(dir string, files []string, previous Fingerprint) Fingerprint {
+	fp := Fingerprint{}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		stamp := FileStamp{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		if prev, ok := previous[file]; ok && prev.Size == stamp.Size && prev.ModTime == stamp.ModTime {
+			stamp.Hash = prev.Hash
+		} else {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			sum := sha256.Sum256(data)
+			stamp.Hash = hex.EncodeToString(sum[:])
+		}
+		fp[file] = stamp
+	}
+	return fp
+}
+
+// serviceFiles lists the files to check for a service: its config, the
+// repository's layout file, the spec files currently present and anything
+// the previous scan read
+func serviceFiles(repoPath, servicePath string, layout Layout, previous Fingerprint) []string {
+	config := filepath.Base(layout.configPath(servicePath))
+	layoutFile := layoutFileFrom(repoPath, servicePath)
+	seen := map[string]bool{config: true, layoutFile: true}
+	files := []string{config, layoutFile}
+	for _, specPath := range findSpecFiles(servicePath, layout.SpecPatterns) {
+		file, _ := filepath.Rel(servicePath, specPath)
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	for _, file := range previous.Files() {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// layoutFileFrom returns the repository's postwhale.config.yml relative to a
+// service directory, so a change to the layout invalidates every service
+func layoutFileFrom(repoPath, servicePath string) string {
+	rel, err := filepath.Rel(servicePath, filepath.Join(repoPath, LayoutFile))
+	if err != nil {
+		return filepath.Join(repoPath, LayoutFile)
+	}
+	return rel
+}
+
+// WatchFiles lists the files that make up a repository's services: the
+// repository's postwhale.config.yml, each service's config, the spec files
+// beside it and whatever the previous scan read through $refs. stored is the
+// layout stored for the repository. Paths are joined to repoPath.
+func WatchFiles(repoPath string, stored Layout, previous map[string]Fingerprint) []string {
+	files := []string{filepath.Join(repoPath, LayoutFile)}
+
+	layout, err := ResolveLayout(repoPath, stored)
+	if err != nil {
+		return files
+	}
+
+	for _, dir := range layout.serviceDirs(repoPath) {
+		servicePath := filepath.Join(repoPath, filepath.FromSlash(dir))
+		for _, file := range serviceFiles(repoPath, servicePath, layout, previous[dir]) {
+			files = append(files, filepath.Join(servicePath, file))
+		}
+	}
+	return files
+}
@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const fingerprintSpec = `openapi: 3.0.0
+info:
+  title: Orders
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200":
+          $ref: './common.yaml#/responses/Ok'
+`
+
+func writeService(t *testing.T, repoPath, name string, files map[string]string) string {
+	t.Helper()
+	servicePath := filepath.Join(repoPath, "services", name)
+	if err := os.MkdirAll(servicePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(servicePath, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return servicePath
+}
+
+func previousOf(result ScanResult) map[string]Fingerprint {
+	previous := map[string]Fingerprint{}
+	for _, svc := range result.Services {
+		previous[svc.Dir] = svc.Fingerprint
+	}
+	return previous
+}
+
+func TestScanRepositoryWith_SkipsUnchangedServices(t *testing.T) {
+	repoPath := t.TempDir()
+	ordersPath := writeService(t, repoPath, "orders", map[string]string{
+		"tw-config.json": `{"serviceId": "orders", "env": {"PORT": 8080}}`,
+		"openapi.yaml":   fingerprintSpec,
+		"common.yaml":    "responses:\n  Ok:\n    description: ok\n",
+	})
+	writeService(t, repoPath, "billing", map[string]string{
+		"tw-config.json": `{"serviceId": "billing", "env": {"PORT": 8081}}`,
+	})
+
+	first := ScanRepository(repoPath)
+	if len(first.Services) != 2 {
+		t.Fatalf("Expected 2 services, got %d", len(first.Services))
+	}
+	orders := first.Services[1]
+	if orders.Dir != "services/orders" {
+		t.Fatalf("Expected services in directory order, got %q", orders.Dir)
+	}
+	if files := orders.Fingerprint.Files(); !reflect.DeepEqual(files, []string{"common.yaml", "openapi.yaml", "tw-config.json"}) {
+		t.Errorf("Expected config, spec and referenced file in the fingerprint, got %v", files)
+	}
+
+	// Nothing changed, or only the modification time
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(ordersPath, "openapi.yaml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	second := ScanRepositoryWith(repoPath, ScanOptions{Previous: previousOf(first)})
+	for _, svc := range second.Services {
+		if !svc.Unchanged {
+			t.Errorf("Expected %s to be unchanged", svc.Dir)
+		}
+	}
+
+	// Editing a file the spec references re-parses just that service
+	if err := os.WriteFile(filepath.Join(ordersPath, "common.yaml"), []byte("responses:\n  Ok:\n    description: all good\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third := ScanRepositoryWith(repoPath, ScanOptions{Previous: previousOf(second), Workers: 1})
+	if !third.Services[0].Unchanged {
+		t.Error("Expected billing to be unchanged")
+	}
+	if third.Services[1].Unchanged || third.Services[1].Endpoints[0].Responses["200"].Description != "all good" {
+		t.Errorf("Expected orders to be re-parsed, got %+v", third.Services[1])
+	}
+
+	// Removing a spec file is a change too
+	if err := os.Remove(filepath.Join(ordersPath, "openapi.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	fourth := ScanRepositoryWith(repoPath, ScanOptions{Previous: previousOf(third)})
+	if fourth.Services[1].Unchanged || len(fourth.Services[1].Endpoints) != 0 {
+		t.Errorf("Expected orders to be re-parsed without endpoints, got %+v", fourth.Services[1])
+	}
+}
+
+func TestFingerprint_Equal(t *testing.T) {
+	a := Fingerprint{"openapi.yaml": {Size: 10, ModTime: 1, Hash: "abc"}}
+	b := Fingerprint{"openapi.yaml": {Size: 10, ModTime: 2, Hash: "abc"}}
+	if !a.Equal(b) {
+		t.Error("Expected fingerprints differing only in mtime to be equal")
+	}
+	b["common.yaml"] = FileStamp{Hash: "def"}
+	if a.Equal(b) {
+		t.Error("Expected an extra file to make fingerprints differ")
+	}
+}
@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LayoutFile is the optional file at a repository root that describes where
+// its services live
+const LayoutFile = "postwhale.config.yml"
+
+// Layout describes where a repository keeps its services. Empty fields fall
+// back to the default layout: services/*/tw-config.json with openapi*/swagger*
+// spec files beside it.
+type Layout struct {
+	// ServiceRoots are globs, relative to the repository, matching service
+	// directories, e.g. services/* or packages/*/services/*
+	ServiceRoots []string `json:"serviceRoots,omitempty" yaml:"serviceRoots,omitempty"`
+	// ConfigFiles are the service config names to look for, in order of
+	// preference; they use the tw-config.json format
+	ConfigFiles []string `json:"configFiles,omitempty" yaml:"configFiles,omitempty"`
+	// SpecPatterns are globs for spec files within a service directory, in
+	// order of preference
+	SpecPatterns []string `json:"specPatterns,omitempty" yaml:"specPatterns,omitempty"`
+	// Exclude skips matching service directories. Patterns with a slash match
+	// the path relative to the repository, others match the directory name.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// layoutFileContent is the shape of postwhale.config.yml
+type layoutFileContent struct {
+	Scan Layout `yaml:"scan"`
+}
+
+// DefaultLayout returns the layout used when nothing is configured
+func DefaultLayout() Layout {
+	return Layout{
+		ServiceRoots: []string{"services/*"},
+		ConfigFiles:  []string{configFile},
+		SpecPatterns: append([]string(nil), specPatterns...),
+	}
+}
+
+// Override returns l with every field set in other replacing its own
+func (l Layout) Override(other Layout) Layout {
+	if len(other.ServiceRoots) > 0 {
+		l.ServiceRoots = other.ServiceRoots
+	}
+	if len(other.ConfigFiles) > 0 {
+		l.ConfigFiles = other.ConfigFiles
+	}
+	if len(other.SpecPatterns) > 0 {
+		l.SpecPatterns = other.SpecPatterns
+	}
+	if len(other.Exclude) > 0 {
+		l.Exclude = other.Exclude
+	}
+	return l
+}
+
+// Validate checks that every pattern is a valid glob that stays inside the
+// repository and that config names are plain file names
+func (l Layout) Validate() error {
+	for _, group := range []struct {
+		field    string
+		patterns []string
+	}{
+		{"serviceRoots", l.ServiceRoots},
+		{"specPatterns", l.SpecPatterns},
+		{"exclude", l.Exclude},
+	} {
+		for _, pattern := range group.patterns {
+			if pattern == "" {
+				return fmt.Errorf("%s: pattern cannot be empty", group.field)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %v", group.field, pattern, err)
+			}
+			if path.IsAbs(pattern) || pattern == ".." || strings.HasPrefix(pattern, "../") || strings.Contains(pattern, "/../") {
+				return fmt.Errorf("%s: pattern %q must be relative and stay inside the repository", group.field, pattern)
+			}
+		}
+	}
+	for _, name := range l.ConfigFiles {
+		if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return fmt.Errorf("configFiles: invalid file name %q", name)
+		}
+	}
+	return nil
+}
+
+// ResolveLayout works out the layout for a repository: the defaults,
+// overridden by postwhale.config.yml at the repository root if present,
+// overridden by the layout stored for the repository
+func ResolveLayout(repoPath string, stored Layout) (Layout, error) {
+	layout := DefaultLayout()
+
+	data, err := os.ReadFile(filepath.Join(repoPath, LayoutFile))
+	switch {
+	case err == nil:
+		var content layoutFileContent
+		if err := yaml.Unmarshal(data, &content); err != nil {
+			return layout, fmt.Errorf("invalid %s: %v", LayoutFile, err)
+		}
+		if err := content.Scan.Validate(); err != nil {
+			return layout, fmt.Errorf("invalid %s: %v", LayoutFile, err)
+		}
+		layout = layout.Override(content.Scan)
+	case !os.IsNotExist(err):
+		return layout, fmt.Errorf("failed to read %s: %v", LayoutFile, err)
+	}
+
+	if err := stored.Validate(); err != nil {
+		return layout, err
+	}
+	return layout.Override(stored), nil
+}
+
+// serviceDirs lists the service directories the layout matches, relative to
+// the repository in slash form, sorted and without duplicates
+func (l Layout) serviceDirs(repoPath string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, root := range l.ServiceRoots {
+		matches, _ := filepath.Glob(filepath.Join(repoPath, filepath.FromSlash(root)))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(repoPath, match)
+			if err != nil {
+				continue
+			}
+			dir := filepath.ToSlash(rel)
+			if seen[dir] || l.excluded(dir) {
+				continue
+			}
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// excluded reports whether an Exclude pattern matches a service directory
+func (l Layout) excluded(dir string) bool {
+	for _, pattern := range l.Exclude {
+		target := dir
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(dir)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// configPath returns the first of the layout's config files present in the
+// service directory; the first name when none is
+func (l Layout) configPath(servicePath string) string {
+	for _, name := range l.ConfigFiles {
+		configPath := filepath.Join(servicePath, name)
+		if info, err := os.Stat(configPath); err == nil && !info.IsDir() {
+			return configPath
+		}
+	}
+	return filepath.Join(servicePath, l.ConfigFiles[0])
+}
+
+// HasServices reports whether the repository at repoPath has at least one
+// service directory with a config under its layout, read from
+// postwhale.config.yml when present
+func HasServices(repoPath string) bool {
+	layout, err := ResolveLayout(repoPath, Layout{})
+	if err != nil {
+		return false
+	}
+	for _, dir := range layout.serviceDirs(repoPath) {
+		if info, err := os.Stat(layout.configPath(filepath.Join(repoPath, filepath.FromSlash(dir)))); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// rootsExist reports whether the fixed leading directory of any service root
+// exists, e.g. services for services/*
+func (l Layout) rootsExist(repoPath string) bool {
+	for _, root := range l.ServiceRoots {
+		base := ""
+		for _, part := range strings.Split(root, "/") {
+			if strings.ContainsAny(part, `*?[\`) {
+				break
+			}
+			base = path.Join(base, part)
+		}
+		if info, err := os.Stat(filepath.Join(repoPath, filepath.FromSlash(base))); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const layoutSpec = `openapi: 3.0.0
+info:
+  title: Layout
+paths:
+  /ping:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+func serviceDirsOf(result ScanResult) []string {
+	var dirs []string
+	for _, svc := range result.Services {
+		dirs = append(dirs, svc.Dir)
+	}
+	return dirs
+}
+
+func TestScanRepository_LayoutFile(t *testing.T) {
+	repoPath := t.TempDir()
+	writeService(t, filepath.Join(repoPath, "packages", "shop"), "orders", map[string]string{
+		"service.json": `{"serviceId": "orders", "env": {"PORT": 8080}}`,
+		"api.yaml":     layoutSpec,
+	})
+	writeService(t, filepath.Join(repoPath, "packages", "shop"), "legacy-billing", map[string]string{
+		"service.json": `{"serviceId": "billing", "env": {"PORT": 8081}}`,
+	})
+	writeService(t, filepath.Join(repoPath, "packages", "admin"), "users", map[string]string{
+		"tw-config.json": `{"serviceId": "users", "env": {"PORT": 8082}}`,
+		"openapi.yaml":   layoutSpec,
+	})
+	layout := `scan:
+  serviceRoots: ["packages/*/services/*"]
+  configFiles: [service.json, tw-config.json]
+  specPatterns: [api.yaml, openapi*.yaml]
+  exclude: ["legacy-*"]
+`
+	if err := os.WriteFile(filepath.Join(repoPath, LayoutFile), []byte(layout), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ScanRepository(repoPath)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if dirs := serviceDirsOf(result); !reflect.DeepEqual(dirs, []string{"packages/admin/services/users", "packages/shop/services/orders"}) {
+		t.Fatalf("Expected nested services without the excluded one, got %v", dirs)
+	}
+	orders := result.Services[1]
+	if orders.ServiceID != "orders" || len(orders.Endpoints) != 1 || orders.Endpoints[0].Source.File != "api.yaml" {
+		t.Errorf("Expected orders read from service.json and api.yaml, got %+v", orders)
+	}
+	if !HasServices(repoPath) {
+		t.Error("Expected HasServices to follow the layout file")
+	}
+
+	// A stored layout overrides the file field by field
+	result = ScanRepositoryWith(repoPath, ScanOptions{Layout: Layout{Exclude: []string{"packages/admin/*/*"}}})
+	if dirs := serviceDirsOf(result); !reflect.DeepEqual(dirs, []string{"packages/shop/services/legacy-billing", "packages/shop/services/orders"}) {
+		t.Errorf("Expected the stored exclude to replace the file's, got %v", dirs)
+	}
+}
+
+func TestScanRepositoryWith_LayoutFileChangeRescans(t *testing.T) {
+	repoPath := t.TempDir()
+	writeService(t, repoPath, "orders", map[string]string{
+		"tw-config.json":       `{"serviceId": "orders", "env": {"PORT": 8080}}`,
+		"openapi.yaml":         layoutSpec,
+		"openapi.private.yaml": "openapi: 3.0.0\ninfo:\n  title: Private\npaths:\n  /secret:\n    get:\n      responses:\n        \"200\":\n          description: ok\n",
+	})
+
+	first := ScanRepository(repoPath)
+	if len(first.Services) != 1 || len(first.Services[0].Endpoints) != 2 {
+		t.Fatalf("Expected both spec files merged, got %+v", first.Services)
+	}
+
+	// Narrowing the spec patterns must re-parse even though no spec changed
+	if err := os.WriteFile(filepath.Join(repoPath, LayoutFile), []byte("scan:\n  specPatterns: [openapi.yaml]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second := ScanRepositoryWith(repoPath, ScanOptions{Previous: previousOf(first)})
+	if second.Services[0].Unchanged || len(second.Services[0].Endpoints) != 1 {
+		t.Errorf("Expected orders re-parsed with one endpoint, got %+v", second.Services[0])
+	}
+}
+
+func TestScanRepository_NoMatchingServiceRoots(t *testing.T) {
+	repoPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoPath, "services"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty services directory is a repository without services yet
+	if result := ScanRepository(repoPath); len(result.Errors) > 0 {
+		t.Errorf("Expected no errors for an empty services directory, got %v", result.Errors)
+	}
+	if HasServices(repoPath) {
+		t.Error("Expected HasServices to be false without a service config")
+	}
+
+	result := ScanRepositoryWith(repoPath, ScanOptions{Layout: Layout{ServiceRoots: []string{"apps/*"}}})
+	if len(result.Errors) == 0 {
+		t.Error("Expected an error when no service root exists")
+	}
+}
+
+func TestLayout_Validate(t *testing.T) {
+	valid := Layout{
+		ServiceRoots: []string{"apps/*", "packages/*/services/*"},
+		ConfigFiles:  []string{"service.json"},
+		SpecPatterns: []string{"api/openapi*.yaml"},
+		Exclude:      []string{"legacy-*"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid layout, got %v", err)
+	}
+
+	invalid := []Layout{
+		{ServiceRoots: []string{"["}},
+		{ServiceRoots: []string{"../other/*"}},
+		{ServiceRoots: []string{"/abs/*"}},
+		{SpecPatterns: []string{""}},
+		{ConfigFiles: []string{"nested/tw-config.json"}},
+	}
+	for _, layout := range invalid {
+		if err := layout.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", layout)
+		}
+	}
+
+	// A broken layout file fails the scan rather than falling back
+	repoPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoPath, LayoutFile), []byte("scan:\n  serviceRoots: [\"../*\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := ScanRepository(repoPath); len(result.Errors) == 0 {
+		t.Error("Expected an error for an invalid layout file")
+	}
+}
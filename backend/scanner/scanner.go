@@ -4,17 +4,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/triplewhale/postwhale/discovery"
 )
 
+// configFile is the default service config that marks a directory as a service
+const configFile = "tw-config.json"
+
 // DiscoveredService represents a discovered service with its config and endpoints
 type DiscoveredService struct {
-	ServiceID string
-	Name      string
-	Port      int
-	Config    *discovery.TWConfig
-	Endpoints []discovery.APIEndpoint
+	ServiceID   string
+	Name        string
+	Path        string // service directory
+	Dir         string // service directory relative to the repository, e.g. services/orders
+	Port        int
+	Config      *discovery.TWConfig
+	Endpoints   []discovery.APIEndpoint
+	Spec        discovery.ServiceSpec // info, servers and security merged from the spec files
+	SpecFiles   []string              // spec files that were parsed, relative to Path
+	Warnings    []string              // spec problems that didn't prevent the scan
+	Fingerprint Fingerprint           // files the service was read from
+
+	// Unchanged is set when the service's files match ScanOptions.Previous.
+	// Only Path, Dir and Fingerprint are filled in; the service wasn't parsed.
+	Unchanged bool
 }
 
 // ScanResult contains the results of scanning a repository
@@ -22,14 +40,35 @@ type ScanResult struct {
 	RepoPath string
 	Services []DiscoveredService
 	Errors   []string
+	Warnings []string // non-fatal problems, prefixed with the service ID
+}
+
+// ScanOptions controls an incremental scan
+type ScanOptions struct {
+	// Previous holds the fingerprints from the last scan, keyed by
+	// DiscoveredService.Dir. Services whose files still match are not parsed.
+	Previous map[string]Fingerprint
+	// Workers bounds how many services are parsed at once; 0 means one per CPU
+	Workers int
+	// Layout is the layout stored for the repository. It overrides
+	// postwhale.config.yml, which overrides DefaultLayout.
+	Layout Layout
 }
 
 // ScanRepository scans a repository path to discover all services and endpoints
 func ScanRepository(repoPath string) ScanResult {
+	return ScanRepositoryWith(repoPath, ScanOptions{})
+}
+
+// ScanRepositoryWith scans a repository, skipping services that are unchanged
+// since opts.Previous and parsing the rest in parallel. Services are returned
+// in directory order regardless of which finishes first.
+func ScanRepositoryWith(repoPath string, opts ScanOptions) ScanResult {
 	result := ScanResult{
 		RepoPath: repoPath,
 		Services: []DiscoveredService{},
 		Errors:   []string{},
+		Warnings: []string{},
 	}
 
 	// Validate input
@@ -44,94 +83,267 @@ func ScanRepository(repoPath string) ScanResult {
 		return result
 	}
 
-	// Check for services directory
-	servicesPath := filepath.Join(repoPath, "services")
-	if _, err := os.Stat(servicesPath); os.IsNotExist(err) {
-		result.Errors = append(result.Errors, fmt.Sprintf("services directory not found: %s", servicesPath))
+	layout, err := ResolveLayout(repoPath, opts.Layout)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
 		return result
 	}
 
-	// Read services directory
-	entries, err := os.ReadDir(servicesPath)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to read services directory: %v", err))
+	// Find the service directories; a repository whose service roots don't
+	// exist at all isn't a services repository
+	dirs := layout.serviceDirs(repoPath)
+	if len(dirs) == 0 && !layout.rootsExist(repoPath) {
+		result.Errors = append(result.Errors, fmt.Sprintf("no service directories found matching %s", strings.Join(layout.ServiceRoots, ", ")))
 		return result
 	}
 
-	// Scan each service directory
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
 
-		servicePath := filepath.Join(servicesPath, entry.Name())
-		service := scanService(servicePath)
+	// Scan each service directory with a bounded pool; results keep their slot
+	// so the output order doesn't depend on scheduling
+	scanned := make([]*DiscoveredService, len(dirs))
+	errs := make([]error, len(dirs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(dirs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				dir := dirs[i]
+				scanned[i], errs[i] = scanServiceIfChanged(repoPath, dir, layout, opts.Previous[dir])
+			}
+		}()
+	}
+	for i := range dirs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-		if service != nil {
-			result.Services = append(result.Services, *service)
+	// A serviceId belongs to the first directory that declares it
+	owners := map[string]string{}
+	for i, service := range scanned {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", dirs[i], errs[i]))
+		}
+		if service == nil {
+			continue
+		}
+		if !service.Unchanged {
+			if owner, dup := owners[service.ServiceID]; dup {
+				result.Warnings = append(result.Warnings, DuplicateServiceWarning(*service, owner))
+				continue
+			}
+			owners[service.ServiceID] = service.Dir
+		}
+		result.Services = append(result.Services, *service)
+		for _, warning := range service.Warnings {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", service.ServiceID, warning))
 		}
 	}
 
 	return result
 }
 
-// findOpenAPIFile finds an OpenAPI file in the service directory
-// Matches pattern: openapi*.yaml or openapi*.yml (e.g., openapi.private.yaml, openapi.public.yml)
-func findOpenAPIFile(servicePath string) string {
-	// Try yaml extension first
-	yamlMatches, _ := filepath.Glob(filepath.Join(servicePath, "openapi*.yaml"))
-	if len(yamlMatches) > 0 {
-		return yamlMatches[0]
+// DuplicateServiceWarning reports a service skipped because the directory
+// owner already declares its serviceId
+func DuplicateServiceWarning(service DiscoveredService, owner string) string {
+	return fmt.Sprintf("%s: serviceId %q is already used by %s; skipping", service.Dir, service.ServiceID, owner)
+}
+
+// scanServiceIfChanged returns an Unchanged placeholder when the service's
+// files match previous, and parses the service otherwise. A config or spec
+// file that exists but can't be read (e.g. saved half-written) is returned as
+// an error together with an Unchanged placeholder carrying previous, so the
+// stored service and its endpoints are kept until the file is fixed rather
+// than removed.
+func scanServiceIfChanged(repoPath, dir string, layout Layout, previous Fingerprint) (*DiscoveredService, error) {
+	servicePath := filepath.Join(repoPath, filepath.FromSlash(dir))
+	if len(previous) > 0 {
+		current := stampFiles(servicePath, serviceFiles(repoPath, servicePath, layout, previous), previous)
+		if current.Equal(previous) {
+			return &DiscoveredService{Path: servicePath, Dir: dir, Fingerprint: current, Unchanged: true}, nil
+		}
+	}
+
+	service, err := scanService(repoPath, servicePath, layout, previous)
+	if err != nil {
+		return &DiscoveredService{Path: servicePath, Dir: dir, Fingerprint: previous, Unchanged: true}, err
+	}
+	if service != nil {
+		service.Dir = dir
 	}
+	return service, nil
+}
 
-	// Try yml extension
-	ymlMatches, _ := filepath.Glob(filepath.Join(servicePath, "openapi*.yml"))
-	if len(ymlMatches) > 0 {
-		return ymlMatches[0]
+// specPatterns are the default spec file names looked for in a service
+// directory, in order of preference. Swagger 2.0 documents are converted
+// when parsed.
+var specPatterns = []string{
+	"openapi*.yaml",
+	"openapi*.yml",
+	"openapi*.json",
+	"swagger*.yaml",
+	"swagger*.yml",
+	"swagger*.json",
+}
+
+// findSpecFiles finds every OpenAPI or Swagger file in the service directory
+// (e.g., openapi.private.yaml and openapi.public.yaml), in pattern order
+func findSpecFiles(servicePath string, patterns []string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(servicePath, filepath.FromSlash(pattern)))
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
 	}
 
-	return ""
+	return files
 }
 
-// scanService scans a single service directory for config and endpoints
-func scanService(servicePath string) *DiscoveredService {
-	// Look for tw-config.json
-	configPath := filepath.Join(servicePath, "tw-config.json")
+// scanService scans a single service directory for config and endpoints.
+// previous supplies hashes to reuse for files that haven't been modified.
+// A directory without a config isn't a service and returns nil; a config
+// or spec file that can't be parsed returns an error.
+func scanService(repoPath, servicePath string, layout Layout, previous Fingerprint) (*DiscoveredService, error) {
+	// Look for tw-config.json, or whichever config name the layout uses
+	configPath := layout.configPath(servicePath)
 	config, err := discovery.ParseTWConfig(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		// Skip services without valid config
-		return nil
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Base(configPath), err)
 	}
 
 	service := &DiscoveredService{
 		ServiceID: config.ServiceID,
 		Name:      "", // Will be populated from OpenAPI
+		Path:      servicePath,
 		Port:      config.Env.Port,
 		Config:    config,
 		Endpoints: []discovery.APIEndpoint{},
 	}
 
-	// Find OpenAPI file (matches openapi*.yaml or openapi*.yml)
-	openapiPath := findOpenAPIFile(servicePath)
-	if openapiPath == "" {
-		// Service has config but no OpenAPI - use serviceID as name
-		service.Name = config.ServiceID
-		return service
+	// Merge every spec file (see specPatterns); earlier files win conflicts
+	files := []string{filepath.Base(configPath), layoutFileFrom(repoPath, servicePath)}
+	byKey := map[string]int{}
+	for _, specPath := range findSpecFiles(servicePath, layout.SpecPatterns) {
+		file, _ := filepath.Rel(servicePath, specPath)
+		files = append(files, file)
+		openapi, err := discovery.ParseOpenAPI(specPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		service.SpecFiles = append(service.SpecFiles, file)
+		service.Warnings = append(service.Warnings, openapi.Warnings...)
+
+		// Files pulled in through $refs are part of the service too
+		for _, ref := range openapi.Files {
+			if rel, err := filepath.Rel(servicePath, ref); err == nil && rel != file {
+				files = append(files, rel)
+			}
+		}
+
+		// Service name comes from the first spec with a title
+		if service.Name == "" {
+			service.Name = openapi.Info.Title
+		}
+		mergeServiceSpec(&service.Spec, discovery.ExtractServiceSpec(openapi))
+
+		source := &discovery.SpecSource{File: file, Visibility: specVisibility(file)}
+		for _, endpoint := range discovery.ExtractEndpoints(openapi) {
+			endpoint.Source = source
+			key := endpoint.Method + " " + endpoint.Path
+			i, dup := byKey[key]
+			if !dup {
+				byKey[key] = len(service.Endpoints)
+				service.Endpoints = append(service.Endpoints, endpoint)
+				continue
+			}
+			if existing := service.Endpoints[i]; !sameOperation(existing, endpoint) {
+				service.Warnings = append(service.Warnings, fmt.Sprintf("%s is defined differently in %s and %s; using %s", key, existing.Source.File, file, existing.Source.File))
+			}
+		}
 	}
 
-	openapi, err := discovery.ParseOpenAPI(openapiPath)
-	if err != nil {
-		// Service has config but OpenAPI failed to parse - use serviceID as name
+	if service.Name == "" {
+		// Service has config but no usable spec - use serviceID as name
 		service.Name = config.ServiceID
-		return service
 	}
+	service.Fingerprint = stampFiles(servicePath, files, previous)
 
-	// Get service name from OpenAPI info
-	service.Name = openapi.Info.Title
+	sort.Slice(service.Endpoints, func(i, j int) bool {
+		a, b := service.Endpoints[i], service.Endpoints[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
 
-	// Extract endpoints
-	endpoints := discovery.ExtractEndpoints(openapi)
-	service.Endpoints = endpoints
+	return service, nil
+}
+
+// mergeServiceSpec folds another file's service-level spec into dst. Info and
+// top-level security come from the first file that declares them; servers
+// and security schemes are combined.
+func mergeServiceSpec(dst *discovery.ServiceSpec, src discovery.ServiceSpec) {
+	if dst.Info.Title == "" && dst.Info.Version == "" {
+		dst.Info = src.Info
+	}
+	if dst.Security == nil {
+		dst.Security = src.Security
+	}
 
-	return service
+	for _, server := range src.Servers {
+		known := false
+		for _, existing := range dst.Servers {
+			if existing.URL == server.URL {
+				known = true
+				break
+			}
+		}
+		if !known {
+			dst.Servers = append(dst.Servers, server)
+		}
+	}
+
+	for name, scheme := range src.SecuritySchemes {
+		if dst.SecuritySchemes == nil {
+			dst.SecuritySchemes = map[string]discovery.SecurityScheme{}
+		}
+		if _, ok := dst.SecuritySchemes[name]; !ok {
+			dst.SecuritySchemes[name] = scheme
+		}
+	}
+}
+
+// sameOperation reports whether two endpoints describe the same operation,
+// ignoring which file they came from
+func sameOperation(a, b discovery.APIEndpoint) bool {
+	a.Source, b.Source = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// specVisibility reads private or public from a spec file name
+// such as openapi.private.yaml; other names have no visibility
+func specVisibility(file string) string {
+	for _, part := range strings.Split(strings.ToLower(filepath.Base(file)), ".") {
+		if part == "private" || part == "public" {
+			return part
+		}
+	}
+	return ""
 }
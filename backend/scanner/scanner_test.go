@@ -1,8 +1,12 @@
 package scanner
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/triplewhale/postwhale/discovery"
 )
 
 func TestScanRepository_ValidRepo(t *testing.T) {
@@ -87,8 +91,36 @@ func TestScanRepository_MissingOpenAPI(t *testing.T) {
 }
 
 func TestScanRepository_InvalidConfig(t *testing.T) {
-	// Test handling of invalid tw-config.json
-	t.Skip("Requires test data setup")
+	repoPath := t.TempDir()
+	writeService(t, repoPath, "orders", map[string]string{
+		"tw-config.json": `{"serviceId": "ord`,
+	})
+	// A directory without a config isn't a service
+	writeService(t, repoPath, "docs", map[string]string{"README.md": "docs"})
+
+	result := ScanRepository(repoPath)
+	if len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0], "services/orders: invalid tw-config.json") {
+		t.Errorf("Expected an error for the orders config, got %v", result.Errors)
+	}
+	// The unreadable service is kept as Unchanged so its stored rows survive
+	if len(result.Services) != 1 || !result.Services[0].Unchanged || result.Services[0].Dir != "services/orders" {
+		t.Errorf("Expected an Unchanged placeholder for orders, got %+v", result.Services)
+	}
+}
+
+func TestScanRepository_DuplicateServiceIDs(t *testing.T) {
+	repoPath := t.TempDir()
+	config := `{"serviceId": "orders", "env": {"PORT": 8080}}`
+	writeService(t, repoPath, "orders", map[string]string{"tw-config.json": config})
+	writeService(t, repoPath, "orders-copy", map[string]string{"tw-config.json": config})
+
+	result := ScanRepository(repoPath)
+	if len(result.Services) != 1 || result.Services[0].Dir != "services/orders" {
+		t.Errorf("Expected the first directory to keep the serviceId, got %+v", result.Services)
+	}
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "services/orders-copy: ") {
+		t.Errorf("Expected a warning for the duplicate, got %v", result.Warnings)
+	}
 }
 
 func TestScanRepository_EmptyPath(t *testing.T) {
@@ -101,3 +133,122 @@ func TestScanRepository_EmptyPath(t *testing.T) {
 		t.Errorf("Expected 0 services, got %d", len(result.Services))
 	}
 }
+
+func TestScanRepository_SwaggerJSON(t *testing.T) {
+	repoPath := t.TempDir()
+	servicePath := filepath.Join(repoPath, "services", "legacy")
+	if err := os.MkdirAll(servicePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(servicePath, "tw-config.json"), []byte(`{"serviceId": "legacy", "env": {"PORT": 9000}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	swagger := `{"swagger": "2.0", "info": {"title": "Legacy"}, "basePath": "/v1",
+		"paths": {"/items": {"get": {"operationId": "listItems", "responses": {"200": {"description": "ok"}}}}}}`
+	if err := os.WriteFile(filepath.Join(servicePath, "swagger.json"), []byte(swagger), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ScanRepository(repoPath)
+	if len(result.Services) != 1 {
+		t.Fatalf("Expected 1 service, got %d (errors %v)", len(result.Services), result.Errors)
+	}
+	svc := result.Services[0]
+	if svc.Name != "Legacy" {
+		t.Errorf("Expected name from swagger info, got %q", svc.Name)
+	}
+	if len(svc.Endpoints) != 1 || svc.Endpoints[0].Path != "/v1/items" {
+		t.Errorf("Expected endpoint from swagger.json, got %+v", svc.Endpoints)
+	}
+}
+
+func TestScanRepository_MergesSpecFiles(t *testing.T) {
+	repoPath := t.TempDir()
+	servicePath := filepath.Join(repoPath, "services", "orders")
+	if err := os.MkdirAll(servicePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"tw-config.json": `{"serviceId": "orders", "env": {"PORT": 8080}}`,
+		"openapi.private.yaml": `openapi: 3.0.0
+info:
+  title: Orders - Private
+servers:
+  - url: http://orders.internal
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+      responses:
+        "200":
+          description: ok
+  /orders/{id}:
+    delete:
+      operationId: deleteOrder
+      responses:
+        "204":
+          description: deleted
+`,
+		"openapi.public.yaml": `openapi: 3.0.0
+info:
+  title: Orders - Public
+servers:
+  - url: https://api.example.com/orders
+paths:
+  /orders:
+    get:
+      operationId: listPublicOrders
+      responses:
+        "200":
+          description: ok
+  /orders/{id}:
+    delete:
+      operationId: deleteOrder
+      responses:
+        "204":
+          description: deleted
+  /status:
+    get:
+      operationId: status
+      responses:
+        "200":
+          description: ok
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(servicePath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := ScanRepository(repoPath)
+	if len(result.Services) != 1 {
+		t.Fatalf("Expected 1 service, got %d (errors %v)", len(result.Services), result.Errors)
+	}
+	svc := result.Services[0]
+	if svc.Name != "Orders - Private" {
+		t.Errorf("Expected name from the first spec file, got %q", svc.Name)
+	}
+	if len(svc.SpecFiles) != 2 || len(svc.Spec.Servers) != 2 {
+		t.Errorf("Expected both files and their servers, got %v / %+v", svc.SpecFiles, svc.Spec.Servers)
+	}
+
+	if len(svc.Endpoints) != 3 {
+		t.Fatalf("Expected 3 merged endpoints, got %+v", svc.Endpoints)
+	}
+	byPath := map[string]discovery.APIEndpoint{}
+	for _, endpoint := range svc.Endpoints {
+		byPath[endpoint.Method+" "+endpoint.Path] = endpoint
+	}
+	if list := byPath["GET /orders"]; list.OperationID != "listOrders" || list.Source.File != "openapi.private.yaml" || list.Source.Visibility != "private" {
+		t.Errorf("Expected the private definition to win, got %+v", list)
+	}
+	if status := byPath["GET /status"]; status.Source == nil || status.Source.Visibility != "public" {
+		t.Errorf("Expected public provenance, got %+v", status.Source)
+	}
+
+	// Identical duplicates are fine; only the conflicting one is reported
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "GET /orders is defined differently in openapi.private.yaml and openapi.public.yaml") {
+		t.Errorf("Expected one conflict warning, got %v", result.Warnings)
+	}
+}
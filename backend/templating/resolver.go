@@ -0,0 +1,213 @@
+package templating
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scope is a named layer of variables
+type Scope struct {
+	Name string
+	Vars map[string]string
+}
+
+// Resolver looks variables up through layered scopes.
+// Later scopes are more specific and win over earlier ones.
+// Names starting with $ are built-ins generated per occurrence.
+type Resolver struct {
+	scopes []Scope
+	now    func() time.Time
+}
+
+// NewResolver creates a Resolver; pass scopes from least to most specific
+func NewResolver(scopes ...Scope) *Resolver {
+	return &Resolver{scopes: scopes, now: time.Now}
+}
+
+// Lookup resolves a single variable name
+func (r *Resolver) Lookup(name string) (string, bool) {
+	if strings.HasPrefix(name, "$") {
+		return r.builtin(name)
+	}
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if value, ok := r.scopes[i].Vars[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Source returns the name of the scope that defines a variable, or "" if none does
+func (r *Resolver) Source(name string) string {
+	if strings.HasPrefix(name, "$") {
+		if _, ok := r.builtin(name); ok {
+			return "builtin"
+		}
+		return ""
+	}
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i].Vars[name]; ok {
+			return r.scopes[i].Name
+		}
+	}
+	return ""
+}
+
+// Expand replaces every {{name}} placeholder in s
+func (r *Resolver) Expand(s string) (string, error) {
+	return Expand(s, r.Lookup)
+}
+
+// builtin generates a value for a $-prefixed built-in variable
+func (r *Resolver) builtin(name string) (string, bool) {
+	switch name {
+	case "$uuid":
+		return newUUID(), true
+	case "$timestamp":
+		return strconv.FormatInt(r.now().Unix(), 10), true
+	case "$isoTimestamp":
+		return r.now().UTC().Format(time.RFC3339), true
+	case "$randomInt":
+		n, err := rand.Int(rand.Reader, big.NewInt(1000))
+		if err != nil {
+			return "0", true
+		}
+		return n.String(), true
+	default:
+		return "", false
+	}
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// QueryParam is a single query string entry
+type QueryParam struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Request holds every templated part of an outgoing request
+type Request struct {
+	Path        string            // endpoint path, may contain {param} segments
+	PathParams  map[string]string // values for {param} segments
+	QueryParams []QueryParam
+	Headers     map[string]string
+	Body        string
+}
+
+// Unresolved is a variable that had no value, and where it was used
+type Unresolved struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// RequestError reports every unresolved variable in a request
+type RequestError struct {
+	Unresolved []Unresolved
+}
+
+func (e *RequestError) Error() string {
+	parts := make([]string, len(e.Unresolved))
+	for i, u := range e.Unresolved {
+		parts[i] = fmt.Sprintf("%s (in %s)", u.Name, u.Location)
+	}
+	return fmt.Sprintf("unresolved variables: %s", strings.Join(parts, ", "))
+}
+
+// ResolveRequest expands placeholders in every part of req.
+// It always returns the best-effort resolved request; if any variable is missing
+// the error is a *RequestError listing each one with its location.
+func (r *Resolver) ResolveRequest(req Request) (Request, error) {
+	var unresolved []Unresolved
+	expand := func(s, location string) string {
+		out, err := r.Expand(s)
+		if u, ok := err.(*UnresolvedError); ok {
+			for _, name := range u.Names {
+				unresolved = append(unresolved, Unresolved{Name: name, Location: location})
+			}
+		}
+		return out
+	}
+
+	resolved := Request{
+		Path:       expand(req.Path, "path"),
+		PathParams: map[string]string{},
+		Headers:    map[string]string{},
+		Body:       expand(req.Body, "body"),
+	}
+
+	for _, key := range sortedKeys(req.PathParams) {
+		resolved.PathParams[key] = expand(req.PathParams[key], "path param "+key)
+	}
+	for _, q := range req.QueryParams {
+		if !q.Enabled {
+			continue
+		}
+		resolved.QueryParams = append(resolved.QueryParams, QueryParam{
+			Key:     expand(q.Key, "query param "+q.Key),
+			Value:   expand(q.Value, "query param "+q.Key),
+			Enabled: true,
+		})
+	}
+	for _, key := range sortedKeys(req.Headers) {
+		resolved.Headers[expand(key, "header "+key)] = expand(req.Headers[key], "header "+key)
+	}
+
+	if len(unresolved) > 0 {
+		return resolved, &RequestError{Unresolved: unresolved}
+	}
+	return resolved, nil
+}
+
+// URL substitutes path params into the path and appends the enabled query params
+func (req Request) URL() string {
+	path := req.Path
+	for key, value := range req.PathParams {
+		path = strings.ReplaceAll(path, "{"+key+"}", url.PathEscape(value))
+	}
+
+	if len(req.QueryParams) == 0 {
+		return path
+	}
+
+	pairs := make([]string, 0, len(req.QueryParams))
+	for _, q := range req.QueryParams {
+		if !q.Enabled || q.Key == "" {
+			continue
+		}
+		pairs = append(pairs, url.QueryEscape(q.Key)+"="+url.QueryEscape(q.Value))
+	}
+	if len(pairs) == 0 {
+		return path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(pairs, "&")
+}
+
+// sortedKeys returns map keys in sorted order so errors are deterministic
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package templating
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestResolver_ScopePrecedence(t *testing.T) {
+	r := NewResolver(
+		Scope{Name: "global", Vars: map[string]string{"shopId": "global-shop", "region": "us"}},
+		Scope{Name: "environment", Vars: map[string]string{"shopId": "env-shop"}},
+		Scope{Name: "savedRequest", Vars: map[string]string{"shopId": "request-shop"}},
+	)
+
+	if value, _ := r.Lookup("shopId"); value != "request-shop" {
+		t.Errorf("Expected most specific scope to win, got %q", value)
+	}
+	if value, _ := r.Lookup("region"); value != "us" {
+		t.Errorf("Expected fallback to global scope, got %q", value)
+	}
+	if source := r.Source("shopId"); source != "savedRequest" {
+		t.Errorf("Expected source savedRequest, got %q", source)
+	}
+}
+
+func TestResolver_Builtins(t *testing.T) {
+	r := NewResolver()
+
+	uuid, ok := r.Lookup("$uuid")
+	if !ok || !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("Expected v4 UUID, got %q", uuid)
+	}
+
+	ts, _ := r.Lookup("$timestamp")
+	if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+		t.Errorf("Expected unix timestamp, got %q", ts)
+	}
+
+	n, _ := r.Lookup("$randomInt")
+	if v, err := strconv.Atoi(n); err != nil || v < 0 || v >= 1000 {
+		t.Errorf("Expected random int in [0, 1000), got %q", n)
+	}
+
+	if _, ok := r.Lookup("$unknown"); ok {
+		t.Error("Expected unknown built-in to be unresolved")
+	}
+}
+
+func TestResolveRequest(t *testing.T) {
+	r := NewResolver(Scope{Name: "global", Vars: map[string]string{
+		"orderId": "ord 1",
+		"shopId":  "shop.myshopify.com",
+		"limit":   "10",
+	}})
+
+	resolved, err := r.ResolveRequest(Request{
+		Path:       "/orders/{orderId}",
+		PathParams: map[string]string{"orderId": "{{orderId}}"},
+		QueryParams: []QueryParam{
+			{Key: "limit", Value: "{{limit}}", Enabled: true},
+			{Key: "skip", Value: "{{missing}}", Enabled: false},
+		},
+		Headers: map[string]string{"x-tw-shop-id": "{{shopId}}"},
+		Body:    `{"shop":"{{shopId}}"}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if url := resolved.URL(); url != "/orders/ord%201?limit=10" {
+		t.Errorf("URL() = %q", url)
+	}
+	if resolved.Headers["x-tw-shop-id"] != "shop.myshopify.com" {
+		t.Errorf("Expected resolved header, got %v", resolved.Headers)
+	}
+	if resolved.Body != `{"shop":"shop.myshopify.com"}` {
+		t.Errorf("Expected resolved body, got %q", resolved.Body)
+	}
+}
+
+func TestResolveRequest_Unresolved(t *testing.T) {
+	r := NewResolver()
+
+	_, err := r.ResolveRequest(Request{
+		Path:    "/orders",
+		Headers: map[string]string{"x-tw-shop-id": "{{shopId}}"},
+		Body:    `{"id":"{{orderId}}"}`,
+	})
+
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("Expected RequestError, got %v", err)
+	}
+	if len(requestErr.Unresolved) != 2 {
+		t.Fatalf("Expected 2 unresolved variables, got %v", requestErr.Unresolved)
+	}
+	if requestErr.Unresolved[0] != (Unresolved{Name: "orderId", Location: "body"}) {
+		t.Errorf("Unexpected first unresolved: %+v", requestErr.Unresolved[0])
+	}
+	if requestErr.Unresolved[1] != (Unresolved{Name: "shopId", Location: "header x-tw-shop-id"}) {
+		t.Errorf("Unexpected second unresolved: %+v", requestErr.Unresolved[1])
+	}
+}
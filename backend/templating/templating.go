@@ -0,0 +1,57 @@
+package templating
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches {{name}} with optional surrounding whitespace
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// LookupFunc resolves a variable name to its value
+type LookupFunc func(name string) (string, bool)
+
+// UnresolvedError lists placeholders that had no value
+type UnresolvedError struct {
+	Names []string
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("unresolved variables: %s", strings.Join(e.Names, ", "))
+}
+
+// Expand replaces every {{name}} placeholder in s using lookup
+// All unresolved names are collected and returned together as an *UnresolvedError
+func Expand(s string, lookup LookupFunc) (string, error) {
+	missing := map[string]bool{}
+
+	out := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := lookup(name); ok {
+			return value
+		}
+		missing[name] = true
+		return match
+	})
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return out, &UnresolvedError{Names: names}
+	}
+
+	return out, nil
+}
+
+// MapLookup returns a LookupFunc backed by a map
+func MapLookup(vars map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
@@ -0,0 +1,30 @@
+package templating
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"host": "preview.example.com", "serviceId": "fusion"}
+
+	out, err := Expand("https://{{host}}/{{ serviceId }}", MapLookup(vars))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out != "https://preview.example.com/fusion" {
+		t.Errorf("Expand() = %q", out)
+	}
+}
+
+func TestExpand_Unresolved(t *testing.T) {
+	_, err := Expand("{{b}}/{{a}}/{{b}}", MapLookup(nil))
+
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("Expected UnresolvedError, got %v", err)
+	}
+	if len(unresolved.Names) != 2 || unresolved.Names[0] != "a" || unresolved.Names[1] != "b" {
+		t.Errorf("Expected sorted unique names [a b], got %v", unresolved.Names)
+	}
+}
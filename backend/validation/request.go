@@ -0,0 +1,240 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+// Mode controls what happens when an outgoing request violates its spec
+type Mode string
+
+const (
+	ModeOff   Mode = "off"   // don't validate
+	ModeWarn  Mode = "warn"  // send anyway and report the issues
+	ModeBlock Mode = "block" // don't send a request with issues
+)
+
+// ParseMode validates a mode name; empty means ModeWarn
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case "", ModeWarn:
+		return ModeWarn, nil
+	case ModeOff, ModeBlock:
+		return Mode(mode), nil
+	default:
+		return "", fmt.Errorf("unknown validation mode %q (expected off, warn or block)", mode)
+	}
+}
+
+// OutgoingRequest is a fully resolved request about to be sent
+type OutgoingRequest struct {
+	Path       string // request path; {param} values missing from PathParams are read from it
+	PathParams map[string]string
+	Query      url.Values
+	Headers    map[string]string
+	Body       string
+}
+
+// ValidateRequest checks an outgoing request against its operation: required
+// parameters, parameter types and the body against the requestBody schema.
+func ValidateRequest(endpoint *discovery.APIEndpoint, req OutgoingRequest) []Issue {
+	issues := []Issue{}
+	if endpoint == nil {
+		return issues
+	}
+
+	fromPath := pathValues(endpoint.Path, req.Path)
+	for _, param := range endpoint.Parameters {
+		var raw []string
+		switch param.In {
+		case "path":
+			if v, ok := req.PathParams[param.Name]; ok && v != "" {
+				raw = []string{v}
+			} else if v, ok := fromPath[param.Name]; ok {
+				raw = []string{v}
+			}
+		case "query":
+			raw = req.Query[param.Name]
+		case "header":
+			// OpenAPI ignores these as header parameters
+			switch strings.ToLower(param.Name) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			if v, ok := headerValue(req.Headers, param.Name); ok {
+				raw = []string{v}
+			}
+		default:
+			continue
+		}
+
+		if len(raw) == 0 {
+			if param.Required || param.In == "path" {
+				issues = append(issues, Issue{In: param.In, Pointer: "/" + escapePointer(param.Name), Message: fmt.Sprintf("missing required %s parameter %q", param.In, param.Name)})
+			}
+			continue
+		}
+
+		value := coerceParam(param.Schema, raw)
+		for _, issue := range ValidatePayload(param.Schema, value, "/"+escapePointer(param.Name), Request) {
+			issue.In = param.In
+			issues = append(issues, issue)
+		}
+	}
+
+	if endpoint.RequestBody != nil {
+		issues = append(issues, validateBody(endpoint.RequestBody, req)...)
+	}
+
+	return issues
+}
+
+// validateBody checks the body's content type and, for JSON, its schema
+func validateBody(body *discovery.RequestBody, req OutgoingRequest) []Issue {
+	if strings.TrimSpace(req.Body) == "" {
+		if body.Required {
+			return []Issue{{In: "body", Pointer: "/", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	contentType, _ := headerValue(req.Headers, "Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if contentType == "" && json.Valid([]byte(req.Body)) {
+		contentType = "application/json"
+	}
+
+	media, ok := matchMediaType(body.Content, contentType)
+	if !ok {
+		return []Issue{{In: "body", Pointer: "/", Message: fmt.Sprintf("content type %q is not documented (expected %s)", contentType, strings.Join(sortedMediaKeys(body.Content), ", "))}}
+	}
+	if !strings.Contains(contentType, "json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(req.Body), &value); err != nil {
+		return []Issue{{In: "body", Pointer: "/", Message: fmt.Sprintf("body is not valid JSON: %v", err)}}
+	}
+
+	issues := ValidatePayload(media.Schema, value, "", Request)
+	for i := range issues {
+		issues[i].In = "body"
+	}
+	return issues
+}
+
+// matchMediaType finds the documented media type for a content type,
+// falling back to type/* and */* ranges
+func matchMediaType(content map[string]discovery.MediaType, contentType string) (discovery.MediaType, bool) {
+	if len(content) == 0 {
+		// Nothing documented to check against
+		return discovery.MediaType{}, true
+	}
+	if media, ok := content[contentType]; ok {
+		return media, true
+	}
+	if i := strings.Index(contentType, "/"); i > 0 {
+		if media, ok := content[contentType[:i]+"/*"]; ok {
+			return media, true
+		}
+	}
+	media, ok := content["*/*"]
+	return media, ok
+}
+
+// coerceParam converts raw parameter strings to the JSON type the schema expects.
+// Values that don't convert are left as strings so validation reports them.
+func coerceParam(schema discovery.Schema, raw []string) interface{} {
+	if schema.Type == "array" {
+		values := raw
+		if len(raw) == 1 {
+			values = strings.Split(raw[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, v := range values {
+			if schema.Items != nil {
+				items[i] = coerceScalar(*schema.Items, v)
+			} else {
+				items[i] = v
+			}
+		}
+		return items
+	}
+	return coerceScalar(schema, raw[0])
+}
+
+// coerceScalar converts a single parameter string
+func coerceScalar(schema discovery.Schema, raw string) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if raw == "true" || raw == "false" {
+			return raw == "true"
+		}
+	case "object":
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &obj); err == nil {
+			return obj
+		}
+	}
+	return raw
+}
+
+// pathValues reads {param} values from a concrete path by matching it
+// segment by segment against the endpoint's path template
+func pathValues(template, path string) map[string]string {
+	values := map[string]string{}
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+
+	tmplSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(tmplSegments) != len(pathSegments) {
+		return values
+	}
+
+	for i, segment := range tmplSegments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		value := pathSegments[i]
+		// Still a placeholder: the value was never filled in
+		if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		values[segment[1:len(segment)-1]] = value
+	}
+	return values
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) (string, bool) {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.EqualFold(k, name) {
+			return headers[k], true
+		}
+	}
+	return "", false
+}
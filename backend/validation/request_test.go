@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+func requestEndpoint() *discovery.APIEndpoint {
+	max := 100.0
+	return &discovery.APIEndpoint{
+		Method: "POST",
+		Path:   "/shops/{shopId}/orders",
+		Parameters: []discovery.Parameter{
+			{Name: "shopId", In: "path", Required: true, Schema: discovery.Schema{Type: "string", Format: "uuid"}},
+			{Name: "limit", In: "query", Schema: discovery.Schema{Type: "integer", Maximum: &max}},
+			{Name: "ids", In: "query", Schema: discovery.Schema{Type: "array", Items: &discovery.Schema{Type: "integer"}}},
+			{Name: "dryRun", In: "query", Required: true, Schema: discovery.Schema{Type: "boolean"}},
+			{Name: "X-Shop-Region", In: "header", Required: true, Schema: discovery.Schema{Type: "string", Enum: []interface{}{"us", "eu"}}},
+			{Name: "Authorization", In: "header", Required: true, Schema: discovery.Schema{Type: "string"}},
+		},
+		RequestBody: &discovery.RequestBody{
+			Required: true,
+			Content: map[string]discovery.MediaType{"application/json": {Schema: discovery.Schema{
+				Type:                 "object",
+				Required:             []string{"id", "qty", "email"},
+				AdditionalProperties: &discovery.AdditionalProperties{Allowed: false},
+				Properties: map[string]discovery.Schema{
+					"id":    {Type: "string", ReadOnly: true},
+					"qty":   {Type: "integer"},
+					"email": {Type: "string", Format: "email"},
+					"state": {Type: "string", Enum: []interface{}{"open", "closed"}},
+				},
+			}}},
+		},
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	issues := ValidateRequest(requestEndpoint(), OutgoingRequest{
+		Path:    "/shops/3fa85f64-5717-4562-b3fc-2c963f66afa6/orders",
+		Query:   url.Values{"limit": {"500"}, "ids": {"1", "x"}},
+		Headers: map[string]string{"x-shop-region": "ap", "content-type": "application/json; charset=utf-8"},
+		Body:    `{"qty": "2", "email": "not-an-email", "state": "pending", "color": "red"}`,
+	})
+
+	expected := []Issue{
+		{In: "query", Pointer: "/limit", Message: "expected at most 100, got 500"},
+		{In: "query", Pointer: "/ids/1", Message: "expected integer, got string"},
+		{In: "query", Pointer: "/dryRun", Message: `missing required query parameter "dryRun"`},
+		{In: "header", Pointer: "/X-Shop-Region", Message: `value "ap" is not one of ["us","eu"]`},
+		{In: "body", Pointer: "/email", Message: `value "not-an-email" is not a valid email`},
+		{In: "body", Pointer: "/qty", Message: "expected integer, got string"},
+		{In: "body", Pointer: "/state", Message: `value "pending" is not one of ["open","closed"]`},
+		{In: "body", Pointer: "/", Message: `unexpected property "color"`},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], expected[i])
+		}
+	}
+}
+
+func TestValidateRequest_PathAndBody(t *testing.T) {
+	endpoint := requestEndpoint()
+	query := url.Values{"dryRun": {"true"}}
+	headers := map[string]string{"X-Shop-Region": "us"}
+
+	// Unfilled placeholder and missing body
+	issues := ValidateRequest(endpoint, OutgoingRequest{Path: "/shops/{shopId}/orders", Query: query, Headers: headers})
+	if len(issues) != 2 || issues[0].Pointer != "/shopId" || issues[1].Message != "request body is required" {
+		t.Errorf("Expected missing path param and body, got %+v", issues)
+	}
+
+	// Path params given explicitly, body sent as the wrong content type
+	issues = ValidateRequest(endpoint, OutgoingRequest{
+		Path:       "/shops/{shopId}/orders",
+		PathParams: map[string]string{"shopId": "3fa85f64-5717-4562-b3fc-2c963f66afa6"},
+		Query:      query,
+		Headers:    map[string]string{"X-Shop-Region": "us", "Content-Type": "text/plain"},
+		Body:       "qty=1",
+	})
+	if len(issues) != 1 || issues[0].Message != `content type "text/plain" is not documented (expected application/json)` {
+		t.Errorf("Expected undocumented content type, got %+v", issues)
+	}
+
+	// Valid request; readOnly id isn't required
+	issues = ValidateRequest(endpoint, OutgoingRequest{
+		Path:    "/shops/3fa85f64-5717-4562-b3fc-2c963f66afa6/orders",
+		Query:   query,
+		Headers: headers,
+		Body:    `{"qty": 2, "email": "a@example.com"}`,
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(""); err != nil || mode != ModeWarn {
+		t.Errorf("Expected warn by default, got %q (%v)", mode, err)
+	}
+	if _, err := ParseMode("strict"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+// IncomingResponse is a received response to check against its operation
+type IncomingResponse struct {
+	StatusCode int
+	Headers    map[string][]string
+	Body       string
+}
+
+// ValidateResponse checks a response against its operation: the status code
+// must be documented, the Content-Type must match a documented media type,
+// required headers must be present and a JSON body must match its schema.
+func ValidateResponse(endpoint *discovery.APIEndpoint, resp IncomingResponse) []Issue {
+	issues := []Issue{}
+	if endpoint == nil || len(endpoint.Responses) == 0 {
+		return issues
+	}
+
+	response, ok := documentedResponse(endpoint.Responses, resp.StatusCode)
+	if !ok {
+		return append(issues, Issue{In: "status", Pointer: "/", Message: fmt.Sprintf("status %d is not documented (expected %s)", resp.StatusCode, strings.Join(sortedResponseKeys(endpoint.Responses), ", "))})
+	}
+
+	headers := firstValues(resp.Headers)
+	for _, name := range sortedHeaderKeys(response.Headers) {
+		// Content-Type is described by content, not headers
+		if strings.EqualFold(name, "content-type") {
+			continue
+		}
+		header := response.Headers[name]
+		raw, ok := headerValue(headers, name)
+		if !ok {
+			if header.Required {
+				issues = append(issues, Issue{In: "header", Pointer: "/" + escapePointer(name), Message: fmt.Sprintf("missing required response header %q", name)})
+			}
+			continue
+		}
+		for _, issue := range ValidatePayload(header.Schema, coerceParam(header.Schema, []string{raw}), "/"+escapePointer(name), Response) {
+			issue.In = "header"
+			issues = append(issues, issue)
+		}
+	}
+
+	if strings.TrimSpace(resp.Body) == "" || len(response.Content) == 0 {
+		return issues
+	}
+
+	contentType, ok := headerValue(headers, "Content-Type")
+	if !ok || contentType == "" {
+		return append(issues, Issue{In: "header", Pointer: "/Content-Type", Message: fmt.Sprintf("missing Content-Type header (expected %s)", strings.Join(sortedMediaKeys(response.Content), ", "))})
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	media, ok := matchMediaType(response.Content, contentType)
+	if !ok {
+		return append(issues, Issue{In: "header", Pointer: "/Content-Type", Message: fmt.Sprintf("content type %q is not documented for status %d (expected %s)", contentType, resp.StatusCode, strings.Join(sortedMediaKeys(response.Content), ", "))})
+	}
+	if !strings.Contains(contentType, "json") {
+		return issues
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(resp.Body), &value); err != nil {
+		return append(issues, Issue{In: "body", Pointer: "/", Message: fmt.Sprintf("body is not valid JSON: %v", err)})
+	}
+
+	for _, issue := range ValidatePayload(media.Schema, value, "", Response) {
+		issue.In = "body"
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// firstValues flattens multi-valued headers to their first value
+func firstValues(headers map[string][]string) map[string]string {
+	values := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if len(v) > 0 {
+			values[k] = v[0]
+		}
+	}
+	return values
+}
+
+// sortedResponseKeys returns documented status codes in sorted order
+func sortedResponseKeys(m map[string]discovery.Response) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedHeaderKeys returns documented header names in sorted order
+func sortedHeaderKeys(m map[string]discovery.Header) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+func responseEndpoint() *discovery.APIEndpoint {
+	return &discovery.APIEndpoint{
+		Method: "GET",
+		Path:   "/orders/{id}",
+		Responses: map[string]discovery.Response{
+			"200": {
+				Description: "ok",
+				Headers: map[string]discovery.Header{
+					"X-Rate-Limit": {Required: true, Schema: discovery.Schema{Type: "integer"}},
+				},
+				Content: map[string]discovery.MediaType{"application/json": {Schema: discovery.Schema{
+					Type:     "object",
+					Required: []string{"id", "total"},
+					Properties: map[string]discovery.Schema{
+						"id":       {Type: "string"},
+						"total":    {Type: "number"},
+						"password": {Type: "string", WriteOnly: true},
+					},
+				}}},
+			},
+			"204": {Description: "no content"},
+			"4XX": {
+				Description: "client error",
+				Content:     map[string]discovery.MediaType{"application/problem+json": {Schema: discovery.Schema{Type: "object", Required: []string{"title"}}}},
+			},
+		},
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	issues := ValidateResponse(responseEndpoint(), IncomingResponse{
+		StatusCode: 200,
+		Headers:    map[string][]string{"Content-Type": {"application/json; charset=utf-8"}, "X-Rate-Limit": {"many"}},
+		Body:       `{"id": 7, "password": "secret"}`,
+	})
+
+	expected := []Issue{
+		{In: "header", Pointer: "/X-Rate-Limit", Message: "expected integer, got string"},
+		{In: "body", Pointer: "/", Message: `missing required property "total"`},
+		{In: "body", Pointer: "/id", Message: "expected string, got integer"},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], expected[i])
+		}
+	}
+}
+
+func TestValidateResponse_StatusAndContentType(t *testing.T) {
+	endpoint := responseEndpoint()
+
+	issues := ValidateResponse(endpoint, IncomingResponse{StatusCode: 500, Body: "boom"})
+	if len(issues) != 1 || issues[0].In != "status" || issues[0].Message != "status 500 is not documented (expected 200, 204, 4XX)" {
+		t.Errorf("Expected undocumented status, got %+v", issues)
+	}
+
+	// Range match with the wrong media type
+	issues = ValidateResponse(endpoint, IncomingResponse{
+		StatusCode: 404,
+		Headers:    map[string][]string{"content-type": {"text/html"}},
+		Body:       "<h1>Not Found</h1>",
+	})
+	if len(issues) != 1 || issues[0].Pointer != "/Content-Type" {
+		t.Errorf("Expected content type issue, got %+v", issues)
+	}
+
+	// Missing required header and Content-Type
+	issues = ValidateResponse(endpoint, IncomingResponse{StatusCode: 200, Body: `{"id": "1", "total": 3}`})
+	if len(issues) != 2 || issues[0].Message != `missing required response header "X-Rate-Limit"` || issues[1].Pointer != "/Content-Type" {
+		t.Errorf("Expected missing header issues, got %+v", issues)
+	}
+
+	// Documented without content: nothing to check
+	if issues := ValidateResponse(endpoint, IncomingResponse{StatusCode: 204}); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+
+	issues = ValidateResponse(endpoint, IncomingResponse{
+		StatusCode: 400,
+		Headers:    map[string][]string{"Content-Type": {"application/problem+json"}},
+		Body:       `{"title": "Bad Request"}`,
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected valid problem response, got %+v", issues)
+	}
+}
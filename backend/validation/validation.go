@@ -0,0 +1,467 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+// Issue is a single schema violation located by a JSON pointer
+type Issue struct {
+	In      string `json:"in,omitempty"` // path, query, header or body for request and response checks
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// Direction says which way a payload travels. It decides whether readOnly
+// or writeOnly properties can be required.
+type Direction int
+
+const (
+	Any      Direction = iota
+	Request            // readOnly properties are never required
+	Response           // writeOnly properties are never required
+)
+
+// ValidateValue checks a decoded JSON value against a schema.
+// pointer is the JSON pointer of value within its document ("" for the root).
+func ValidateValue(schema discovery.Schema, value interface{}, pointer string) []Issue {
+	return ValidatePayload(schema, value, pointer, Any)
+}
+
+// ValidatePayload is ValidateValue for a request or response payload
+func ValidatePayload(schema discovery.Schema, value interface{}, pointer string, direction Direction) []Issue {
+	c := &checker{direction: direction, issues: []Issue{}}
+	c.validate(schema, value, pointer)
+	return c.issues
+}
+
+// checker accumulates the issues of one validation pass
+type checker struct {
+	direction Direction
+	issues    []Issue
+}
+
+// validate records every violation of schema by value
+func (c *checker) validate(schema discovery.Schema, value interface{}, pointer string) {
+	// Unresolved references can't be checked
+	if isBareRef(schema) {
+		return
+	}
+	if value == nil && schema.Nullable {
+		return
+	}
+
+	if schema.Type != "" && !matchesType(schema.Type, value) {
+		c.add(pointer, "expected %s, got %s", schema.Type, typeName(value))
+		return
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		c.add(pointer, "value %s is not one of %s", compact(value), compact(schema.Enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c.validateObject(schema, v, pointer)
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			c.add(pointer, "expected at least %d items, got %d", *schema.MinItems, len(v))
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			c.add(pointer, "expected at most %d items, got %d", *schema.MaxItems, len(v))
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				c.validate(*schema.Items, item, pointer+"/"+strconv.Itoa(i))
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			c.add(pointer, "expected at least %d characters, got %d", *schema.MinLength, length)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			c.add(pointer, "expected at most %d characters, got %d", *schema.MaxLength, length)
+		}
+		if schema.Format != "" && !validFormat(schema.Format, v) {
+			c.add(pointer, "value %q is not a valid %s", v, schema.Format)
+		}
+		if schema.Pattern != "" {
+			// Patterns RE2 can't compile (e.g. lookaheads) are not enforced
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(v) {
+				c.add(pointer, "value %q does not match pattern %q", v, schema.Pattern)
+			}
+		}
+	case float64:
+		c.validateNumber(schema, v, pointer)
+	}
+
+	for _, sub := range schema.AllOf {
+		c.validate(sub, value, pointer)
+	}
+
+	if len(schema.AnyOf) > 0 && c.countMatches(schema.AnyOf, value, pointer) == 0 {
+		c.add(pointer, "value does not match any schema in anyOf")
+	}
+
+	if len(schema.OneOf) > 0 {
+		c.validateOneOf(schema, value, pointer)
+	}
+
+	if schema.Not != nil && len(ValidatePayload(*schema.Not, value, pointer, c.direction)) == 0 {
+		c.add(pointer, "value must not match the schema in not")
+	}
+}
+
+// validateObject checks required, declared and additional properties
+func (c *checker) validateObject(schema discovery.Schema, v map[string]interface{}, pointer string) {
+	for _, name := range schema.Required {
+		if !c.requires(schema.Properties[name]) {
+			continue
+		}
+		if _, ok := v[name]; !ok {
+			c.add(pointer, "missing required property %q", name)
+		}
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		if child, ok := v[name]; ok {
+			c.validate(schema.Properties[name], child, pointer+"/"+escapePointer(name))
+		}
+	}
+
+	extra := schema.AdditionalProperties
+	if extra == nil {
+		return
+	}
+	for _, name := range sortedValueKeys(v) {
+		if _, declared := schema.Properties[name]; declared {
+			continue
+		}
+		if !extra.Allowed {
+			c.add(pointer, "unexpected property %q", name)
+		} else if extra.Schema != nil {
+			c.validate(*extra.Schema, v[name], pointer+"/"+escapePointer(name))
+		}
+	}
+}
+
+// requires reports whether a required property applies in this direction
+func (c *checker) requires(prop discovery.Schema) bool {
+	switch c.direction {
+	case Request:
+		return !prop.ReadOnly
+	case Response:
+		return !prop.WriteOnly
+	default:
+		return true
+	}
+}
+
+// validateNumber checks minimum and maximum, honouring the exclusive flags
+func (c *checker) validateNumber(schema discovery.Schema, v float64, pointer string) {
+	if min := schema.Minimum; min != nil {
+		if schema.ExclusiveMinimum && v <= *min {
+			c.add(pointer, "expected greater than %s, got %s", formatNumber(*min), formatNumber(v))
+		} else if v < *min {
+			c.add(pointer, "expected at least %s, got %s", formatNumber(*min), formatNumber(v))
+		}
+	}
+	if max := schema.Maximum; max != nil {
+		if schema.ExclusiveMaximum && v >= *max {
+			c.add(pointer, "expected less than %s, got %s", formatNumber(*max), formatNumber(v))
+		} else if v > *max {
+			c.add(pointer, "expected at most %s, got %s", formatNumber(*max), formatNumber(v))
+		}
+	}
+}
+
+// validateOneOf requires exactly one alternative to match. With a
+// discriminator, the alternative is picked by the discriminating property and
+// its issues are reported directly.
+func (c *checker) validateOneOf(schema discovery.Schema, value interface{}, pointer string) {
+	if option, name, ok := discriminated(schema, value); ok {
+		if option == nil {
+			c.add(pointer+"/"+escapePointer(schema.Discriminator.PropertyName), "unknown discriminator value %q", name)
+			return
+		}
+		c.validate(*option, value, pointer)
+		return
+	}
+
+	switch matches := c.countMatches(schema.OneOf, value, pointer); {
+	case matches == 0:
+		c.add(pointer, "value does not match any schema in oneOf")
+	case matches > 1:
+		c.add(pointer, "value matches %d schemas in oneOf, expected exactly 1", matches)
+	}
+}
+
+// discriminated finds the oneOf alternative selected by the discriminator.
+// ok is false when there is no discriminator value to go by; option is nil
+// when the value doesn't map to any alternative.
+func discriminated(schema discovery.Schema, value interface{}) (option *discovery.Schema, name string, ok bool) {
+	if schema.Discriminator == nil {
+		return nil, "", false
+	}
+	obj, isObject := value.(map[string]interface{})
+	if !isObject {
+		return nil, "", false
+	}
+	name, ok = obj[schema.Discriminator.PropertyName].(string)
+	if !ok {
+		return nil, "", false
+	}
+
+	// Explicit mapping first, then the implicit schema name
+	ref := schema.Discriminator.Mapping[name]
+	for i := range schema.OneOf {
+		candidate := schema.OneOf[i].Ref
+		if candidate == "" {
+			continue
+		}
+		if candidate == ref || (ref == "" && strings.HasSuffix(candidate, "/"+name)) {
+			return &schema.OneOf[i], name, true
+		}
+	}
+	return nil, name, true
+}
+
+// countMatches returns how many schemas value satisfies
+func (c *checker) countMatches(schemas []discovery.Schema, value interface{}, pointer string) int {
+	matches := 0
+	for _, sub := range schemas {
+		if len(ValidatePayload(sub, value, pointer, c.direction)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// isBareRef reports whether schema is a reference that was never expanded
+// (e.g. a recursive one) and so carries nothing to check
+func isBareRef(schema discovery.Schema) bool {
+	return schema.Ref != "" && schema.Type == "" && len(schema.Properties) == 0 &&
+		schema.Items == nil && len(schema.AllOf) == 0 && len(schema.OneOf) == 0 &&
+		len(schema.AnyOf) == 0 && schema.Not == nil && len(schema.Enum) == 0
+}
+
+// inEnum reports whether value equals one of the allowed values. Values are
+// compared by their JSON encoding since enums come from YAML (ints) and
+// values from JSON (float64).
+func inEnum(enum []interface{}, value interface{}) bool {
+	encoded := compact(value)
+	for _, allowed := range enum {
+		if compact(allowed) == encoded {
+			return true
+		}
+	}
+	return false
+}
+
+// compact renders a value as compact JSON for comparisons and messages
+func compact(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// formatNumber renders a float without a trailing .0 for whole numbers
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// add appends a formatted issue at pointer
+func (c *checker) add(pointer, format string, args ...interface{}) {
+	c.issues = append(c.issues, Issue{
+		Pointer: pointerOrRoot(pointer),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// uuidPattern matches the canonical 8-4-4-4-12 hex form
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validFormat checks the string formats our specs rely on; other formats are not enforced
+func validFormat(format, v string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(v)
+	case "email":
+		addr, err := mail.ParseAddress(v)
+		return err == nil && addr.Address == v
+	case "ipv4":
+		ip := net.ParseIP(v)
+		return ip != nil && ip.To4() != nil && !strings.Contains(v, ":")
+	case "ipv6":
+		return net.ParseIP(v) != nil && strings.Contains(v, ":")
+	case "uri", "url":
+		u, err := url.Parse(v)
+		return err == nil && u.Scheme != ""
+	default:
+		return true
+	}
+}
+
+// matchesType reports whether value has the given JSON schema type
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return value == nil
+	default:
+		// Unknown types are not enforced
+		return true
+	}
+}
+
+// typeName describes the JSON type of a decoded value
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// escapePointer escapes a property name for use in a JSON pointer (RFC 6901)
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// pointerOrRoot returns "/" for the document root so pointers are never empty in output
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+// sortedKeys returns property names in sorted order so issues are deterministic
+func sortedKeys(m map[string]discovery.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResponseSchema finds the documented JSON schema for a status code.
+// Lookup order follows OpenAPI: exact code, then range (e.g. 2XX), then default.
+func ResponseSchema(endpoint *discovery.APIEndpoint, statusCode int) (discovery.Schema, bool) {
+	if endpoint == nil {
+		return discovery.Schema{}, false
+	}
+
+	response, ok := documentedResponse(endpoint.Responses, statusCode)
+	if !ok {
+		return discovery.Schema{}, false
+	}
+
+	media, ok := jsonMediaType(response.Content)
+	if !ok {
+		return discovery.Schema{}, false
+	}
+	return media.Schema, true
+}
+
+// documentedResponse finds the response declared for a status code
+func documentedResponse(responses map[string]discovery.Response, statusCode int) (discovery.Response, bool) {
+	code := strconv.Itoa(statusCode)
+	if r, ok := responses[code]; ok {
+		return r, true
+	}
+	for key, r := range responses {
+		if len(key) == 3 && strings.EqualFold(key[1:], "XX") && key[0] == code[0] {
+			return r, true
+		}
+	}
+	if r, ok := responses["default"]; ok {
+		return r, true
+	}
+	return discovery.Response{}, false
+}
+
+// jsonMediaType picks the JSON media type from a content map
+func jsonMediaType(content map[string]discovery.MediaType) (discovery.MediaType, bool) {
+	if media, ok := content["application/json"]; ok {
+		return media, true
+	}
+	for _, contentType := range sortedMediaKeys(content) {
+		if strings.Contains(contentType, "json") {
+			return content[contentType], true
+		}
+	}
+	return discovery.MediaType{}, false
+}
+
+// sortedValueKeys returns the keys of a decoded JSON object in sorted order
+func sortedValueKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedMediaKeys returns content types in sorted order
+func sortedMediaKeys(m map[string]discovery.MediaType) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
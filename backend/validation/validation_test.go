@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/triplewhale/postwhale/discovery"
+)
+
+func orderSchema() discovery.Schema {
+	return discovery.Schema{
+		Type:     "object",
+		Required: []string{"id", "total"},
+		Properties: map[string]discovery.Schema{
+			"id":    {Type: "string"},
+			"total": {Type: "number"},
+			"items": {Type: "array", Items: &discovery.Schema{
+				Type:       "object",
+				Required:   []string{"sku"},
+				Properties: map[string]discovery.Schema{"qty": {Type: "integer"}},
+			}},
+		},
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	var value interface{}
+	json.Unmarshal([]byte(`{"id": 5, "items": [{"sku": "a", "qty": 1}, {"qty": 1.5}]}`), &value)
+
+	issues := ValidateValue(orderSchema(), value, "")
+
+	expected := []Issue{
+		{Pointer: "/", Message: `missing required property "total"`},
+		{Pointer: "/id", Message: "expected string, got integer"},
+		{Pointer: "/items/1", Message: `missing required property "sku"`},
+		{Pointer: "/items/1/qty", Message: "expected integer, got number"},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %v", len(expected), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], expected[i])
+		}
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	endpoint := &discovery.APIEndpoint{
+		Responses: map[string]discovery.Response{
+			"200":     {Content: map[string]discovery.MediaType{"application/json": {Schema: orderSchema()}}},
+			"4XX":     {Content: map[string]discovery.MediaType{"application/problem+json": {Schema: discovery.Schema{Type: "object"}}}},
+			"default": {Description: "error"},
+		},
+	}
+
+	if schema, ok := ResponseSchema(endpoint, 200); !ok || schema.Type != "object" || len(schema.Required) != 2 {
+		t.Errorf("Expected exact 200 schema, got %+v (ok=%v)", schema, ok)
+	}
+	if _, ok := ResponseSchema(endpoint, 404); !ok {
+		t.Error("Expected 4XX range to match 404")
+	}
+	if _, ok := ResponseSchema(endpoint, 500); ok {
+		t.Error("Expected default response without content to have no schema")
+	}
+}
+
+func TestValidateValue_Keywords(t *testing.T) {
+	min, max := 1.0, 100.0
+	minLen := 3
+	petSchema := discovery.Schema{
+		OneOf: []discovery.Schema{
+			{Ref: "#/components/schemas/Cat", Type: "object", Properties: map[string]discovery.Schema{"lives": {Type: "integer", Maximum: &max}}},
+			{Ref: "#/components/schemas/Dog", Type: "object", Required: []string{"breed"}},
+		},
+		Discriminator: &discovery.Discriminator{PropertyName: "kind", Mapping: map[string]string{"doggo": "#/components/schemas/Dog"}},
+	}
+	schema := discovery.Schema{
+		AllOf: []discovery.Schema{
+			{Type: "object", Required: []string{"id"}},
+			{Type: "object", Properties: map[string]discovery.Schema{
+				"status": {Type: "string", Enum: []interface{}{"open", "closed"}},
+				"qty":    {Type: "integer", Minimum: &min, ExclusiveMinimum: true},
+				"code":   {Type: "string", MinLength: &minLen, Pattern: "^[A-Z]+$"},
+				"note":   {Type: "string", Nullable: true},
+				"tags":   {Type: "object", AdditionalProperties: &discovery.AdditionalProperties{Allowed: true, Schema: &discovery.Schema{Type: "string"}}},
+				"meta":   {Type: "object", AdditionalProperties: &discovery.AdditionalProperties{Allowed: false}},
+				"pets":   {Type: "array", Items: &petSchema},
+				"ref":    {AnyOf: []discovery.Schema{{Type: "string"}, {Type: "integer"}}, Not: &discovery.Schema{Enum: []interface{}{0}}},
+			}},
+		},
+	}
+
+	var value interface{}
+	json.Unmarshal([]byte(`{
+		"status": "pending", "qty": 1, "code": "ab", "note": null,
+		"tags": {"a": "x", "b": 2}, "meta": {"extra": true},
+		"pets": [{"kind": "Cat", "lives": 101}, {"kind": "doggo"}, {"kind": "fish"}],
+		"ref": 0
+	}`), &value)
+
+	issues := ValidateValue(schema, value, "")
+
+	expected := []Issue{
+		{Pointer: "/", Message: `missing required property "id"`},
+		{Pointer: "/code", Message: "expected at least 3 characters, got 2"},
+		{Pointer: "/code", Message: `value "ab" does not match pattern "^[A-Z]+$"`},
+		{Pointer: "/meta", Message: `unexpected property "extra"`},
+		{Pointer: "/pets/0/lives", Message: "expected at most 100, got 101"},
+		{Pointer: "/pets/1", Message: `missing required property "breed"`},
+		{Pointer: "/pets/2/kind", Message: `unknown discriminator value "fish"`},
+		{Pointer: "/qty", Message: "expected greater than 1, got 1"},
+		{Pointer: "/ref", Message: "value must not match the schema in not"},
+		{Pointer: "/status", Message: `value "pending" is not one of ["open","closed"]`},
+		{Pointer: "/tags/b", Message: "expected string, got integer"},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %v", len(expected), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], expected[i])
+		}
+	}
+}
+
+func TestValidateValue_OneOfWithoutDiscriminator(t *testing.T) {
+	schema := discovery.Schema{OneOf: []discovery.Schema{{Type: "number"}, {Type: "integer"}}}
+
+	if issues := ValidateValue(schema, 1.5, ""); len(issues) != 0 {
+		t.Errorf("Expected a single match to pass, got %v", issues)
+	}
+	issues := ValidateValue(schema, float64(2), "")
+	if len(issues) != 1 || issues[0].Message != "value matches 2 schemas in oneOf, expected exactly 1" {
+		t.Errorf("Expected ambiguous oneOf issue, got %v", issues)
+	}
+	issues = ValidateValue(schema, "x", "")
+	if len(issues) != 1 || issues[0].Message != "value does not match any schema in oneOf" {
+		t.Errorf("Expected no-match oneOf issue, got %v", issues)
+	}
+}
@@ -117,10 +117,32 @@ function startBackend() {
       const response = JSON.parse(line);
       console.log('[Electron] Backend response:', response);
 
-      // If there's a pending request handler, resolve it
       const requestId = response.requestId;
+
+      // Events (e.g. repositoryChanged) aren't tied to a request; forward them as-is
+      if (response.event !== undefined) {
+        if (mainWindow) {
+          mainWindow.webContents.send('ipc-event', response);
+        }
+        return;
+      }
+
+      // Progress messages for long-running requests (e.g. runCollection) are
+      // forwarded to the renderer and keep the request alive; they don't resolve it
+      if (response.progress !== undefined) {
+        if (requestId && requestHandlers.has(requestId)) {
+          requestHandlers.get(requestId).resetTimeout();
+        }
+        if (mainWindow) {
+          mainWindow.webContents.send('ipc-progress', response);
+        }
+        return;
+      }
+
+      // If there's a pending request handler, resolve it
       if (requestId && requestHandlers.has(requestId)) {
-        const { resolve } = requestHandlers.get(requestId);
+        const { resolve, clearTimeout: clear } = requestHandlers.get(requestId);
+        clear();
         resolve(response);
         requestHandlers.delete(requestId);
       }
@@ -143,13 +165,32 @@ function startBackend() {
 }
 
 // Handle IPC requests from renderer
-ipcMain.handle('ipc-request', async (event, action, data) => {
+// The renderer may pass its own requestId so it can cancel the request later
+ipcMain.handle('ipc-request', async (event, action, data, rendererRequestId) => {
   return new Promise((resolve, reject) => {
     // Generate unique request ID
-    const requestId = Date.now() + Math.random();
+    const requestId = rendererRequestId ?? Date.now() + Math.random();
+
+    // Timeout after 30 seconds without a response or progress message
+    let timer;
+    const resetTimeout = () => {
+      clearTimeout(timer);
+      timer = setTimeout(() => {
+        if (requestHandlers.has(requestId)) {
+          requestHandlers.delete(requestId);
+          reject(new Error('Request timeout'));
+        }
+      }, 30000);
+    };
 
     // Store handler for this request
-    requestHandlers.set(requestId, { resolve, reject });
+    requestHandlers.set(requestId, {
+      resolve,
+      reject,
+      resetTimeout,
+      clearTimeout: () => clearTimeout(timer),
+    });
+    resetTimeout();
 
     // Create request object
     const request = { action, data, requestId };
@@ -162,17 +203,10 @@ ipcMain.handle('ipc-request', async (event, action, data) => {
       backendProcess.stdin.write(requestLine);
     } catch (error) {
       console.error('[Electron] Failed to write to backend:', error);
+      clearTimeout(timer);
       requestHandlers.delete(requestId);
       reject(error);
     }
-
-    // Timeout after 30 seconds
-    setTimeout(() => {
-      if (requestHandlers.has(requestId)) {
-        requestHandlers.delete(requestId);
-        reject(new Error('Request timeout'));
-      }
-    }, 30000);
   });
 });
 
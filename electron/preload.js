@@ -2,10 +2,21 @@ const { contextBridge, ipcRenderer } = require('electron');
 
 // Expose IPC API to renderer process
 contextBridge.exposeInMainWorld('electron', {
-  invoke: async (action, data) => {
-    return ipcRenderer.invoke('ipc-request', action, data);
+  invoke: async (action, data, requestId) => {
+    return ipcRenderer.invoke('ipc-request', action, data, requestId);
+  },
+  cancel: async (requestId) => {
+    return ipcRenderer.invoke('ipc-request', 'cancelRequest', { requestId });
   },
   onResponse: (callback) => {
     ipcRenderer.on('ipc-response', (event, response) => callback(response));
+  },
+  onProgress: (callback) => {
+    ipcRenderer.on('ipc-progress', (event, message) => callback(message));
+  },
+  onEvent: (callback) => {
+    const listener = (event, message) => callback(message);
+    ipcRenderer.on('ipc-event', listener);
+    return () => ipcRenderer.removeListener('ipc-event', listener);
   }
 });
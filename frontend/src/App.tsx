@@ -101,7 +101,7 @@ function AppContent() {
   const abortControllerRef = useRef<AbortController | null>(null)
   const saveTimeoutRef = useRef<ReturnType<typeof setTimeout> | null>(null)
 
-  const { invoke } = useIPC()
+  const { invoke, cancel } = useIPC()
   const { addError } = useErrorHistory()
 
   const activeConfigId = useMemo(() => {
@@ -136,6 +136,20 @@ function AppContent() {
     return () => abortControllerRef.current?.abort()
   }, [])
 
+  // The backend watches repositories on disk and reports rescans
+  useEffect(() => {
+    const unsubscribe = window.electron?.onEvent?.((message) => {
+      if (message.event === 'repositoryChanged') loadData(false)
+    })
+    invoke('subscribe', { topics: ['repositoryChanged'] }).catch((err) =>
+      console.error('Failed to subscribe to repository changes:', err)
+    )
+    return () => {
+      unsubscribe?.()
+      invoke('unsubscribe', { topics: ['repositoryChanged'] }).catch(() => {})
+    }
+  }, [])
+
   const loadData = async (showGlobalLoading = true) => {
     if (showGlobalLoading) setIsLoadingData(true)
     setError(null)
@@ -277,13 +291,11 @@ function AppContent() {
 
     setIsSaving(true)
     try {
+      // Params, headers and body are left out so the backend fills them
+      // from an example generated from the endpoint's spec
       await invoke('saveSavedRequest', {
         endpointId,
         name,
-        pathParamsJson: '{}',
-        queryParamsJson: '[]',
-        headersJson: '[]',
-        body: '',
       })
       await loadData(false)
     } catch (err) {
@@ -334,7 +346,12 @@ function AppContent() {
   }) => {
     if (!activeConfigId || !activeEndpoint || isLoading) return
 
+    // Aborting (Cancel or unmount) also stops the request in the backend
+    const requestId = crypto.randomUUID()
     const controller = new AbortController()
+    controller.signal.addEventListener('abort', () => {
+      cancel(requestId).catch(() => {})
+    })
     abortControllerRef.current = controller
 
     const service = services.find((s) => s.id === activeEndpoint.serviceId)
@@ -384,7 +401,7 @@ function AppContent() {
         environment,
         endpointId: activeEndpoint.id,
         authEnabled: config.authEnabled,
-      })
+      }, requestId)
 
       if (!controller.signal.aborted) {
         requestResponses.set(activeConfigId, { request: requestData, response: result, isLoading: false })
@@ -406,7 +423,7 @@ function AppContent() {
       setStatusMessage(undefined)
       abortControllerRef.current = null
     }
-  }, [activeConfigId, activeEndpoint, isLoading, services, environment, invoke, requestResponses])
+  }, [activeConfigId, activeEndpoint, isLoading, services, environment, invoke, cancel, requestResponses])
 
   const handleLoadingStart = useCallback(() => {
     if (!activeConfigId) return
@@ -4,8 +4,10 @@ import { useCallback } from 'react';
 declare global {
   interface Window {
     electron?: {
-      invoke: (action: string, data?: any) => Promise<any>;
+      invoke: (action: string, data?: any, requestId?: string) => Promise<any>;
+      cancel?: (requestId: string) => Promise<any>;
       onResponse: (callback: (response: any) => void) => void;
+      onEvent?: (callback: (message: { event: string; data?: any }) => void) => () => void;
     };
   }
 }
@@ -26,10 +28,10 @@ interface IPCResponse<T = any> {
  * In Electron, this uses window.electron.invoke to communicate with the Go backend.
  */
 export function useIPC() {
-  const invoke = useCallback(async <T,>(action: string, data?: any): Promise<T> => {
+  const invoke = useCallback(async <T,>(action: string, data?: any, requestId?: string): Promise<T> => {
     // Check if running in Electron
     if (typeof window !== 'undefined' && window.electron) {
-      const response = await window.electron.invoke(action, data) as IPCResponse<T>;
+      const response = await window.electron.invoke(action, data, requestId) as IPCResponse<T>;
 
       if (!response.success) {
         throw new Error(response.error || 'Unknown error');
@@ -77,5 +79,15 @@ export function useIPC() {
     return {} as T;
   }, []);
 
-  return { invoke };
+  /**
+   * Asks the backend to abort the request sent with requestId. A cancel that
+   * arrives before the backend starts the request is still honored.
+   */
+  const cancel = useCallback(async (requestId: string): Promise<void> => {
+    if (typeof window !== 'undefined' && window.electron?.cancel) {
+      await window.electron.cancel(requestId);
+    }
+  }, []);
+
+  return { invoke, cancel };
 }
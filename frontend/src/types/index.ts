@@ -4,6 +4,16 @@ export interface Repository {
   id: number;
   name: string;
   path: string;
+  layout?: ScanLayout;
+}
+
+// Overrides for where a repository keeps its services; empty fields use
+// postwhale.config.yml or the default services/*/tw-config.json layout
+export interface ScanLayout {
+  serviceRoots?: string[];
+  configFiles?: string[];
+  specPatterns?: string[];
+  exclude?: string[];
 }
 
 export interface Service {
@@ -20,9 +30,15 @@ export interface Endpoint {
   method: string;
   path: string;
   operationId: string;
+  source?: SpecSource | null; // Spec file the endpoint was read from
   spec?: EndpointSpec; // Optional - backend may not include this field
 }
 
+export interface SpecSource {
+  file: string;
+  visibility?: 'private' | 'public';
+}
+
 export interface EndpointSpec {
   summary?: string;
   description?: string;
@@ -1,6 +1,6 @@
-export type HttpMethod = 'GET' | 'POST' | 'PUT' | 'PATCH' | 'DELETE'
+export type HttpMethod = 'GET' | 'POST' | 'PUT' | 'PATCH' | 'DELETE' | 'HEAD' | 'OPTIONS' | 'TRACE'
 
-export const HTTP_METHODS: readonly HttpMethod[] = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE']
+export const HTTP_METHODS: readonly HttpMethod[] = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'HEAD', 'OPTIONS', 'TRACE']
 
 export function getMethodColor(method: string): string {
   const colors: Record<string, string> = {
@@ -9,6 +9,8 @@ export function getMethodColor(method: string): string {
     PUT: 'indigo',
     PATCH: 'cyan',
     DELETE: 'pink',
+    HEAD: 'violet',
+    OPTIONS: 'grape',
   }
   return colors[method] || 'gray'
 }